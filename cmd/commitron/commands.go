@@ -1,68 +1,242 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/johnstilia/commitron/pkg/ai"
+	"github.com/johnstilia/commitron/pkg/clipboard"
 	"github.com/johnstilia/commitron/pkg/config"
 	"github.com/johnstilia/commitron/pkg/git"
+	"github.com/johnstilia/commitron/pkg/history"
+	"github.com/johnstilia/commitron/pkg/timing"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+	"github.com/johnstilia/commitron/pkg/version"
 	"github.com/spf13/cobra"
 )
 
 // Command-specific flags
 var dryRun bool
 var force bool
+var legacy bool
+var initProvider string
+var selfTest bool
+var quick bool
+var statsJSON bool
+var showResolved bool
+var amend bool
+var resetAuthor bool
+var edit bool
+var fromPatch string
+var diffOnly bool
+var bodyOnly bool
+var fixedSubject string
+var userContext string
+var interactiveBody bool
+var ignoreContextFile bool
+var outputFormat string
+var fromStdin bool
+var patchesDir string
+var writePatches bool
+var squashRange string
+var commitSquash bool
+var push bool
+var noVerify bool
+var gpgSign bool
+var outputFile string
+var copyMessage bool
+var alsoCommit bool
+var stageAll bool
+var stageTracked bool
+var forceHook bool
+var forceBranch bool
+var timeout time.Duration
+var verbose bool
 
 // generateCmd represents the generate command
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate a commit message using AI",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Use specified config file or default
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if quick {
+			cfg.Commit.QuickMode = true
+		}
+		cfg.Context.UserContext = strings.TrimSpace(userContext)
+		if !ignoreContextFile {
+			cfg.Context.FileContext = ai.ReadContextFile(cfg)
+		}
+		if err := checkAPIKey(cfg); err != nil {
+			return err
+		}
+
+		// Bound the whole run; provider requests are cancelled once the deadline passes
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			cfg.Ctx = ctx
+		}
+		// Timings differ on every run, so reproducible output leaves them out
+		if (verbose || outputFormat == "json") && !cfg.Deterministic {
+			cfg.Timings = &timing.Recorder{}
+		}
+		if verbose && !cfg.Deterministic {
+			// Also reported when the run fails, which is when it's most useful
+			started := time.Now()
+			defer func() { printTimings(cfg.Timings, time.Since(started)) }()
+		}
+		if outputFormat != "text" && outputFormat != "json" {
+			return failure("Unknown --format "+outputFormat, errors.New("use text or json"))
+		}
+		if outputFormat == "json" && !bodyOnly {
+			return failure("--format json can only be used with --body-only", nil)
+		}
+		// A given subject turns --body-only into "write the rest of this message"
+		if fixedSubject != "" {
+			if !bodyOnly {
+				return failure("--subject can only be used with --body-only", nil)
+			}
+			if outputFormat == "json" {
+				return failure("--format json cannot be used with --subject", nil)
+			}
+			fixedSubject = strings.TrimSpace(ai.SanitizeMessage(fixedSubject))
+			if fixedSubject == "" || strings.Contains(fixedSubject, "\n") {
+				return failure("--subject must be a single non-empty line", nil)
+			}
+		}
+		if bodyOnly {
+			if cfg.Commit.QuickMode {
+				return failure("--body-only cannot be used with --quick", nil)
+			}
+			cfg.Commit.IncludeBody = true
+		}
+		if interactiveBody {
+			if cfg.Commit.QuickMode {
+				return failure("--interactive-body cannot be used with --quick", nil)
+			}
+			cfg.Commit.IncludeBody = true
+		}
+
+		// The self-test only exercises the provider, so it works outside a repository
+		if selfTest {
+			return runSelfTest(cfg)
+		}
+
+		// Patches are described without touching any repository
+		if patchesDir != "" {
+			if fromPatch != "" || fromStdin {
+				return failure("--patches cannot be used with --from-patch or --from-stdin", nil)
+			}
+			return runPatches(cfg)
+		}
+		if writePatches {
+			return failure("--write can only be used with --patches", nil)
+		}
+
+		// Squash messages describe existing commits and never touch the index
+		if squashRange != "" {
+			return runSquash(cfg)
+		}
+		if commitSquash {
+			return failure("--commit-squash can only be used with --squash-range", nil)
+		}
+		if fromPatch != "" || fromStdin {
+			return runFromPatch(cfg)
+		}
+
+		if alsoCommit && outputFile == "" {
+			return failure("--commit can only be used with --output-file", nil)
+		}
+		// Fail before calling the provider if the message can't be saved
+		if outputFile != "" {
+			if err := checkWritable(outputFile); err != nil {
+				return failure("Cannot write to "+outputFile, err)
+			}
+		}
+
+		if resetAuthor && !amend {
+			return failure("--reset-author can only be used with --amend", nil)
+		}
+
 		// Check if we're in a git repository
 		if !git.IsGitRepo() {
-			return fmt.Errorf("\033[1;31m❌ Not a git repository\033[0m")
+			return failure("Not a git repository", nil)
 		}
 
-		// Use specified config file or default
-		var cfg *config.Config
-		var err error
-		if configPath != "" {
-			cfg, err = config.LoadConfigFromPath(configPath)
-			if err != nil {
-				return fmt.Errorf("\033[1;31m❌ Error loading configuration from %s: %w\033[0m", configPath, err)
+		// Ask before anything is staged when this run would commit to a protected branch
+		commits := !dryRun && (!bodyOnly || fixedSubject != "") && !diffOnly && (outputFile == "" || alsoCommit)
+		autoStage := cfg.UI.AutoStage || stageTracked || stageAll
+		if commits && !forceBranch {
+			// While the user answers, build the context from what is staged now; it is only
+			// used if staging leaves the diff unchanged
+			prefetch := !amend && !autoStage && fixedSubject == ""
+			if err := confirmProtectedBranch(cfg, prefetch); err != nil {
+				return err
 			}
-		} else {
-			cfg, err = config.LoadConfig()
+		}
+
+		// Hold the repository lock while the index is read, staged and committed so
+		// concurrent commitron runs can't commit each other's partial index. Runs that
+		// only preview the message leave the index alone and don't need it.
+		if commits || autoStage {
+			lock, err := git.AcquireLock()
 			if err != nil {
-				return fmt.Errorf("\033[1;31m❌ Error loading configuration: %w\033[0m", err)
+				return failure("Could not lock the repository", err)
 			}
+			defer lock.Release()
 		}
 
 		// Get staged files
+		stopGit := cfg.Timings.Track("git")
 		stagedFiles, err := git.GetStagedFiles()
 		if err != nil {
-			return fmt.Errorf("\033[1;31m❌ Error getting staged files: %w\033[0m", err)
+			return failure("Could not get staged files", err)
 		}
 
-		// Always auto-stage all modified files (tracked files only, excludes untracked)
-		fmt.Println("\033[1;33m🔄 Auto-staging all modified files...\033[0m")
-		
-		// Stage all modified files
-		err = git.StageAllModified()
-		if err != nil {
-			return fmt.Errorf("\033[1;31m❌ Error staging files: %w\033[0m", err)
-		}
-		
-		// Get staged files after staging
-		stagedFiles, err = git.GetStagedFiles()
-		if err != nil {
-			return fmt.Errorf("\033[1;31m❌ Error getting staged files after staging: %w\033[0m", err)
+		// Only stage on request, so what the user staged is exactly what gets committed
+		if autoStage {
+			fmt.Println("\033[1;33m🔄 Auto-staging all modified files...\033[0m")
+
+			// Stage all modified files, and new files too with --all
+			if stageAll {
+				err = git.StageAll()
+			} else {
+				err = git.StageAllModified()
+			}
+			if err != nil {
+				return failure("Could not stage files", err)
+			}
+
+			// Get staged files after staging
+			stagedFiles, err = git.GetStagedFiles()
+			if err != nil {
+				return failure("Could not get staged files after staging", err)
+			}
 		}
-		
-		if len(stagedFiles) == 0 {
-			return fmt.Errorf("\033[1;31m❌ No modified files found. Make some changes before running commitron\033[0m")
+
+		if len(stagedFiles) == 0 && !amend {
+			// Tracked modifications that aren't staged only need staging
+			if modified, err := git.GetUnstagedFiles(); !autoStage && err == nil && len(modified) > 0 {
+				return failure("Nothing to commit", errNothingStaged)
+			}
+			// New files that were never added are the usual reason for an "empty" change
+			if untracked, err := git.UntrackedFiles(); err == nil && len(untracked) > 0 {
+				return failure("Nothing to commit", untrackedError(untracked))
+			}
+			return failure("Nothing to commit", errNoStagedFiles)
 		}
 		
 		fmt.Printf("\033[1;32m✓ Staged %d files\033[0m\n", len(stagedFiles))
@@ -70,35 +244,1028 @@ var generateCmd = &cobra.Command{
 		// Get changes content for context
 		changes, err := git.GetStagedChanges()
 		if err != nil {
-			return fmt.Errorf("\033[1;31m❌ Error getting staged changes: %w\033[0m", err)
+			return failure("Could not get staged changes", err)
+		}
+
+		// When amending, describe everything the rewritten HEAD commit will contain
+		if amend {
+			base, err := git.AmendBase()
+			if err != nil {
+				return failure("Cannot amend", err)
+			}
+			cfg.Context.DiffBase = base
+
+			stagedFiles, err = git.GetStagedFilesSince(base)
+			if err != nil {
+				return failure("Could not get the files of the amended commit", err)
+			}
+			if len(stagedFiles) == 0 {
+				return failure("The amended commit would contain no changes", nil)
+			}
+
+			changes, err = git.GetStagedChangesSince(base)
+			if err != nil {
+				return failure("Could not get the changes of the amended commit", err)
+			}
+		}
+
+		stopGit()
+
+		if diffOnly {
+			printContext(cfg, stagedFiles, changes)
+			return nil
+		}
+
+		if interactiveBody {
+			cfg.Context.Intent = askIntent()
 		}
 
 		// Generate commit message using AI
 		fmt.Println("\033[1;36m🤖 Analyzing changes...\033[0m")
-		message, err := ai.GenerateCommitMessage(cfg, stagedFiles, changes)
-		if err != nil {
-			return fmt.Errorf("\033[1;31m❌ Error generating commit message: %w\033[0m", err)
+		var message string
+		revertHead, mergeHead := "", ""
+		if !amend && fixedSubject == "" {
+			mergeHead = git.MergeHead()
+			revertHead = git.RevertHead()
+			if mergeHead == "" && revertHead == "" && cfg.Commit.DetectReverts {
+				stopDetect := cfg.Timings.Track("git")
+				revertHead, err = git.FindRevertedCommit(revertSearchDepth)
+				stopDetect()
+				if err != nil {
+					return failure("Could not compare the changes with recent commits", err)
+				}
+			}
+		}
+		if fixedSubject != "" {
+			// The author wrote the subject; only the body is generated
+			message, err = ai.RegenerateBody(cfg, stagedFiles, changes, fixedSubject)
+			if err != nil {
+				return failure("Could not generate a commit body", err)
+			}
+			for _, warning := range ai.CheckMessage(message, cfg) {
+				fmt.Printf("\033[1;33m⚠️  %s\033[0m\n", warning)
+			}
+			fmt.Printf("\n%s\n", message)
+		} else if mergeHead != "" {
+			// Finishing a merge: keep git's canonical subject instead of describing the merged diff
+			subject, err := git.MergeSubject()
+			if err != nil {
+				return failure("Could not read the merge message", err)
+			}
+			var commits []string
+			if cfg.Commit.IncludeBody && cfg.Commit.MergeSummary {
+				if commits, err = git.MergedCommits(); err != nil {
+					return failure("Could not list the merged commits", err)
+				}
+			}
+			message = ai.GenerateMergeMessage(cfg, subject, commits)
+			fmt.Printf("\n%s\n", message)
+		} else if revertHead != "" {
+			// Finishing "git revert --no-commit" (or undoing a commit by hand with commit.detect_reverts):
+			// keep the linkage and only have the AI explain why
+			originalSubject, err := git.CommitSubject(revertHead)
+			if err != nil {
+				return failure("Could not read the reverted commit", err)
+			}
+			message, err = ai.GenerateRevertMessage(cfg, revertHead, originalSubject, changes)
+			if err != nil {
+				return failure("Could not generate a revert message", err)
+			}
+			fmt.Printf("\n%s\n", message)
+		} else {
+			message, err = ai.GenerateCommitMessage(cfg, stagedFiles, changes)
+			if err != nil {
+				var accepted bool
+				if message, accepted, err = acceptRawResponse(cfg, err); err != nil {
+					return failure("Could not generate a commit message", err)
+				}
+				if !accepted {
+					fmt.Println("\n\033[38;5;244m🚫 Commit cancelled.\033[0m")
+					return nil
+				}
+			}
+			if warning := ai.CheckType(message, stagedFiles, cfg); warning != "" {
+				fmt.Printf("\033[1;33m⚠️  %s\033[0m\n", warning)
+			}
+		}
+
+		if bodyOnly && fixedSubject == "" {
+			return printBody(cfg, message)
+		}
+
+		// Let the user accept, regenerate or cancel the message before anything is committed
+		if cfg.UI.ConfirmCommit && commits && stdinIsTerminal() {
+			var accepted bool
+			message, accepted, err = confirmMessage(cfg, stagedFiles, changes, message, revertHead == "" && mergeHead == "" && fixedSubject == "")
+			if err != nil {
+				return failure("Could not regenerate the commit message", err)
+			}
+			if !accepted {
+				recordHistory(cfg, message, false)
+				fmt.Println("\n\033[38;5;244m🚫 Commit cancelled.\033[0m")
+				return nil
+			}
+		}
+
+		// Let the user finalize the draft; their text is checked but never rewritten
+		if edit || cfg.UI.AlwaysEdit {
+			message, err = git.EditMessage(message)
+			if err != nil {
+				return failure("Commit aborted", err)
+			}
+			for _, warning := range ai.CheckMessage(message, cfg) {
+				fmt.Printf("\033[1;33m⚠️  %s\033[0m\n", warning)
+			}
+		}
+
+		// The attribution trailer survives editing, format_command and raw responses
+		if mergeHead == "" {
+			message = ai.EnsureAttribution(message, cfg)
+		}
+		message = ai.SanitizeMessage(message)
+
+		// Copying never stops the run; without a clipboard tool it only warns
+		if copyMessage {
+			if err := clipboard.Copy(message); err != nil {
+				fmt.Printf("\033[1;33m⚠️  Couldn't copy the message: %v\033[0m\n", err)
+			} else {
+				fmt.Println("\033[1;32m✓ Message copied to the clipboard\033[0m")
+			}
+		}
+
+		// The message file is written even in dry run mode; only committing is skipped
+		if outputFile != "" {
+			if err := writeMessageFile(outputFile, message); err != nil {
+				return failure("Could not write the message file", err)
+			}
+			fmt.Printf("\033[1;32m✓ Message written to %s\033[0m\n", outputFile)
+
+			if !alsoCommit && !dryRun {
+				recordHistory(cfg, message, true)
+				return nil
+			}
 		}
 
 		// In dry run mode, just display the message without committing
 		if dryRun {
+			recordHistory(cfg, message, false)
 			fmt.Println("\n\033[38;5;244m🔍 Dry run completed. No commit was created.\033[0m")
 			return nil
 		}
 
 		// Create the commit with the confirmed message
-		fmt.Print("\n\033[1;36m💾 Creating commit... \033[0m")
-		err = git.Commit(message)
+		opts := git.CommitOptions{ResetAuthor: resetAuthor, NoVerify: noVerify, Sign: gpgSign}
+		stopCommit := cfg.Timings.Track("git commit")
+		if amend {
+			fmt.Print("\n\033[1;36m💾 Amending commit... \033[0m")
+			err = git.AmendCommit(message, opts)
+		} else {
+			fmt.Print("\n\033[1;36m💾 Creating commit... \033[0m")
+			err = git.CommitWithOptions(message, opts)
+		}
+		stopCommit()
+		recordHistory(cfg, message, err == nil)
 		if err != nil {
 			fmt.Println("\033[1;31m❌ failed\033[0m")
-			return fmt.Errorf("\033[1;31m❌ Error: %w\033[0m", err)
+			return failure("Could not create the commit", err)
 		}
 		fmt.Println("\033[1;32m✓ complete\033[0m")
 
+		if push {
+			fmt.Print("\033[1;36m🚀 Pushing... \033[0m")
+			if err := git.Push(!git.HasUpstream()); err != nil {
+				fmt.Println("\033[1;31m❌ failed\033[0m")
+				return failure("The commit was created but could not be pushed", err)
+			}
+			fmt.Println("\033[1;32m✓ complete\033[0m")
+		}
+
 		return nil
 	},
 }
 
+// historyPath returns the configured history file or the default location
+func historyPath(cfg *config.Config) (string, error) {
+	if cfg.History.Path != "" {
+		return cfg.History.Path, nil
+	}
+	return history.DefaultPath()
+}
+
+// recordHistory appends the most recent generation to the local history when enabled
+func recordHistory(cfg *config.Config, message string, accepted bool) {
+	if !cfg.History.Enabled {
+		return
+	}
+
+	path, err := historyPath(cfg)
+	if err != nil {
+		fmt.Printf("\033[1;33m⚠️  Could not record history: %v\033[0m\n", err)
+		return
+	}
+
+	usage := ai.LastUsage()
+	repo, _ := os.Getwd()
+	entry := history.Entry{
+		Time:             time.Now(),
+		Repo:             repo,
+		Provider:         usage.Provider,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		LatencyMs:        usage.Latency.Milliseconds(),
+		Attempts:         usage.Attempts,
+		Accepted:         accepted,
+		Message:          message,
+	}
+	if err := history.Append(path, entry); err != nil {
+		fmt.Printf("\033[1;33m⚠️  Could not record history: %v\033[0m\n", err)
+	}
+}
+
+// checkAPIKey fails before any request when the API key is missing or a placeholder,
+// and only warns when it looks malformed
+func checkAPIKey(cfg *config.Config) error {
+	warning, err := cfg.CheckAPIKey()
+	if err != nil {
+		return failure("Set your API key in the config", err)
+	}
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "\033[1;33m⚠️  %s\033[0m\n", warning)
+	}
+	return nil
+}
+
+// stdinIsTerminal reports whether there is a user at the terminal to answer prompts
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// acceptRawResponse shows a response that couldn't be parsed and asks whether to use it
+// as the message. Any other error, or no terminal to ask on, is returned unchanged.
+func acceptRawResponse(cfg *config.Config, err error) (string, bool, error) {
+	var rawErr *ai.RawResponseError
+	if !errors.As(err, &rawErr) || !cfg.Commit.AllowRawFallback || !stdinIsTerminal() {
+		return "", false, err
+	}
+
+	fmt.Println("\n\033[1;33m⚠️  The response could not be parsed, this is the raw output\033[0m")
+	ai.PrintCommitMessage(rawErr.Response)
+	choice, err := ai.AskConfirmation(false, 0, ai.ConfirmReject) // A raw response is never used unseen
+	if err != nil {
+		return "", false, err
+	}
+	return ai.SanitizeMessage(rawErr.Response), choice == ai.ConfirmAccept, nil
+}
+
+// revertSearchDepth is the number of recent commits commit.detect_reverts compares the
+// staged changes against
+const revertSearchDepth = 50
+
+// maxRegenerations caps how often the message or its body can be regenerated at the prompt
+const maxRegenerations = 3
+
+// confirmMessage asks whether to commit the message, regenerating the whole message or
+// only its body on request. It returns the final message and whether it was accepted.
+func confirmMessage(cfg *config.Config, files []string, changes, message string, canRegenerate bool) (string, bool, error) {
+	if !cfg.UI.EnableTUI {
+		ai.PrintCommitMessage(message)
+	}
+
+	for regenerations := 0; ; regenerations++ {
+		// Which staged files the message seems to cover, to catch one that ignores part of the change
+		if cfg.UI.ShowCoverage {
+			ai.DisplayCoverage(message, files, changes, cfg.UI.DisplayFilesLimit)
+		}
+		if canRegenerate && regenerations == maxRegenerations {
+			fmt.Printf("\033[38;5;244m   Regenerated %d times, only accepting or cancelling is left\033[0m\n", maxRegenerations)
+		}
+		timeout, onTimeout := confirmTimeout(cfg)
+		choice, err := ai.AskConfirmation(canRegenerate && regenerations < maxRegenerations, timeout, onTimeout)
+		if err != nil {
+			return message, false, err
+		}
+
+		switch choice {
+		case ai.ConfirmAccept:
+			return message, true, nil
+		case ai.ConfirmReject:
+			return message, false, nil
+		case ai.ConfirmRegenerate:
+			fmt.Println("\n\033[1;36m🤖 Regenerating...\033[0m")
+			recordHistory(cfg, message, false)
+			message, err = ai.GenerateCommitMessage(cfg, files, changes)
+			var rawErr *ai.RawResponseError
+			if errors.As(err, &rawErr) && cfg.Commit.AllowRawFallback {
+				// The prompt below is the confirmation the raw response needs
+				fmt.Println("\n\033[1;33m⚠️  The response could not be parsed, this is the raw output\033[0m")
+				message, err = rawErr.Response, nil
+				ai.PrintCommitMessage(message)
+			} else if err == nil && !cfg.UI.EnableTUI {
+				ai.PrintCommitMessage(message)
+			}
+		case ai.ConfirmRegenerateBody:
+			fmt.Println("\n\033[1;36m🤖 Regenerating the body...\033[0m")
+			message, err = ai.RegenerateBody(cfg, files, changes, message)
+			if err == nil {
+				ai.PrintCommitMessage(message)
+			}
+		}
+		if err != nil {
+			return message, false, err
+		}
+	}
+}
+
+// confirmTimeout returns how long the confirmation prompt waits and what no answer means
+func confirmTimeout(cfg *config.Config) (time.Duration, ai.ConfirmChoice) {
+	onTimeout := ai.ConfirmReject
+	if cfg.UI.NonInteractiveAction == config.ActionAccept {
+		onTimeout = ai.ConfirmAccept
+	}
+	return time.Duration(cfg.UI.ConfirmTimeoutSeconds) * time.Second, onTimeout
+}
+
+// confirmProtectedBranch asks before committing to a branch listed in git.protected_branches.
+// Without a terminal to ask on it fails, since nobody would see the question. With prefetch,
+// the staged context is built while the question waits for an answer.
+func confirmProtectedBranch(cfg *config.Config, prefetch bool) error {
+	branch, err := git.CurrentBranch()
+	if err != nil || branch == "" {
+		return nil
+	}
+	pattern := git.MatchBranch(cfg.Git.ProtectedBranches, branch)
+	if pattern == "" {
+		return nil
+	}
+
+	detail := fmt.Errorf("%w: %s matches %q in git.protected_branches", git.ErrProtectedBranch, branch, pattern)
+	if !stdinIsTerminal() {
+		return failure("Not committing to protected branch "+branch, detail)
+	}
+
+	if prefetch {
+		ai.PrefetchContext(cfg)
+	}
+	fmt.Printf("\033[1;33m⚠️  %s is a protected branch. Commit to it anyway? [y/N] \033[0m", branch)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return failure("Not committing to protected branch "+branch, detail)
+}
+
+// askIntent asks the author why they are making the change, for --interactive-body. Without a
+// terminal nobody would see the question, so it is skipped and "" returned, as for no answer.
+func askIntent() string {
+	if !stdinIsTerminal() {
+		return ""
+	}
+	fmt.Print("\033[1;36m💬 In one sentence, why are you making this change? \033[0m")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(ai.SanitizeMessage(answer))
+}
+
+// loadConfig loads the configuration from the --config files (layered in order) or the
+// default locations, then applies any commitron.* overrides from git config
+func loadConfig() (*config.Config, error) {
+	if logFormat != config.LogFormatText && logFormat != config.LogFormatJSON {
+		return nil, failure(fmt.Sprintf("Unknown --log-format %q; use text or json", logFormat), nil)
+	}
+
+	var cfg *config.Config
+	var err error
+	headline := "Could not load configuration"
+	if len(configPaths) > 0 {
+		cfg, err = config.LoadConfigFromPaths(configPaths)
+		headline += " from " + strings.Join(configPaths, ", ")
+	} else {
+		cfg, err = config.LoadConfig()
+	}
+
+	// Unknown keys are usually typos, so they fail the run unless --no-strict-config
+	var unknown *config.UnknownKeysError
+	if errors.As(err, &unknown) && noStrictConfig {
+		for _, key := range unknown.Keys {
+			fmt.Fprintf(os.Stderr, "\033[1;33m⚠️  %s\033[0m\n", key)
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, failure(headline, err)
+	}
+
+	cfg.LogFormat = logFormat
+	cfg.Deterministic = deterministic
+
+	// commitron.* keys in git config (e.g. per repository) override the file
+	if err := cfg.ApplyGitConfig(git.GetConfig); err != nil {
+		return nil, failure("Invalid commitron setting in git config", err)
+	}
+
+	// Broken prompt templates are reported now, with their line, rather than mid-generation
+	if err := cfg.LoadPromptTemplates(); err != nil {
+		return nil, failure("Invalid prompt template", err)
+	}
+	if err := ai.CheckPromptTemplates(cfg); err != nil {
+		return nil, failure("Invalid prompt template", err)
+	}
+	if err := ai.CheckIssuePattern(cfg); err != nil {
+		return nil, failure("Invalid configuration", err)
+	}
+	if err := tokenizer.SetEstimator(cfg.Context.TokenEstimator); err != nil {
+		return nil, failure("Invalid configuration", err)
+	}
+	return cfg, nil
+}
+
+// checkWritable verifies a file can be created in the target's directory
+func checkWritable(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".commitron-check-")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// writeMessageFile atomically writes the message with LF line endings and a trailing newline
+func writeMessageFile(path, message string) error {
+	content := strings.TrimRight(strings.ReplaceAll(message, "\r\n", "\n"), "\n") + "\n"
+
+	// Write next to the target and rename so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".commitron-msg-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// runFromPatch prints a message for a patch file or stdin without any git interaction
+func runFromPatch(cfg *config.Config) error {
+	var data []byte
+	var err error
+	if fromStdin {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fromPatch)
+	}
+	if err != nil {
+		return failure("Could not read the patch", err)
+	}
+
+	patch := string(data)
+	fileDiffs, err := ai.ParsePatch(patch)
+	if err != nil {
+		return failure("Could not parse the patch", err)
+	}
+
+	files := make([]string, 0, len(fileDiffs))
+	for _, fd := range fileDiffs {
+		files = append(files, fd.Path)
+	}
+
+	patchOnly(cfg)
+
+	if diffOnly {
+		printContext(cfg, files, patch)
+		return nil
+	}
+
+	message, err := ai.GenerateCommitMessage(cfg, files, patch)
+	if err != nil {
+		var accepted bool
+		if message, accepted, err = acceptRawResponse(cfg, err); err != nil {
+			return failure("Could not generate a commit message", err)
+		}
+		if !accepted {
+			return nil
+		}
+	}
+
+	if bodyOnly {
+		return printBody(cfg, message)
+	}
+
+	fmt.Println(message)
+	return nil
+}
+
+// patchOnly turns off everything that reads the repository, since only the patch is available
+func patchOnly(cfg *config.Config) {
+	cfg.Context.UseProvidedDiff = true
+	cfg.Context.IncludeFileStats = false
+	cfg.Context.IncludeFileSummaries = false
+	cfg.Context.ShowFirstLinesOfFile = 0
+	cfg.Context.IncludeRepoStructure = false
+	cfg.Context.DirectoryGroupDepth = 0
+	cfg.Commit.LearnScopesFromHistory = false
+	cfg.UI.EnableTUI = false
+}
+
+// runPatches generates a message for every .patch and .diff file in patchesDir, printing
+// them or, with --write, writing each into its patch. A patch that can't be read, parsed or
+// described is reported and skipped; the command fails if any did.
+func runPatches(cfg *config.Config) error {
+	entries, err := os.ReadDir(patchesDir)
+	if err != nil {
+		return failure("Could not read the patch directory", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".patch" || ext == ".diff") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return failure("No .patch or .diff files in "+patchesDir, nil)
+	}
+
+	patchOnly(cfg)
+
+	failed := 0
+	for _, name := range names {
+		path := filepath.Join(patchesDir, name)
+		if err := describePatch(cfg, path); err != nil {
+			fmt.Printf("\033[1;31m✗ %s: %v\033[0m\n", name, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return failure(fmt.Sprintf("%d of %d patches could not be described", failed, len(names)), nil)
+	}
+	return nil
+}
+
+// describePatch generates the message for one patch file of a --patches run
+func describePatch(cfg *config.Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	patch := string(data)
+
+	fileDiffs, err := ai.ParsePatch(patch)
+	if err != nil {
+		return err
+	}
+	if len(fileDiffs) == 0 {
+		return errors.New("no file changes in the patch")
+	}
+	files := make([]string, 0, len(fileDiffs))
+	for _, fd := range fileDiffs {
+		files = append(files, fd.Path)
+	}
+
+	// A copy per patch; it shares cfg's timing recorder, so stage timings add up over the series
+	patchCfg := *cfg
+	message, err := ai.GenerateCommitMessage(&patchCfg, files, patch)
+	if err != nil {
+		return err
+	}
+
+	if !writePatches {
+		fmt.Printf("\033[1;36m=== %s ===\033[0m\n%s\n\n", filepath.Base(path), message)
+		return nil
+	}
+	if err := writeMessageFile(path, git.SetPatchMessage(patch, message)); err != nil {
+		return err
+	}
+	fmt.Printf("\033[1;32m✓ %s\033[0m\n", filepath.Base(path))
+	return nil
+}
+
+// runSquash prints one message for the combined diff of the commits in squashRange and, with
+// --commit-squash, records that diff as a single commit on a new branch
+func runSquash(cfg *config.Config) error {
+	if !git.IsGitRepo() {
+		return failure("Not a git repository", nil)
+	}
+
+	commits, err := git.ResolveRange(squashRange)
+	if err != nil {
+		return failure("Invalid --squash-range", err)
+	}
+	subjects, err := commits.Subjects()
+	if err != nil {
+		return failure("Nothing to squash", err)
+	}
+	files, err := commits.Files()
+	if err != nil {
+		return failure("Could not get the changed files", err)
+	}
+	if len(files) == 0 {
+		return failure("Nothing to squash", fmt.Errorf("the commits in %s cancel out, their combined diff is empty", squashRange))
+	}
+	changes, err := commits.Diff()
+	if err != nil {
+		return failure("Could not get the combined diff", err)
+	}
+
+	// The combined diff goes through the usual budgeting; nothing staged applies
+	patchOnly(cfg)
+	cfg.Context.SquashedSubjects = subjects
+
+	message, err := ai.GenerateCommitMessage(cfg, files, changes)
+	if err != nil {
+		var accepted bool
+		if message, accepted, err = acceptRawResponse(cfg, err); err != nil {
+			return failure("Could not generate a commit message", err)
+		}
+		if !accepted {
+			return nil
+		}
+	}
+	fmt.Println(message)
+
+	if !commitSquash {
+		return nil
+	}
+	branch := squashBranch(commits)
+	commit, err := commits.CreateSquashCommit(message, branch)
+	if err != nil {
+		return failure("Could not create the squashed commit", err)
+	}
+	fmt.Printf("\033[1;32m✓ Squashed %d commits into %s on branch %s\033[0m\n", len(subjects), commit[:12], branch)
+	return nil
+}
+
+// squashBranch names the branch --commit-squash creates after the squashed branch, e.g.
+// "feature-squashed", falling back to the head commit on a detached HEAD
+func squashBranch(commits git.CommitRange) string {
+	_, head, found := strings.Cut(commits.Spec, "...")
+	if !found {
+		_, head, _ = strings.Cut(commits.Spec, "..")
+	}
+	if head == "" || head == "HEAD" {
+		head, _ = git.CurrentBranch()
+	}
+	if head == "" {
+		return "squashed-" + commits.Head[:12]
+	}
+	return head + "-squashed"
+}
+
+// printBody prints everything below the subject line of a generated message, as text or
+// JSON, to stdout or the --output-file. Nothing is committed.
+func printBody(cfg *config.Config, message string) error {
+	_, body, _ := strings.Cut(strings.ReplaceAll(message, "\r\n", "\n"), "\n\n")
+	body = strings.TrimSpace(body)
+
+	output := body
+	if outputFormat == "json" {
+		data, err := json.MarshalIndent(struct {
+			Body    string         `json:"body"`
+			Timings []timing.Stage `json:"timings,omitempty"`
+		}{body, cfg.Timings.Stages()}, "", "  ")
+		if err != nil {
+			return err
+		}
+		output = string(data)
+	}
+
+	if outputFile != "" {
+		if err := writeMessageFile(outputFile, output); err != nil {
+			return failure("Could not write the message file", err)
+		}
+		return nil
+	}
+	fmt.Println(output)
+	return nil
+}
+
+// printTimings writes the time spent per pipeline stage to stderr
+func printTimings(recorder *timing.Recorder, total time.Duration) {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nSTAGE\tTIME")
+	for _, stage := range recorder.Stages() {
+		fmt.Fprintf(w, "%s\t%s\n", stage.Name, stage.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "total\t%s\n", total.Round(time.Millisecond))
+	w.Flush()
+}
+
+// printContext prints the processed diff context that would be sent to the model, without
+// calling it. The token count per pipeline stage goes to stderr so stdout is just the context.
+func printContext(cfg *config.Config, files []string, changes string) {
+	processed, stages := ai.PreviewContext(cfg, files, changes)
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tTOKENS\tDETAIL")
+	for _, stage := range stages {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", stage.Name, stage.Tokens, stage.Detail)
+	}
+	w.Flush()
+	fmt.Fprintln(os.Stderr)
+
+	// The context file goes into every prompt next to the diff
+	if cfg.Context.FileContext != "" {
+		fmt.Printf("# %s\n%s\n\n", cfg.Context.ContextFile, cfg.Context.FileContext)
+	}
+	fmt.Println(processed)
+}
+
+// runSelfTest verifies the provider round-trip with a fixed fake diff
+func runSelfTest(cfg *config.Config) error {
+	fmt.Printf("\033[1;36m🩺 Running provider self-test (%s/%s)...\033[0m\n", cfg.AI.Provider, cfg.AI.Model)
+
+	message, latency, err := ai.SelfTest(cfg)
+	if err != nil {
+		return failure(fmt.Sprintf("Self-test failed after %s", latency.Round(time.Millisecond)), err)
+	}
+
+	if cfg.Deterministic {
+		fmt.Println("\033[1;32m✓ Round-trip succeeded\033[0m")
+	} else {
+		fmt.Printf("\033[1;32m✓ Round-trip succeeded in %s\033[0m\n", latency.Round(time.Millisecond))
+	}
+	fmt.Println("\n\033[38;5;244m────────────────────────\033[0m")
+	for _, line := range strings.Split(message, "\n") {
+		if line == "" {
+			fmt.Println()
+		} else {
+			fmt.Printf("   %s\n", line)
+		}
+	}
+	fmt.Println("\033[38;5;244m────────────────────────\033[0m")
+	return nil
+}
+
+// hookCmd represents the hook command, run from git's prepare-commit-msg hook
+var hookCmd = &cobra.Command{
+	Use:   "hook <message-file> [source] [sha]",
+	Short: "Write a generated message into the commit message file (prepare-commit-msg hook)",
+	Args:  cobra.RangeArgs(1, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		messageFile := args[0]
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := checkAPIKey(cfg); err != nil {
+			return err
+		}
+		cfg.Context.FileContext = ai.ReadContextFile(cfg)
+		// The hook runs inside git commit, so never prompt or draw the TUI
+		cfg.UI.EnableTUI = false
+		cfg.UI.ConfirmCommit = false
+
+		// A rebase replays commits that already have messages
+		if git.InProgressOperation() == "rebase" {
+			return nil
+		}
+
+		data, err := os.ReadFile(messageFile)
+		if err != nil {
+			return failure("Could not read the commit message file", err)
+		}
+		existing := string(data)
+
+		// Only generate when git hasn't been given a message already
+		commentChar := git.CommentChar()
+		kind := git.ClassifyCommitMessage(existing, commentChar, git.CommitTemplate())
+		if kind == git.MessageReal && !cfg.Hook.AugmentExisting {
+			return nil
+		}
+
+		stagedFiles, err := git.GetStagedFiles()
+		if err != nil {
+			return failure("Could not get staged files", err)
+		}
+		if len(stagedFiles) == 0 {
+			return nil
+		}
+
+		changes, err := git.GetStagedChanges()
+		if err != nil {
+			return failure("Could not get staged changes", err)
+		}
+
+		message, err := ai.GenerateCommitMessage(cfg, stagedFiles, changes)
+		if err != nil {
+			return failure("Could not generate a commit message", err)
+		}
+		message = ai.SanitizeMessage(message)
+
+		var content string
+		if kind == git.MessageReal {
+			// Keep the user's message and add the generated body underneath it
+			content = augmentMessage(existing, message, commentChar)
+		} else {
+			// Put the generated message above git's comments so they stay visible in the editor
+			_, comments := git.SplitMessageComments(existing, commentChar)
+			content = message + "\n"
+			if comments != "" {
+				content += "\n" + comments
+			}
+		}
+
+		err = os.WriteFile(messageFile, []byte(content), 0644)
+		recordHistory(cfg, message, err == nil)
+		return err
+	},
+}
+
+// hookScript runs commitron from prepare-commit-msg with the arguments git passes
+const hookScript = `exec commitron hook "$@"
+`
+
+// hookInstallCmd installs the prepare-commit-msg hook
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the prepare-commit-msg hook in the current repository",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !git.IsGitRepo() {
+			return failure("Not a git repository", nil)
+		}
+
+		// Hooks live in the common git directory, so this also covers every worktree
+		path, err := git.InstallHook("prepare-commit-msg", hookScript, forceHook)
+		if err != nil {
+			return failure("Could not install the hook", err)
+		}
+
+		fmt.Printf("\033[1;32m✓ Installed prepare-commit-msg hook at %s\033[0m\n", path)
+		if git.IsWorktree() {
+			fmt.Println("   The hook is shared by all worktrees of this repository")
+		}
+		return nil
+	},
+}
+
+// augmentMessage appends the body of the generated message below an existing message
+func augmentMessage(existing, generated, commentChar string) string {
+	text, comments := git.SplitMessageComments(existing, commentChar)
+
+	parts := strings.SplitN(generated, "\n\n", 2)
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		return existing
+	}
+
+	content := text + "\n\n" + strings.TrimSpace(parts[1]) + "\n"
+	if comments != "" {
+		content += "\n" + comments
+	}
+	return content
+}
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show usage statistics from the local generation history",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if !cfg.History.Enabled {
+			return failure("History is disabled", errors.New("set history.enabled: true in your configuration to collect statistics"))
+		}
+
+		path, err := historyPath(cfg)
+		if err != nil {
+			return failure("Could not locate the history file", err)
+		}
+		entries, err := history.Load(path)
+		if err != nil {
+			return failure("Could not read the history", err)
+		}
+
+		stats := history.Summarize(entries, time.Now())
+		if statsJSON {
+			data, err := json.MarshalIndent(stats, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		printStats(stats)
+		return nil
+	},
+}
+
+// limitsCmd represents the limits command
+var limitsCmd = &cobra.Command{
+	Use:   "limits",
+	Short: "Show the token limits and how the staged diff would be handled",
+	Long: `Show the input token limit of the configured provider and model, the configured
+override, the budget left for the diff after the reserved prompt and response tokens, the
+tokenizer encoding, and the size of the staged diff with the strategy that would be used
+for it. Nothing is sent to the model.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		var stagedFiles []string
+		var changes string
+		if git.IsGitRepo() {
+			if stagedFiles, err = git.GetStagedFiles(); err != nil {
+				return failure("Could not get staged files", err)
+			}
+			if changes, err = git.GetStagedChanges(); err != nil {
+				return failure("Could not get staged changes", err)
+			}
+		}
+
+		printLimits(ai.ComputeLimits(cfg, stagedFiles, changes), len(stagedFiles))
+		return nil
+	},
+}
+
+// printLimits writes the token limits as a plain table
+func printLimits(limits ai.Limits, stagedFiles int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	override := "not set"
+	if limits.MaxInputTokens > 0 {
+		override = fmt.Sprintf("%d", limits.MaxInputTokens)
+		if limits.MaxInputTokens > limits.ProviderLimit {
+			override += " (above the built-in limit, ignored)"
+		}
+	}
+
+	fmt.Fprintf(w, "Provider	%s\n", limits.Provider)
+	fmt.Fprintf(w, "Model	%s\n", limits.Model)
+	fmt.Fprintf(w, "Built-in input limit	%d\n", limits.ProviderLimit)
+	fmt.Fprintf(w, "context.max_input_tokens	%s\n", override)
+	fmt.Fprintf(w, "Effective input budget	%d\n", limits.InputBudget)
+	fmt.Fprintf(w, "Reserved for the prompt	%d\n", limits.PromptOverhead)
+	fmt.Fprintf(w, "Reserved for the response	%d\n", limits.ResponseTokens)
+	fmt.Fprintf(w, "Available for the diff	%d\n", limits.ForChanges)
+	fmt.Fprintf(w, "Tokenizer	%s (%s)\n", limits.Encoding, limits.TokenizerModel)
+
+	if stagedFiles == 0 {
+		fmt.Fprintf(w, "Staged diff	nothing staged\n")
+	} else {
+		strategy := "none (fits)"
+		if limits.Strategy != "" {
+			strategy = limits.Strategy
+		}
+		fmt.Fprintf(w, "Staged diff	%d tokens in %d files\n", limits.DiffTokens, stagedFiles)
+		fmt.Fprintf(w, "Strategy	%s\n", strategy)
+	}
+
+	w.Flush()
+}
+
+// printStats writes the statistics as plain tables
+func printStats(stats history.Stats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Generations\t%d\n", stats.Generations)
+	fmt.Fprintf(w, "Accepted\t%d (%.0f%%)\n", stats.Accepted, stats.AcceptanceRate*100)
+	fmt.Fprintf(w, "Avg attempts\t%.2f\n", stats.AvgAttempts)
+	fmt.Fprintf(w, "Avg prompt tokens\t%.0f\n", stats.AvgPromptTokens)
+	fmt.Fprintf(w, "Avg completion tokens\t%.0f\n", stats.AvgCompletionTokens)
+	fmt.Fprintf(w, "Avg latency\t%s\n", time.Duration(stats.AvgLatencyMs*float64(time.Millisecond)).Round(time.Millisecond))
+
+	fmt.Fprintln(w, "\nDAY\tGENERATIONS")
+	for _, day := range stats.PerDay {
+		fmt.Fprintf(w, "%s\t%d\n", day.Period, day.Generations)
+	}
+
+	fmt.Fprintln(w, "\nWEEK\tGENERATIONS")
+	for _, week := range stats.PerWeek {
+		fmt.Fprintf(w, "%s\t%d\n", week.Period, week.Generations)
+	}
+
+	fmt.Fprintln(w, "\nPROVIDER\tMODEL\tGENERATIONS\tPROMPT TOKENS\tCOMPLETION TOKENS\tEST. SPEND")
+	for _, spend := range stats.Spend {
+		cost := "unknown"
+		if spend.Priced {
+			cost = fmt.Sprintf("$%.4f", spend.EstimatedUSD)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n", spend.Provider, spend.Model, spend.Generations, spend.PromptTokens, spend.CompletionTokens, cost)
+	}
+
+	w.Flush()
+}
+
 // initCmd represents the init command
 var initCmd = &cobra.Command{
 	Use:   "init",
@@ -106,24 +1273,31 @@ var initCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Determine config path
 		var targetPath string
-		if configPath != "" {
-			targetPath = configPath
+		if len(configPaths) > 1 {
+			return failure("Cannot initialize several configuration files", errors.New("pass a single --config path"))
+		}
+		if len(configPaths) == 1 {
+			targetPath = configPaths[0]
 		} else {
-			homeDir, err := os.UserHomeDir()
+			var err error
+			if legacy {
+				targetPath, err = config.LegacyConfigPath()
+			} else {
+				targetPath, err = config.DefaultConfigPath()
+			}
 			if err != nil {
-				return fmt.Errorf("\033[1;31m❌ Error getting home directory: %w\033[0m", err)
+				return failure("Could not find the home directory", err)
 			}
-			targetPath = filepath.Join(homeDir, ".commitronrc")
 		}
 
 		// Check if config file already exists
 		if _, err := os.Stat(targetPath); err == nil && !force {
-			return fmt.Errorf("\033[1;31m❌ Configuration file already exists at %s (use --force to overwrite)\033[0m", targetPath)
+			return failure("Configuration file already exists at "+targetPath, errors.New("use --force to overwrite it"))
 		}
 
 		// Create example config
-		if err := config.SaveExampleConfig(targetPath); err != nil {
-			return fmt.Errorf("\033[1;31m❌ Error creating configuration file: %w\033[0m", err)
+		if err := config.SaveExampleConfig(targetPath, config.AIProvider(initProvider)); err != nil {
+			return failure("Could not create the configuration file", err)
 		}
 
 		fmt.Println("\n\033[1;32m✓ Configuration Ready\033[0m")
@@ -133,12 +1307,40 @@ var initCmd = &cobra.Command{
 	},
 }
 
+// configCmd groups the commands that inspect the configuration
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+// configShowCmd prints the configuration with the file each setting came from
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the configuration and where each setting came from",
+	Long: `Show the settings read from the configuration files and git config, each annotated
+with its source. With --resolved, the full effective configuration is shown, including
+defaults. API keys are redacted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		data, err := cfg.ResolvedYAML(showResolved)
+		if err != nil {
+			return failure("Could not render the configuration", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	},
+}
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show the version information",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("\n\033[1;36mcommitron v0.1.0\033[0m")
+		fmt.Printf("\n\033[1;36mcommitron v%s\033[0m\n", version.Version)
 		fmt.Println("\n  \033[38;5;252m🤖 AI-powered commit message generator\033[0m")
 		fmt.Println("\n  \033[38;5;244mBuilt with ❤️ using Go\033[0m")
 	},
@@ -147,7 +1349,52 @@ var versionCmd = &cobra.Command{
 func init() {
 	// Add flags to generate command
 	generateCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Preview the commit message without creating a commit")
+	generateCmd.Flags().StringVar(&userContext, "context", "", "Extra context for the AI, such as why the change was made or why a commit is reverted")
+	generateCmd.Flags().BoolVar(&ignoreContextFile, "ignore-context-file", false, "Leave out the repository's context file (context.context_file) for this run")
+	generateCmd.Flags().BoolVar(&interactiveBody, "interactive-body", false, "Ask why you are making the change and write the body around your answer (skipped without a terminal)")
+	generateCmd.Flags().BoolVar(&bodyOnly, "body-only", false, "Print only the generated body (e.g. for a PR description) without committing")
+	generateCmd.Flags().StringVar(&fixedSubject, "subject", "", "With --body-only, keep this subject and generate only the body, then commit the full message")
+	generateCmd.Flags().StringVar(&outputFormat, "format", "text", "Output format for --body-only: text or json")
+	generateCmd.Flags().BoolVarP(&quick, "quick", "q", false, "Generate a subject-only message from a minimal prompt")
+	generateCmd.Flags().BoolVar(&amend, "amend", false, "Regenerate the message for the HEAD commit and amend it with the staged changes")
+	generateCmd.Flags().BoolVar(&resetAuthor, "reset-author", false, "With --amend, take over authorship and reset the author date")
+	generateCmd.Flags().BoolVarP(&edit, "edit", "e", false, "Open the generated message in your editor before committing")
+	generateCmd.Flags().BoolVarP(&stageAll, "all", "a", false, "Stage modified and untracked files (respects .gitignore)")
+	generateCmd.Flags().BoolVar(&stageTracked, "stage-all", false, "Stage modified tracked files before generating (same as ui.auto_stage)")
+	generateCmd.Flags().BoolVar(&push, "push", false, "Push the branch after committing (sets the upstream to origin if there is none)")
+	generateCmd.Flags().BoolVarP(&noVerify, "no-verify", "n", false, "Skip the pre-commit and commit-msg hooks (the pre-push hook still runs with --push)")
+	generateCmd.Flags().BoolVar(&forceBranch, "force-branch", false, "Commit even if the current branch is listed in git.protected_branches")
+	generateCmd.Flags().BoolVarP(&gpgSign, "gpg-sign", "S", false, "Sign the commit (not needed when git's commit.gpgsign is set)")
+	generateCmd.Flags().BoolVar(&copyMessage, "copy", false, "Also copy the final message to the clipboard (with --dry-run, copy without committing)")
+	generateCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Write the message to a file instead of committing")
+	generateCmd.Flags().BoolVar(&alsoCommit, "commit", false, "With --output-file, also create the commit")
+	generateCmd.Flags().StringVar(&fromPatch, "from-patch", "", "Print a message for a patch file instead of the staged changes (never commits)")
+	generateCmd.Flags().BoolVar(&fromStdin, "from-stdin", false, "Print a message for a patch read from stdin (never commits)")
+	generateCmd.Flags().StringVar(&patchesDir, "patches", "", "Generate a message for every .patch and .diff file in a directory (never commits)")
+	generateCmd.Flags().BoolVar(&writePatches, "write", false, "With --patches, write each message into its patch instead of printing it")
+	generateCmd.Flags().StringVar(&squashRange, "squash-range", "", "Print one message for squashing the commits in a range such as main..HEAD (never commits)")
+	generateCmd.Flags().BoolVar(&commitSquash, "commit-squash", false, "With --squash-range, also create the squashed commit on a new <branch>-squashed branch")
+	generateCmd.Flags().BoolVar(&diffOnly, "diff-only", false, "Print the processed diff context that would be sent, with token counts per stage, without calling the AI")
+	generateCmd.Flags().DurationVar(&timeout, "timeout", 0, "Give up when generating takes longer than this, e.g. 30s (0 = no limit)")
+	generateCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print the time spent in each stage (git, diff processing, token counting, provider, parsing)")
+	generateCmd.Flags().BoolVar(&selfTest, "self-test", false, "Send a small fixed diff to the configured provider to verify the key, model and endpoint")
+
+	// Plain "commitron" runs generate, so the root command accepts the same flags.
+	// The flags are shared, so both spellings set the same variables.
+	rootCmd.Flags().AddFlagSet(generateCmd.Flags())
+
+	// Add flags to stats command
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the statistics as JSON")
+
+	// Add flags for the config show command
+	configShowCmd.Flags().BoolVar(&showResolved, "resolved", false, "Show the full effective configuration, including defaults")
+	configCmd.AddCommand(configShowCmd)
 
 	// Add flags to init command
+	hookInstallCmd.Flags().BoolVarP(&forceHook, "force", "f", false, "Replace an existing prepare-commit-msg hook")
+	hookCmd.AddCommand(hookInstallCmd)
+
 	initCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing configuration file")
+	initCmd.Flags().StringVar(&initProvider, "provider", "openai", "Provider to set up in the new file: openai, claude, gemini or ollama")
+	initCmd.Flags().BoolVar(&legacy, "legacy", false, "Create the configuration at ~/.commitronrc instead of ~/.config/commitron/config.yaml")
 }
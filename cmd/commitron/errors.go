@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/ai"
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// errNoStagedFiles is returned when the working tree is clean
+var errNoStagedFiles = errors.New("no modified files found")
+
+// errNothingStaged is returned when nothing is staged and auto-staging is off
+var errNothingStaged = errors.New("no staged changes; stage files or use --stage-all")
+
+// errOnlyUntracked is returned when new files exist but none are tracked or staged
+var errOnlyUntracked = errors.New("only untracked files were found")
+
+// maxListedUntracked is the number of untracked files named in the error
+const maxListedUntracked = 10
+
+// untrackedError lists the untracked files that commitron did not stage
+func untrackedError(files []string) error {
+	var list strings.Builder
+	for i, file := range files {
+		if i == maxListedUntracked {
+			list.WriteString(fmt.Sprintf("\n  ... and %d more", len(files)-maxListedUntracked))
+			break
+		}
+		list.WriteString("\n  " + file)
+	}
+	return fmt.Errorf("%w:%s", errOnlyUntracked, list.String())
+}
+
+// cliError is a command failure with a short headline and the underlying cause
+type cliError struct {
+	headline string
+	err      error
+}
+
+func (e *cliError) Error() string {
+	if e.err == nil {
+		return e.headline
+	}
+	return e.headline + ": " + e.err.Error()
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+// failure wraps err with a headline; err may be nil
+func failure(headline string, err error) error {
+	return &cliError{headline: headline, err: err}
+}
+
+// errorHints maps known errors to a remediation hint, checked in order with errors.Is
+var errorHints = []struct {
+	target error
+	hint   string
+}{
+	{errNoStagedFiles, "make some changes before running commitron"},
+	{config.ErrUnknownKey, "fix or remove the key, or pass --no-strict-config to only warn about it"},
+	{ai.ErrAllExcluded, "commit them with a message you write, or narrow the patterns in .commitronignore or context.exclude_paths"},
+	{errNothingStaged, "set `ui.auto_stage: true` to always stage modified tracked files"},
+	{errOnlyUntracked, "stage them with `git add <file>`, or run `commitron --all` to include untracked files"},
+	{git.ErrEmptyRange, "check the order of the range: `main..feature` lists the commits on feature that main lacks"},
+	{git.ErrProtectedBranch, "switch to a feature branch, or pass --force-branch to commit here anyway"},
+	{git.ErrHookExists, "run `commitron hook install --force` to replace it, or call `commitron hook \"$@\"` from your existing hook"},
+	{git.ErrSigningFailed, "check user.signingkey and gpg.format in your git config and that your gpg or ssh agent is running"},
+	{config.ErrAPIKeyNotSet, "set ai.api_key in your config file (~/.config/commitron/config.yaml, ~/.commitronrc or the file passed with --config)"},
+	{ai.ErrSubjectTooLong, "commit it with your own edits (`git commit -e`), raise commit.max_length (or commit.max_subject_words), or set commit.on_length_violation: truncate"},
+	{ai.ErrRefused, "the diff may have tripped the provider's safety filter: write this message yourself (`git commit`), or try another ai.provider or ai.model"},
+	{ai.ErrUnparsedResponse, "retry, or use a model that follows the requested format; in a terminal commit.allow_raw_fallback lets you review and use the raw output"},
+	{ai.ErrAuthFailed, "check ai.api_key in your configuration, then run `commitron generate --self-test`"},
+	{ai.ErrTokenLimit, "split the change into smaller commits, set context.diff_strategy: batch, or lower context.max_input_tokens"},
+	{context.DeadlineExceeded, "raise --timeout, or use a faster model or a smaller diff (see --verbose for where the time went)"},
+	{ai.ErrProviderUnavailable, "check your network and ai.openai_endpoint / ai.ollama_host, then retry"},
+}
+
+// presentError prints a headline, an indented detail block and a "try:" hint.
+// Color is only used when w is a terminal and NO_COLOR is unset.
+func presentError(w io.Writer, err error) {
+	color := useColor(w)
+	paint := func(code, text string) string {
+		if !color {
+			return text
+		}
+		return "\033[" + code + "m" + text + "\033[0m"
+	}
+
+	headline := err.Error()
+	var detail string
+	var cerr *cliError
+	if errors.As(err, &cerr) && cerr.err != nil {
+		headline = cerr.headline
+		detail = cerr.err.Error()
+	}
+
+	fmt.Fprintln(w, paint("1;31", "❌ "+headline))
+	if detail != "" {
+		for _, line := range strings.Split(strings.TrimSpace(detail), "\n") {
+			fmt.Fprintln(w, "   "+line)
+		}
+	}
+
+	hint := ""
+	for _, h := range errorHints {
+		if errors.Is(err, h.target) {
+			hint = h.hint
+			break
+		}
+	}
+	if hint == "" && cerr == nil {
+		// Anything that isn't a command failure comes from argument parsing
+		hint = "run `commitron --help` for usage"
+	}
+	if hint != "" {
+		fmt.Fprintln(w, paint("38;5;244", "   try: "+hint))
+	}
+}
+
+// useColor reports whether ANSI colors should be written to w
+func useColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/johnstilia/commitron/pkg/ai"
+)
+
+// Scripted provider responses for the generate pipeline tests
+var (
+	validJSONResponse   = `{"type":"feat","scope":"parser","subject":"add empty input guard","body":"Return early when the input is empty."}`
+	chattyResponse      = "Sure! Here's a commit message for your changes:\n\nfix(parser): handle empty input\n\nReturn early when the input is empty."
+	longSubjectResponse = `{"type":"fix","scope":"parser","subject":"` + strings.Repeat("handle the empty input case ", 6) + `","body":"Return early when the input is empty."}`
+	missingBodyResponse = `{"type":"fix","scope":"parser","subject":"handle empty input"}`
+)
+
+func TestGenerateCommitsScriptedResponses(t *testing.T) {
+	conventions := []struct {
+		name   string
+		commit string
+		typed  bool // Whether the subject starts with "type(scope): "
+	}{
+		{"conventional", "  convention: conventional", true},
+		{"none", "  convention: none", false},
+		{"custom", "  convention: custom\n  custom_template: \"{{subject}}\"", false},
+	}
+	responses := []struct {
+		name     string
+		response string
+		subject  string // Expected subject without the type and scope, "" to only check the limit
+		body     string // Text the body must contain, "" for any non-empty body
+	}{
+		{"valid JSON", validJSONResponse, "add empty input guard", "Return early when the input is empty."},
+		{"chatty text", chattyResponse, "handle empty input", "Return early when the input is empty."},
+		{"over-length subject", longSubjectResponse, "", "Return early when the input is empty."},
+		{"missing body", missingBodyResponse, "handle empty input", ""},
+	}
+
+	typedSubject := regexp.MustCompile(`^(feat|fix)\(parser\): (.+)$`)
+	for _, convention := range conventions {
+		for _, tt := range responses {
+			t.Run(convention.name+"/"+tt.name, func(t *testing.T) {
+				newTestRepo(t)
+				writeFile(t, "parser.go", "package parser\n\nfunc Parse(input string) {}\n")
+				runGit(t, "add", "parser.go")
+				provider := newFakeProvider(t, tt.response)
+				config := writeTestConfig(t, provider, convention.commit+"\n  max_length: 72\n  include_body: true")
+
+				if output, err := runCommitron(t, "generate", "-c", config); err != nil {
+					t.Fatalf("generate failed: %v\n%s", err, output)
+				}
+				message := lastCommitMessage(t)
+				if message == "initial commit" {
+					t.Fatal("no commit was created")
+				}
+
+				subject, body, _ := strings.Cut(message, "\n\n")
+				if length := utf8.RuneCountInString(subject); length > 72 {
+					t.Errorf("subject is %d characters, over max_length 72: %q", length, subject)
+				}
+				if convention.typed {
+					match := typedSubject.FindStringSubmatch(subject)
+					if match == nil {
+						t.Fatalf("subject %q is not \"type(scope): subject\"", subject)
+					}
+					subject = match[2]
+				} else if typedSubject.MatchString(subject) {
+					t.Errorf("subject %q has a type prefix under convention %s", subject, convention.name)
+				}
+				if tt.subject != "" && !strings.EqualFold(subject, tt.subject) {
+					t.Errorf("subject = %q, want %q", subject, tt.subject)
+				}
+				if strings.TrimSpace(body) == "" || !strings.Contains(body, tt.body) {
+					t.Errorf("body = %q, want it to contain %q", body, tt.body)
+				}
+				if strings.Contains(message, "Sure!") {
+					t.Errorf("chatty preamble was committed: %q", message)
+				}
+			})
+		}
+	}
+}
+
+func TestRootAndGenerateDryRunMatch(t *testing.T) {
+	outputs := make(map[string]string)
+	prompts := make(map[string]string)
+	for _, args := range [][]string{{"-c", "CONFIG", "--dry-run"}, {"generate", "-c", "CONFIG", "--dry-run"}} {
+		name := strings.Join(args, " ")
+		newTestRepo(t)
+		writeFile(t, "parser.go", "package parser\n\nfunc Parse(input string) {}\n")
+		runGit(t, "add", "parser.go")
+		provider := newFakeProvider(t, validJSONResponse)
+		config := writeTestConfig(t, provider, "  convention: conventional")
+
+		withConfig := append([]string(nil), args...)
+		withConfig[len(withConfig)-2] = config
+		output, err := runCommitron(t, append(withConfig, "--deterministic")...)
+		if err != nil {
+			t.Fatalf("commitron %s failed: %v\n%s", name, err, output)
+		}
+		if provider.calls() != 1 {
+			t.Fatalf("commitron %s made %d provider calls, want 1", name, provider.calls())
+		}
+		if message := lastCommitMessage(t); message != "initial commit" {
+			t.Errorf("commitron %s committed %q in dry run mode", name, message)
+		}
+		if !strings.Contains(output, "Dry run completed") {
+			t.Errorf("commitron %s output lacks the dry run note:\n%s", name, output)
+		}
+		outputs[name] = strings.ReplaceAll(output, provider.server.URL, "URL")
+		prompts[name] = provider.prompts[0]
+	}
+
+	root, generate := "-c CONFIG --dry-run", "generate -c CONFIG --dry-run"
+	if outputs[root] != outputs[generate] {
+		t.Errorf("output differs\ncommitron %s:\n%s\ncommitron %s:\n%s", root, outputs[root], generate, outputs[generate])
+	}
+	if prompts[root] != prompts[generate] {
+		t.Errorf("prompt differs\ncommitron %s:\n%s\ncommitron %s:\n%s", root, prompts[root], generate, prompts[generate])
+	}
+}
+
+func TestGenerateOnLengthViolation(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		responses []string
+		calls     int    // Provider calls the run should make
+		subject   string // Committed subject, "" when the run must fail with ErrSubjectTooLong
+	}{
+		{"truncate", "truncate", []string{longSubjectResponse}, 1, ""},
+		{"retry succeeds", "retry", []string{longSubjectResponse, validJSONResponse}, 2, "feat(parser): add empty input guard"},
+		{"retry exhausted", "retry", []string{longSubjectResponse}, 3, ""},
+		{"error", "error", []string{longSubjectResponse, validJSONResponse}, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			writeFile(t, "parser.go", "package parser\n\nfunc Parse(input string) {}\n")
+			runGit(t, "add", "parser.go")
+			provider := newFakeProvider(t, tt.responses...)
+			config := writeTestConfig(t, provider, "  convention: conventional\n  max_length: 72\n  max_retries: 2\n  on_length_violation: "+tt.policy)
+
+			output, err := runCommitron(t, "generate", "-c", config)
+			if calls := provider.calls(); calls != tt.calls {
+				t.Errorf("provider was called %d times, want %d", calls, tt.calls)
+			}
+			message := lastCommitMessage(t)
+			subject, _, _ := strings.Cut(message, "\n")
+
+			switch {
+			case tt.policy == "truncate":
+				if err != nil {
+					t.Fatalf("generate failed: %v\n%s", err, output)
+				}
+				if length := utf8.RuneCountInString(subject); message == "initial commit" || length > 72 || !strings.HasSuffix(subject, "...") {
+					t.Errorf("subject = %q (%d characters), want one shortened to 72 with \"...\"", subject, length)
+				}
+			case tt.subject != "":
+				if err != nil {
+					t.Fatalf("generate failed: %v\n%s", err, output)
+				}
+				if subject != tt.subject {
+					t.Errorf("subject = %q, want %q", subject, tt.subject)
+				}
+				if !strings.Contains(provider.prompts[1], "YOUR PREVIOUS RESPONSE WAS REJECTED") {
+					t.Errorf("the retry prompt doesn't say why the first response was rejected:\n%s", provider.prompts[1])
+				}
+			default:
+				if !errors.Is(err, ai.ErrSubjectTooLong) {
+					t.Fatalf("error = %v, want ErrSubjectTooLong\n%s", err, output)
+				}
+				if message != "initial commit" {
+					t.Errorf("committed %q despite the length violation", message)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateOutputFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		content string // Expected file content, "" when the file must not be written
+		json    bool   // Whether the file holds the --format json object
+		wantErr bool
+	}{
+		{"dry run", []string{"--dry-run"}, "feat(parser): add empty input guard\n\n- Return early when the input is empty.\n", false, false},
+		{"dry run with commit", []string{"--dry-run", "--commit"}, "feat(parser): add empty input guard\n\n- Return early when the input is empty.\n", false, false},
+		{"body as JSON", []string{"--body-only", "--format", "json"}, "- Return early when the input is empty.", true, false},
+		{"body as JSON, dry run", []string{"--body-only", "--format", "json", "--dry-run"}, "- Return early when the input is empty.", true, false},
+		{"JSON without body only", []string{"--format", "json"}, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			writeFile(t, "parser.go", "package parser\n\nfunc Parse(input string) {}\n")
+			runGit(t, "add", "parser.go")
+			provider := newFakeProvider(t, validJSONResponse)
+			config := writeTestConfig(t, provider, "  convention: conventional\n  include_body: true")
+			path := filepath.Join(t.TempDir(), "message.txt")
+
+			output, err := runCommitron(t, append([]string{"generate", "-c", config, "--output-file", path}, tt.args...)...)
+			if message := lastCommitMessage(t); message != "initial commit" {
+				t.Errorf("committed %q", message)
+			}
+			data, readErr := os.ReadFile(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("generate succeeded, want an error\n%s", output)
+				}
+				if provider.calls() != 0 || readErr == nil {
+					t.Errorf("the provider was called %d times and the file was written despite the invalid flags", provider.calls())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate failed: %v\n%s", err, output)
+			}
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+
+			content := string(data)
+			if tt.json {
+				var body struct {
+					Body string `json:"body"`
+				}
+				if err := json.Unmarshal(data, &body); err != nil {
+					t.Fatalf("the file isn't JSON: %v\n%s", err, data)
+				}
+				content = body.Body
+				if strings.Contains(output, tt.content) {
+					t.Errorf("the body was printed as well as written:\n%s", output)
+				}
+			}
+			if content != tt.content {
+				t.Errorf("file content = %q, want %q", content, tt.content)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// fakeProvider is an OpenAI-compatible chat completions endpoint that answers with scripted
+// responses in order, repeating the last one once they run out
+type fakeProvider struct {
+	mu        sync.Mutex
+	responses []string
+	prompts   []string
+	server    *httptest.Server
+}
+
+func newFakeProvider(t *testing.T, responses ...string) *fakeProvider {
+	t.Helper()
+	p := &fakeProvider{responses: responses}
+	p.server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeProvider) serve(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	json.Unmarshal(body, &request)
+
+	p.mu.Lock()
+	var prompt strings.Builder
+	for _, message := range request.Messages {
+		prompt.WriteString(message.Content + "\n")
+	}
+	p.prompts = append(p.prompts, prompt.String())
+	content := p.responses[min(len(p.prompts), len(p.responses))-1]
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": content}}},
+		"usage":   map[string]int{"prompt_tokens": 100, "completion_tokens": 20, "total_tokens": 120},
+	})
+}
+
+// calls returns how many requests the provider has answered
+func (p *fakeProvider) calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.prompts)
+}
+
+// newTestRepo creates a git repository on a feature branch with one commit, makes it the
+// working directory and isolates commitron's state and git's global config from the user's
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".state"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+
+	runGit(t, "init", "-q", "-b", "main")
+	runGit(t, "config", "user.name", "Test")
+	runGit(t, "config", "user.email", "test@example.com")
+	runGit(t, "config", "commit.gpgsign", "false")
+	writeFile(t, "README.md", "# test\n")
+	runGit(t, "add", "README.md")
+	runGit(t, "commit", "-q", "-m", "initial commit")
+	runGit(t, "checkout", "-q", "-b", "feature")
+	return dir
+}
+
+// runGit runs git in the working directory and returns its output
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// writeFile writes content to a path relative to the working directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestConfig writes a config for provider with the TUI and confirmation off, followed
+// by the commit settings in commit (YAML lines under "commit:"), and returns its path
+func writeTestConfig(t *testing.T, provider *fakeProvider, commit string) string {
+	t.Helper()
+	config := fmt.Sprintf(`ai:
+  provider: openai
+  api_key: test-key
+  model: gpt-4o
+  openai_endpoint: %s/v1/chat/completions
+  stream: false
+ui:
+  enable_tui: false
+  confirm_commit: false
+context:
+  token_estimator: chars
+commit:
+%s
+`, provider.server.URL, commit)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// resetFlags puts every flag back to its default, since cobra keeps the values of earlier runs
+func resetFlags() {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	rootCmd.PersistentFlags().VisitAll(reset)
+	rootCmd.Flags().VisitAll(reset)
+	generateCmd.Flags().VisitAll(reset)
+}
+
+// runCommitron runs the command line args through cobra as main does and returns what was
+// printed to stdout
+func runCommitron(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	resetFlags()
+	rootCmd.SetArgs(args)
+
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = write
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(read)
+		output <- string(data)
+	}()
+
+	_, err = rootCmd.ExecuteC()
+	write.Close()
+	os.Stdout = stdout
+	return <-output, err
+}
+
+// lastCommitMessage returns the message of HEAD as git stores it
+func lastCommitMessage(t *testing.T) string {
+	t.Helper()
+	return strings.TrimSpace(runGit(t, "log", "-1", "--format=%B"))
+}
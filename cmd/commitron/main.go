@@ -1,43 +1,54 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
+	"github.com/johnstilia/commitron/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 // Flags that are used across commands
-var configPath string
+var configPaths []string
+var noStrictConfig bool
+var logFormat string
+var deterministic bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "commitron",
 	Short: "AI-powered commit message generator",
 	Long:  `Commitron is a CLI tool that generates AI-powered commit messages based on your staged changes in a git repository.`,
-	// This is the default command when none is provided
-	Run: func(cmd *cobra.Command, args []string) {
-		// Run the generate command when no command is specified
-		if err := generateCmd.RunE(cmd, args); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
+	// This is the default command when none is provided; it accepts generate's flags (see commands.go)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCmd.RunE(cmd, args)
 	},
 }
 
 func init() {
+	// Errors are rendered by presentError and shouldn't be buried under the usage text
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
 	// Global flags available to all commands
-	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to the configuration file (default: ~/.commitronrc)")
+	rootCmd.PersistentFlags().StringArrayVarP(&configPaths, "config", "c", nil, "Path to the configuration file, repeatable with later files overriding earlier ones (default: ~/.config/commitron/config.yaml or ~/.commitronrc)")
+	rootCmd.PersistentFlags().BoolVar(&noStrictConfig, "no-strict-config", false, "Warn about unknown configuration keys instead of failing")
+	rootCmd.PersistentFlags().BoolVar(&deterministic, "deterministic", false, "Reproducible output for demos and snapshot tests: temperature 0, a fixed seed where supported, no timestamps or timings")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", config.LogFormatText, "Format of ai.debug output: text (framed blocks on stdout) or json (one object per line on stderr)")
 
 	// Add all commands
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(hookCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(limitsCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
 func main() {
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
+		presentError(os.Stderr, err)
 		os.Exit(1)
 	}
 }
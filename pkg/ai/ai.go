@@ -3,15 +3,20 @@ package ai
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
 	"github.com/johnstilia/commitron/pkg/tokenizer"
 	"github.com/johnstilia/commitron/pkg/ui"
 )
@@ -98,8 +103,10 @@ const (
 				"type": "",
 				"scope": "",
 				"subject": "",
-				"body": ""
-			}
+				"body": "",
+				"footers": [{"token": "", "value": ""}]
+			},
+			"footers_note": "Only add footers that the changes call for, e.g. BREAKING CHANGE, Refs or Closes; otherwise return an empty list"
 		}
 	}`
 
@@ -193,10 +200,36 @@ Conventional Commits 1.0.0 Rules:
 
 // CommitMessage represents a structured commit message
 type CommitMessage struct {
-	Type    string `json:"type"`
-	Scope   string `json:"scope"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
+	Type    string   `json:"type"`
+	Scope   string   `json:"scope"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	Footers []Footer `json:"footers,omitempty"`
+}
+
+// Footer is a conventional commit footer such as "BREAKING CHANGE: ..." or "Refs: #123"
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+// UnmarshalJSON accepts {"token": ..., "value": ...} as well as a plain "Token: value" string,
+// which models return often enough that it shouldn't fail the whole response
+func (f *Footer) UnmarshalJSON(data []byte) error {
+	var line string
+	if err := json.Unmarshal(data, &line); err == nil {
+		token, value, _ := strings.Cut(line, ":")
+		f.Token, f.Value = strings.TrimSpace(token), strings.TrimSpace(value)
+		return nil
+	}
+
+	type footer Footer // Avoids recursing into this method
+	var parsed footer
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	*f = Footer(parsed)
+	return nil
 }
 
 // EnhancedFileInfo contains detailed information about a changed file
@@ -213,10 +246,20 @@ type EnhancedFileInfo struct {
 // FormatCommitMessage formats a CommitMessage into a string according to the configuration
 func FormatCommitMessage(msg CommitMessage, cfg *config.Config) string {
 	var result strings.Builder
+	msg.Subject = strings.TrimSpace(normalizeNewlines(msg.Subject))
+	msg.Body = normalizeNewlines(msg.Body)
+	if structuredNone(cfg) {
+		msg = structureMessage(msg)
+	}
 
 	// Format the subject line according to convention
+	result.WriteString(expandSubjectTemplate(cfg.Commit.SubjectPrefix))
+	if prefix := subjectPackagePrefix(cfg); prefix != "" {
+		result.WriteString(prefix)
+		msg.Subject = packageSubject(msg.Subject, cfg.Commit.SubjectPackage)
+	}
 	switch cfg.Commit.Convention {
-	case config.ConventionalCommits:
+	case config.ConventionalCommits, config.AngularConvention:
 		if msg.Scope != "" {
 			result.WriteString(fmt.Sprintf("%s(%s): %s", msg.Type, msg.Scope, msg.Subject))
 		} else {
@@ -228,39 +271,233 @@ func FormatCommitMessage(msg CommitMessage, cfg *config.Config) string {
 	default:
 		result.WriteString(msg.Subject)
 	}
+	result.WriteString(expandSubjectTemplate(cfg.Commit.SubjectSuffix))
 
 	// Add body if configured and provided - format as bullet points
 	if cfg.Commit.IncludeBody && msg.Body != "" {
-		result.WriteString("\n\n")
-		
-		// Format body as bullet points if it's not already formatted
-		bodyLines := strings.Split(strings.TrimSpace(msg.Body), "\n")
-		for _, line := range bodyLines {
-			line = strings.TrimSpace(line)
-			if line != "" {
-				// Add bullet point if not already present
-				if !strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "* ") {
-					result.WriteString("- ")
+		result.WriteString("\n\n" + formatBody(msg.Body))
+	}
+
+	// Footers and the model trailer share the final paragraph so git reads them as trailers
+	var trailers []string
+	for _, footer := range msg.Footers {
+		token := strings.TrimSpace(footer.Token)
+		value := strings.TrimSpace(normalizeNewlines(footer.Value))
+		if token == "" || value == "" {
+			continue
+		}
+		if token == "BREAKING-CHANGE" {
+			token = "BREAKING CHANGE"
+		}
+		trailers = append(trailers, fmt.Sprintf("%s: %s", token, value))
+	}
+	if cfg.Commit.RecordModel {
+		trailers = append(trailers, fmt.Sprintf("%s: %s/%s", ModelTrailer, cfg.AI.Provider, cfg.AI.Model))
+	}
+	if trailer := attributionTrailer(cfg); trailer != "" {
+		trailers = append(trailers, trailer)
+	}
+	if len(trailers) > 0 {
+		result.WriteString("\n\n" + strings.Join(trailers, "\n"))
+	}
+
+	return SanitizeMessage(result.String())
+}
+
+// formatBody formats the body as bullet points, keeping lines that already are bullets.
+// Lines the model wrapped itself are joined first so a sentence becomes one bullet.
+func formatBody(body string) string {
+	var lines []string
+	for _, line := range unwrapBody(body) {
+		if !strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "* ") {
+			line = "- " + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listMarkerPattern matches the marker of a bulleted or numbered list item
+var listMarkerPattern = regexp.MustCompile(`^([-*+•]|[0-9]+[.)])\s`)
+
+// unwrapBody splits a body into logical items, joining soft-wrapped lines. A line continues
+// the previous one unless that one ends with sentence punctuation, the line starts a list
+// item, or a blank line separates them.
+func unwrapBody(body string) []string {
+	var items []string
+	continues := false
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continues = false
+			continue
+		}
+		if continues && !listMarkerPattern.MatchString(line) {
+			items[len(items)-1] += " " + line
+		} else {
+			items = append(items, line)
+		}
+		continues = !strings.ContainsAny(line[len(line)-1:], ".!?:;")
+	}
+	return items
+}
+
+// ModelTrailer is the git trailer token used by commit.record_model
+const ModelTrailer = "Commitron-Model"
+
+// ticketPattern matches issue tracker keys such as ABC-123 in branch names
+var ticketPattern = regexp.MustCompile(`[A-Z][A-Z0-9]+-[0-9]+`)
+
+// expandSubjectTemplate replaces {{ticket}}, {{branch}} and {{date}} in a subject prefix/suffix template
+func expandSubjectTemplate(template string) string {
+	if template == "" || !strings.Contains(template, "{{") {
+		return template
+	}
+
+	branch, _ := git.CurrentBranch()
+	ticket := branchTicket(branch)
+
+	replacer := strings.NewReplacer(
+		"{{ticket}}", ticket,
+		"{{branch}}", branch,
+		"{{date}}", time.Now().Format("2006-01-02"),
+	)
+	return replacer.Replace(template)
+}
+
+// branchTicket extracts an issue tracker key such as ABC-123 from a branch name
+func branchTicket(branch string) string {
+	return ticketPattern.FindString(strings.ToUpper(branch))
+}
+
+// branchContext describes the current branch as a hint for the prompt.
+// Returns "" on a detached HEAD or the default branch, where the name says nothing about the change.
+func branchContext() string {
+	branch, err := git.CurrentBranch()
+	if err != nil || branch == "" || git.IsDefaultBranch(branch) {
+		return ""
+	}
+
+	hint := fmt.Sprintf("\nCurrent branch: %s", branch)
+	if ticket := branchTicket(branch); ticket != "" {
+		hint += fmt.Sprintf(" (ticket %s)", ticket)
+	}
+	return hint + "\nThe branch name often reflects the intent of the change; use it as a hint for the type, scope and subject, but describe what the diff actually does."
+}
+
+// subjectLineLength returns the rune length of "type(scope): subject", without the prefix/suffix
+func subjectLineLength(msg CommitMessage, cfg *config.Config) int {
+	length := utf8.RuneCountInString(strings.TrimSpace(msg.Subject))
+	if cfg.Commit.Convention.IsConventional() && msg.Type != "" {
+		length += utf8.RuneCountInString(msg.Type) + 2 // ": "
+		if msg.Scope != "" {
+			length += utf8.RuneCountInString(msg.Scope) + 2 // "()"
+		}
+	}
+	return length
+}
+
+// formatWithinLimit formats msg and, if the first line still exceeds maxLength runes, shortens
+// only the subject component (never the type, scope or prefix/suffix) and formats it again
+func formatWithinLimit(msg CommitMessage, cfg *config.Config, maxLength int) string {
+	formatted := FormatCommitMessage(msg, cfg)
+	if maxLength <= 0 {
+		return formatted
+	}
+
+	for {
+		firstLine, _, _ := strings.Cut(formatted, "\n")
+		over := utf8.RuneCountInString(firstLine) - maxLength
+		subject := []rune(strings.TrimSpace(msg.Subject))
+		if over <= 0 || len(subject) == 0 {
+			return formatted
+		}
+
+		// Cut enough for the overflow and the "..." marker, preferring a word boundary
+		keep := len(subject) - over - 3
+		if keep <= 0 {
+			debugPrint(cfg, "FINAL LENGTH", fmt.Sprintf("no room for a subject within %d characters", maxLength))
+			return formatted
+		}
+		for i := keep; i > keep-10 && i > 0; i-- {
+			if subject[i] == ' ' {
+				keep = i
+				break
+			}
+		}
+		msg.Subject = strings.TrimRight(string(subject[:keep]), " ,;") + "..."
+
+		debugPrint(cfg, "FINAL LENGTH", fmt.Sprintf("first line was %d characters over %d, subject shortened", over, maxLength))
+		formatted = FormatCommitMessage(msg, cfg)
+	}
+}
+
+// fitTotalLength shortens body until the message built by format is at most maxTotal
+// runes. Only the body is cut, so a message may stay over the limit when the subject
+// and footers alone exceed it.
+func fitTotalLength(cfg *config.Config, body string, maxTotal int, format func(body string) string) string {
+	formatted := format(body)
+	for maxTotal > 0 {
+		over := utf8.RuneCountInString(formatted) - maxTotal
+		runes := []rune(strings.TrimSpace(body))
+		if over <= 0 || len(runes) == 0 {
+			break
+		}
+
+		keep := len(runes) - over - 3
+		if keep <= 0 {
+			body = ""
+		} else {
+			for i := keep; i > keep-20 && i > 0; i-- {
+				if runes[i] == ' ' || runes[i] == '\n' {
+					keep = i
+					break
 				}
-				result.WriteString(line)
-				result.WriteString("\n")
 			}
+			body = strings.TrimRight(string(runes[:keep]), " ,;\n") + "..."
 		}
-		// Remove trailing newline
-		resultStr := result.String()
-		result.Reset()
-		result.WriteString(strings.TrimSuffix(resultStr, "\n"))
+
+		debugPrint(cfg, "TOTAL LENGTH", fmt.Sprintf("message was %d characters over %d, body shortened", over, maxTotal))
+		formatted = format(body)
+	}
+	return formatted
+}
+
+// subjectAffixLength returns the number of subject characters taken by the expanded prefix and suffix
+func subjectAffixLength(cfg *config.Config) int {
+	return utf8.RuneCountInString(expandSubjectTemplate(cfg.Commit.SubjectPrefix)+subjectPackagePrefix(cfg)) +
+		utf8.RuneCountInString(expandSubjectTemplate(cfg.Commit.SubjectSuffix))
+}
+
+// subjectAffixInstruction tells the model about text that is added to the subject automatically
+func subjectAffixInstruction(cfg *config.Config) string {
+	prefix := expandSubjectTemplate(cfg.Commit.SubjectPrefix) + subjectPackagePrefix(cfg)
+	suffix := expandSubjectTemplate(cfg.Commit.SubjectSuffix)
+	if prefix == "" && suffix == "" {
+		return ""
 	}
 
-	return result.String()
+	var parts []string
+	if prefix != "" {
+		parts = append(parts, fmt.Sprintf("the prefix %q", prefix))
+	}
+	if suffix != "" {
+		parts = append(parts, fmt.Sprintf("the suffix %q", suffix))
+	}
+	return fmt.Sprintf("NOTE: %s will be added to the subject automatically and %d characters are reserved for it. DO NOT include it in your response.",
+		strings.Join(parts, " and "), subjectAffixLength(cfg))
 }
 
 // GenerateTextPrompt creates a natural language prompt for commit message generation
 // This function generates a more human-readable prompt compared to the JSON template approach
 func GenerateTextPrompt(cfg *config.Config, files []string, changes string) string {
+	if prompt := customPrompt(cfg, files, changes); prompt != "" {
+		return prompt
+	}
+
 	// Determine the commit convention type
 	conventionType := ""
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		conventionType = "conventional"
 	}
 
@@ -280,14 +517,33 @@ func GenerateTextPrompt(cfg *config.Config, files []string, changes string) stri
 	}
 
 	// Add specific format requirements for conventional commits first to emphasize importance
-	if cfg.Commit.Convention == config.ConventionalCommits {
-		prompts = append(prompts, "YOUR RESPONSE MUST START WITH A CONVENTIONAL COMMIT TYPE. Valid types are: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert.")
-		prompts = append(prompts, "Format MUST BE: type(optional-scope): subject")
-		prompts = append(prompts, "Example: fix(parser): correct array parsing issue")
+	if cfg.Commit.Convention.IsConventional() {
+		prompts = append(prompts, "YOUR RESPONSE MUST START WITH A CONVENTIONAL COMMIT TYPE. Valid types are: "+commitTypeList(cfg)+".")
+		prompts = append(prompts, fmt.Sprintf("Format MUST BE: %s", scopeFormat(cfg)))
+		if cfg.Commit.Scope == config.ScopeForbidden {
+			prompts = append(prompts, "Example: fix: correct array parsing issue")
+		} else {
+			prompts = append(prompts, "Example: fix(parser): correct array parsing issue")
+		}
+		if instruction := scopeInstruction(cfg); instruction != "" {
+			prompts = append(prompts, instruction)
+		}
+		if cfg.Commit.Convention == config.AngularConvention {
+			prompts = append(prompts, "Follow the Angular commit guidelines: use the imperative, present tense ('change' not 'changed' nor 'changes'), do not capitalize the first letter and do not end the subject with a period.")
+		}
+		if instruction := typeConstraintInstruction(files, cfg); instruction != "" {
+			prompts = append(prompts, instruction)
+		}
 		prompts = append(prompts, "DO NOT START YOUR RESPONSE WITH A COLON. The type MUST come first, followed by colon.")
 	}
 
 	prompts = append(prompts, fmt.Sprintf("CRITICAL: Commit message subject MUST NOT exceed %d characters total. YOU MUST COUNT THE CHARACTERS YOURSELF AND ENSURE THE TOTAL IS UNDER %d. This is a HARD REQUIREMENT.", cfg.Commit.MaxLength, cfg.Commit.MaxLength))
+	if instruction := subjectWordsInstruction(cfg); instruction != "" {
+		prompts = append(prompts, instruction)
+	}
+	if instruction := subjectAffixInstruction(cfg); instruction != "" {
+		prompts = append(prompts, instruction)
+	}
 
 	// Add body instructions based on configuration
 	if cfg.Commit.IncludeBody {
@@ -312,7 +568,7 @@ func GenerateTextPrompt(cfg *config.Config, files []string, changes string) stri
 	prompts = append(prompts, "- Start your response immediately with the commit type (e.g., 'fix:', 'feat:', etc.)")
 
 	// Add conventional commit rules if using that convention
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		prompts = append(prompts, "You MUST follow these conventional commit rules:")
 		prompts = append(prompts, ConventionalCommitRules)
 	}
@@ -325,6 +581,14 @@ func GenerateTextPrompt(cfg *config.Config, files []string, changes string) stri
 	// Add format specification
 	if format, ok := CommitTypeFormats[conventionType]; ok {
 		formatExample := format
+		if conventionType == "conventional" {
+			switch cfg.Commit.Scope {
+			case config.ScopeRequired:
+				formatExample = "<type>(<scope>): <commit message>"
+			case config.ScopeForbidden:
+				formatExample = "<type>: <commit message>"
+			}
+		}
 		if cfg.Commit.IncludeBody {
 			formatExample += "\n\n<descriptive body explanation>"
 		}
@@ -332,11 +596,12 @@ func GenerateTextPrompt(cfg *config.Config, files []string, changes string) stri
 	}
 
 	// Add specific limit instructions for conventional commits
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		prompts = append(prompts, fmt.Sprintf("For conventional commits: CRITICAL AND MOST IMPORTANT INSTRUCTION: TOTAL length of 'type(scope): subject' MUST BE STRICTLY LESS THAN %d characters. Count all characters including type, scope, colons, spaces, and subject text. Keep subject extremely brief to ensure total length stays under %d.", cfg.Commit.MaxLength, cfg.Commit.MaxLength))
-		prompts = append(prompts, fmt.Sprintf("Examples of good length subjects:\n- fix: update validation logic (%d chars)\n- feat(auth): add login timeout (%d chars)",
+		prompts = append(prompts, fmt.Sprintf("Examples of good length subjects:\n- fix: update validation logic (%d chars)\n- %s (%d chars)",
 			len("fix: update validation logic"),
-			len("feat(auth): add login timeout")))
+			scopedExample(cfg),
+			len(scopedExample(cfg))))
 	}
 
 	// Add guidance for analyzing the diff
@@ -383,6 +648,55 @@ When analyzing the code changes:
 		}
 	}
 
+	// Name the tests touched by a full diff; summarized files already list them
+	if cfg.Context.IncludeDiff {
+		if tests := testsSection(changes); tests != "" {
+			prompts = append(prompts, tests)
+		}
+		if hint := commentOnlyHint(changes, cfg.Commit.Convention.IsConventional()); hint != "" {
+			prompts = append(prompts, hint)
+		}
+		if issues := issuesSection(cfg, changes); issues != "" {
+			prompts = append(prompts, issues)
+		}
+	}
+	if deletions := deletionsSection(cfg); deletions != "" {
+		prompts = append(prompts, deletions)
+	}
+
+	// Context the author passed with --context, after the repository's standing context
+	if section := contextFileSection(cfg); section != "" {
+		prompts = append(prompts, section)
+	}
+	if cfg.Context.UserContext != "" {
+		prompts = append(prompts, "\nContext from the author (use it to explain why): "+cfg.Context.UserContext)
+	}
+	if intent := intentSection(cfg); intent != "" {
+		prompts = append(prompts, intent)
+	}
+
+	// The commits a squash replaces
+	if squash := squashSection(cfg); squash != "" {
+		prompts = append(prompts, squash)
+	}
+
+	// Domain terms the model should use as the project does
+	if glossary := glossarySection(cfg); glossary != "" {
+		prompts = append(prompts, glossary)
+	}
+
+	// The project's own commits anchor tone and format better than the rules above
+	if examples := examplesSection(cfg); examples != "" {
+		prompts = append(prompts, examples)
+	}
+
+	// Add the branch name as a cheap hint about intent
+	if cfg.Context.IncludeBranchName {
+		if branch := branchContext(); branch != "" {
+			prompts = append(prompts, branch)
+		}
+	}
+
 	// Add repository structure if enabled (as secondary context)
 	if cfg.Context.IncludeRepoStructure {
 		repoStructure, err := GetRepoStructure(cfg)
@@ -397,6 +711,9 @@ When analyzing the code changes:
 		if err == nil && len(enhancedInfos) > 0 {
 			// Add detailed file information section
 			prompts = append(prompts, "\nFile changes in detail:")
+			if summary := directorySummary(cfg, files); summary != "" {
+				prompts = append(prompts, summary)
+			}
 
 			for _, info := range enhancedInfos {
 				fileDetails := []string{fmt.Sprintf("* %s", info.Path)}
@@ -411,8 +728,10 @@ When analyzing the code changes:
 				// Add change statistics
 				if cfg.Context.IncludeFileStats && (info.AddedLines > 0 || info.RemovedLines > 0) {
 					fileDetails = append(fileDetails, fmt.Sprintf("  Changes: +%d/-%d lines", info.AddedLines, info.RemovedLines))
-					if info.PercentageChange != "" {
+					if strings.HasSuffix(info.PercentageChange, "%") {
 						fileDetails = append(fileDetails, fmt.Sprintf("  Modified: %s of file", info.PercentageChange))
+					} else if info.PercentageChange != "" {
+						fileDetails = append(fileDetails, fmt.Sprintf("  Modified: %s", info.PercentageChange))
 					}
 				}
 
@@ -427,7 +746,11 @@ When analyzing the code changes:
 		}
 	} else if cfg.Context.IncludeFileNames {
 		// Just add the file names if detailed info is not enabled
-		prompts = append(prompts, fmt.Sprintf("\nFiles changed:\n%s", strings.Join(files, "\n")))
+		prompts = append(prompts, "\nFiles changed:")
+		if summary := directorySummary(cfg, files); summary != "" {
+			prompts = append(prompts, summary)
+		}
+		prompts = append(prompts, strings.Join(files, "\n"))
 	}
 
 	// Final constraint to ensure clean output
@@ -436,6 +759,39 @@ When analyzing the code changes:
 	return strings.Join(prompts, "\n")
 }
 
+// quickModeDiffTokens is the diff budget used in quick mode
+const quickModeDiffTokens = 2000
+
+// GenerateQuickPrompt creates a minimal prompt asking only for a subject line
+func GenerateQuickPrompt(cfg *config.Config, files []string, changes string) string {
+	prompts := []string{
+		"Write a git commit subject line for the changes below. Output ONLY the subject line, nothing else.",
+		fmt.Sprintf("Use present tense and keep it under %d characters.", cfg.Commit.MaxLength),
+	}
+
+	if cfg.Commit.Convention.IsConventional() {
+		prompts = append(prompts, fmt.Sprintf("Format: %s. Valid types are: %s.", scopeFormat(cfg), commitTypeList(cfg)))
+		if instruction := scopeInstruction(cfg); instruction != "" {
+			prompts = append(prompts, instruction)
+		}
+	}
+	if instruction := subjectAffixInstruction(cfg); instruction != "" {
+		prompts = append(prompts, instruction)
+	}
+	if glossary := glossarySection(cfg); glossary != "" {
+		prompts = append(prompts, glossary)
+	}
+
+	if cfg.Context.IncludeFileNames {
+		prompts = append(prompts, fmt.Sprintf("\nFiles changed:\n%s", strings.Join(files, "\n")))
+	}
+	if cfg.Context.IncludeDiff {
+		prompts = append(prompts, fmt.Sprintf("\nGit Diff:\n```\n%s\n```", changes))
+	}
+
+	return strings.Join(prompts, "\n")
+}
+
 // ParseCommitMessageJSON attempts to parse a JSON response into a CommitMessage struct
 func ParseCommitMessageJSON(response string) (CommitMessage, error) {
 	var msg CommitMessage
@@ -474,34 +830,97 @@ func ParseCommitMessageJSON(response string) (CommitMessage, error) {
 	return extractedMsg, nil
 }
 
-// extractJSON attempts to extract a JSON object from text that might contain other content
+// extractJSON extracts the commit message JSON object from a response that may wrap it in
+// prose, code fences or an array. Returns "" when no object has a type or subject key.
 func extractJSON(text string) string {
-	// Look for JSON object start and end
-	start := strings.Index(text, "{")
-	if start == -1 {
-		return ""
+	// Prefer fenced blocks, then the whole response, then each balanced object in order,
+	// accepting the first candidate that looks like a commit message
+	var candidates []string
+	for _, match := range codeFencePattern.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, match[1])
 	}
+	candidates = append(candidates, text)
 
-	// Find matching closing brace
-	depth := 1
-	for end := start + 1; end < len(text); end++ {
-		switch text[end] {
-		case '{':
+	for _, candidate := range candidates {
+		if object, ok := commitJSONObject(candidate); ok {
+			return object
+		}
+	}
+
+	for start := strings.IndexByte(text, '{'); start != -1; {
+		if object, ok := balancedObject(text, start); ok {
+			if _, ok := commitJSONObject(object); ok {
+				return object
+			}
+		}
+		next := strings.IndexByte(text[start+1:], '{')
+		if next == -1 {
+			break
+		}
+		start += next + 1
+	}
+
+	return ""
+}
+
+// codeFencePattern matches markdown code fences, optionally tagged as json
+var codeFencePattern = regexp.MustCompile("(?s)```(?:json|JSON)?[ \t]*\n?(.*?)```")
+
+// commitJSONObject returns candidate as a JSON object if it has a type or subject key.
+// A JSON array yields its first object.
+func commitJSONObject(candidate string) (string, bool) {
+	candidate = strings.TrimSpace(candidate)
+
+	if strings.HasPrefix(candidate, "[") {
+		var items []json.RawMessage
+		if err := json.Unmarshal([]byte(candidate), &items); err != nil || len(items) == 0 {
+			return "", false
+		}
+		candidate = strings.TrimSpace(string(items[0]))
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(candidate), &fields); err != nil {
+		return "", false
+	}
+	_, hasType := fields["type"]
+	_, hasSubject := fields["subject"]
+	if !hasType && !hasSubject {
+		return "", false
+	}
+	return candidate, true
+}
+
+// balancedObject returns the object starting at text[start], skipping braces inside strings
+func balancedObject(text string, start int) (string, bool) {
+	depth := 0
+	inString := false
+	escaped := false
+	for end := start; end < len(text); end++ {
+		c := text[end]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
 			depth++
-		case '}':
+		case c == '}':
 			depth--
 			if depth == 0 {
-				return text[start : end+1]
+				return text[start : end+1], true
 			}
 		}
 	}
-
-	return ""
+	return "", false
 }
 
 // parseTextCommitMessage attempts to parse a plain text commit message
 func parseTextCommitMessage(text string) CommitMessage {
-	lines := strings.Split(text, "\n")
+	lines := strings.Split(normalizeNewlines(text), "\n")
 	msg := CommitMessage{}
 
 	// Look for [SUBJECT] and [BODY] markers
@@ -840,13 +1259,13 @@ func DisplayCommitMessage(commitMsg string) (bool, error) {
 
 // DisplayAnalysisComplete prints a completion message
 func DisplayAnalysisComplete() {
-	fmt.Println("\033[1;32m✓ Analysis complete\033[0m\n")
+	fmt.Print("\033[1;32m✓ Analysis complete\033[0m\n\n")
 }
 
 // GetGitDiff returns clean git diff output for the staged files
 func GetGitDiff(files []string) (string, error) {
 	// Get clean git diff output without extra headers
-	cmd := exec.Command("git", "diff", "--staged")
+	cmd := git.DiffCommand("--staged")
 	diffOutput, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("error getting git diff: %w", err)
@@ -855,76 +1274,122 @@ func GetGitDiff(files []string) (string, error) {
 	return string(diffOutput), nil
 }
 
-// GenerateCommitMessage generates a commit message using the configured AI provider
-func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (string, error) {
-	// Display staged files in TUI format if enabled
-	if cfg.UI.EnableTUI {
-		DisplayStagedFiles(files)
+// ContextStage records the size of the diff context after one processing stage
+type ContextStage struct {
+	Name   string // Pipeline stage, e.g. "sanitized" or "summarize strategy"
+	Tokens int    // Tokens in the context after the stage
+	Detail string // What the stage did
+}
+
+// diffContext is the processed diff that is sent to the model
+type diffContext struct {
+	Changes        string
+	Tokens         int
+	TokenizerModel string
+	MaxTokens      int            // Input token limit of the model
+	Stages         []ContextStage // Only recorded when tracing
+	Small          bool           // Took the small diff fast path; token counts are estimates
+	Deleted        []string       // Files the diff deletes, before any strategy could drop them
+}
+
+// smallDiffBytes is the size below which a diff skips tokenization. A token always covers
+// at least one byte, so a diff this small can't exceed the budget for changes. It is a
+// variable so benchmarks can turn the fast path off.
+var smallDiffBytes = 2048
+
+// countTokens counts tokens with the encoder, or estimates them for small diffs so the
+// encoder is never loaded on the fast path
+func (d diffContext) countTokens(text string) int {
+	if d.Small {
+		return tokenizer.EstimateTokens(text)
 	}
+	return tokenizer.CountTokens(text, d.TokenizerModel)
+}
 
-	// Get more detailed git diff if requested
-	var detailedDiff string
-	var err error
-	if cfg.Context.IncludeDiff {
-		detailedDiff, err = GetGitDiff(files)
-		if err == nil && detailedDiff != "" {
-			// Use the detailed diff instead of the basic changes
-			changes = detailedDiff
+// generationConfig applies the quick mode and subject affix adjustments to a copy of cfg
+func generationConfig(cfg *config.Config) *config.Config {
+	// Quick mode trades detail for speed: no body and no extra file context
+	if cfg.Commit.QuickMode {
+		quickCfg := *cfg
+		quickCfg.Commit.IncludeBody = false
+		quickCfg.Context.IncludeFileStats = false
+		quickCfg.Context.IncludeFileSummaries = false
+		quickCfg.Context.ShowFirstLinesOfFile = 0
+		quickCfg.Context.IncludeRepoStructure = false
+		if quickCfg.Context.DiffStrategy == "" || quickCfg.Context.DiffStrategy == "auto" {
+			quickCfg.Context.DiffStrategy = "summarize"
 		}
+		cfg = &quickCfg
 	}
 
-	// Token-aware processing
-	tokenizerModel := cfg.Context.TokenizerModel
-	if tokenizerModel == "" {
-		tokenizerModel = cfg.AI.Model // Default to AI model
+	// Reserve room for the subject prefix/suffix so the final subject line still respects max_length
+	if reserved := subjectAffixLength(cfg); reserved > 0 {
+		budgetCfg := *cfg
+		budgetCfg.Commit.MaxLength = max(cfg.Commit.MaxLength-reserved, 10)
+		cfg = &budgetCfg
+		debugPrint(cfg, "SUBJECT AFFIX", fmt.Sprintf("%d characters reserved, subject budget is %d", reserved, cfg.Commit.MaxLength))
 	}
 
-	inputTokens := tokenizer.CountTokens(changes, tokenizerModel)
-	providerLimit := tokenizer.GetProviderTokenLimit(string(cfg.AI.Provider), cfg.AI.Model)
-	maxTokens := cfg.Context.MaxInputTokens
-	if maxTokens == 0 || maxTokens > providerLimit {
-		maxTokens = providerLimit // Use safe provider limit
-	}
+	return cfg
+}
 
-	// Reserve space for prompt overhead and response
-	// Prompt overhead: instructions, file info, etc. (~15K tokens typical)
-	// Response: cfg.AI.MaxTokens (usually 1000-5000)
-	promptOverhead := 15000
-	responseTokens := cfg.AI.MaxTokens
-	if responseTokens == 0 {
-		responseTokens = 5000
+// processDiff runs the diff pipeline: sanitizing, lockfile and metadata reduction, move detection,
+// and the token-aware strategy (summarize, batch or truncate). With trace, the token count after
+// each stage is recorded.
+func processDiff(cfg *config.Config, files []string, changes string, trace bool) diffContext {
+	var stages []ContextStage
+	tokenizerModel := tokenizerModelFor(cfg)
+
+	// record notes the size of the context after a stage; tokens are only counted when tracing
+	record := func(changes, name, detail string) {
+		if trace {
+			stages = append(stages, ContextStage{Name: name, Tokens: tokenizer.CountTokens(changes, tokenizerModel), Detail: detail})
+		}
 	}
-	// Calculate available space for changes (50% of remaining space to be safe)
-	availableForChanges := (maxTokens - promptOverhead - responseTokens) / 2
-	if availableForChanges < 10000 {
-		availableForChanges = 10000 // Minimum 10K tokens for changes
+	changes = prepareDiff(cfg, files, changes, record)
+	defer cfg.Timings.Track("diff processing")()
+	var deleted []string
+	if cfg.Context.HighlightDeletions {
+		deleted = deletedPaths(changes)
 	}
 
+	budget := tokenBudgetFor(cfg)
+	maxTokens := budget.MaxTokens
+	availableForChanges := budget.ForChanges
+
+	// Small diffs fit in bytes, so they can't need a strategy or truncation
+	if !trace && len(changes) < smallDiffBytes && len(changes) <= availableForChanges {
+		debugPrint(cfg, "SMALL DIFF", fmt.Sprintf("%d bytes, skipping tokenization", len(changes)))
+		return diffContext{
+			Changes:        changes,
+			Tokens:         tokenizer.EstimateTokens(changes),
+			TokenizerModel: tokenizerModel,
+			MaxTokens:      maxTokens,
+			Small:          true,
+			Deleted:        deleted,
+		}
+	}
+
+	stopCounting := cfg.Timings.Track("token counting")
+	inputTokens := tokenizer.CountTokens(changes, tokenizerModel)
+	stopCounting()
+
 	// Debug: Show token analysis
 	if cfg.AI.Debug {
 		debugPrint(cfg, "TOKEN ANALYSIS", map[string]interface{}{
-			"input_tokens":         inputTokens,
-			"max_tokens":           maxTokens,
-			"provider_limit":       providerLimit,
-			"prompt_overhead":      promptOverhead,
-			"response_tokens":      responseTokens,
+			"input_tokens":          inputTokens,
+			"max_tokens":            maxTokens,
+			"provider_limit":        budget.ProviderLimit,
+			"prompt_overhead":       budget.PromptOverhead,
+			"response_tokens":       budget.ResponseTokens,
 			"available_for_changes": availableForChanges,
-			"model":                tokenizerModel,
+			"model":                 tokenizerModel,
+			"token_counting":        tokenizer.Method(tokenizerModel),
 		})
 	}
 
 	// Apply smart processing if exceeds available space
-	if inputTokens > availableForChanges {
-		strategy := cfg.Context.DiffStrategy
-		if strategy == "" || strategy == "auto" {
-			// Auto-select strategy based on size
-			if inputTokens < availableForChanges*3 {
-				strategy = "summarize"
-			} else {
-				strategy = "batch"
-			}
-		}
-
+	if strategy := selectStrategy(cfg, inputTokens, availableForChanges); strategy != "" {
 		debugPrint(cfg, "PROCESSING LARGE DIFF", fmt.Sprintf("Using %s strategy (%d tokens > %d available)", strategy, inputTokens, availableForChanges))
 
 		var processed string
@@ -936,7 +1401,7 @@ func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (
 		case "summarize":
 			processed, processErr = BuildContextFromDiff(changes, availableForChanges, cfg)
 		default: // "truncate"
-			processed = tokenizer.TruncateToTokenLimit(changes, availableForChanges, tokenizerModel)
+			processed = truncateDiff(cfg, changes, availableForChanges, tokenizerModel)
 		}
 
 		if processErr == nil {
@@ -946,8 +1411,9 @@ func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (
 		} else {
 			debugPrint(cfg, "PROCESSING ERROR", processErr.Error())
 			// Fallback to simple truncation on error
-			changes = tokenizer.TruncateToTokenLimit(changes, availableForChanges, tokenizerModel)
+			changes = truncateDiff(cfg, changes, availableForChanges, tokenizerModel)
 		}
+		record(changes, strategy+" strategy", fmt.Sprintf("%d tokens available", availableForChanges))
 	}
 
 	// FINAL SAFETY: Ensure changes is ALWAYS under hard limit before building prompt
@@ -958,7 +1424,230 @@ func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (
 		debugPrint(cfg, "HARD LIMIT ENFORCEMENT", fmt.Sprintf("Changes still %d tokens > %d limit, forcing truncation", finalChangesTokens, hardLimit))
 		changes = tokenizer.TruncateToTokenLimit(changes, hardLimit, tokenizerModel)
 		finalChangesTokens = tokenizer.CountTokens(changes, tokenizerModel)
+		record(changes, "hard limit truncation", fmt.Sprintf("%d tokens allowed", hardLimit))
+	}
+
+	return diffContext{
+		Changes:        changes,
+		Tokens:         finalChangesTokens,
+		TokenizerModel: tokenizerModel,
+		MaxTokens:      maxTokens,
+		Stages:         stages,
+		Deleted:        deleted,
 	}
+}
+
+// tokenizerModelFor returns the model whose encoding counts tokens
+func tokenizerModelFor(cfg *config.Config) string {
+	if cfg.Context.TokenizerModel != "" {
+		return cfg.Context.TokenizerModel
+	}
+	return cfg.AI.Model // Default to AI model
+}
+
+// estimatedLimitPercent is the share of the provider limit used when tokens are estimated
+const estimatedLimitPercent = 60
+
+// tokenBudget is the input token budget the diff pipeline works within
+type tokenBudget struct {
+	ProviderLimit  int // Built-in safe input limit for the provider and model
+	MaxTokens      int // Input limit after context.max_input_tokens
+	PromptOverhead int // Reserved for instructions, file info, etc.
+	ResponseTokens int // Reserved for the response
+	ForChanges     int // Tokens the diff may use
+}
+
+// tokenBudgetFor computes the token budget for the configured provider and model
+func tokenBudgetFor(cfg *config.Config) tokenBudget {
+	budget := tokenBudget{ProviderLimit: tokenizer.GetProviderTokenLimit(string(cfg.AI.Provider), cfg.AI.Model)}
+	if tokenizer.Estimating(tokenizerModelFor(cfg)) {
+		// Estimates can be well off for code, so leave more headroom below the provider's limit
+		budget.ProviderLimit = budget.ProviderLimit * estimatedLimitPercent / 100
+	}
+	budget.MaxTokens = cfg.Context.MaxInputTokens
+	if budget.MaxTokens == 0 || budget.MaxTokens > budget.ProviderLimit {
+		budget.MaxTokens = budget.ProviderLimit // Use safe provider limit
+	}
+
+	// Reserve space for prompt overhead and response
+	// Prompt overhead: instructions, file info, etc. (~15K tokens typical)
+	// Response: the model's max_tokens (usually 1000-5000)
+	budget.PromptOverhead = 15000
+	fileContextTokens := 0
+	if cfg.Context.FileContext != "" {
+		// The context file is always sent whole, so the diff gives way to it
+		fileContextTokens = tokenizer.CountTokens(cfg.Context.FileContext, tokenizerModelFor(cfg))
+		budget.PromptOverhead += fileContextTokens
+	}
+	budget.ResponseTokens = maxOutputTokens(cfg)
+	if budget.ResponseTokens == 0 {
+		budget.ResponseTokens = 5000
+	}
+	// Calculate available space for changes (50% of remaining space to be safe)
+	budget.ForChanges = (budget.MaxTokens - budget.PromptOverhead - budget.ResponseTokens) / 2
+	if budget.ForChanges < 10000 {
+		budget.ForChanges = 10000 // Minimum 10K tokens for changes
+	}
+	if cfg.Commit.QuickMode {
+		budget.ForChanges = quickModeDiffTokens
+	}
+	if window := ollamaContextWindow(cfg); window > 0 {
+		fitOllamaWindow(cfg, &budget, window, fileContextTokens)
+	}
+	return budget
+}
+
+// selectStrategy returns the strategy that shrinks a diff of inputTokens to fit available
+// tokens, or "" when it already fits
+func selectStrategy(cfg *config.Config, inputTokens, available int) string {
+	if inputTokens <= available {
+		return ""
+	}
+	strategy := cfg.Context.DiffStrategy
+	if strategy == "" || strategy == "auto" {
+		// Auto-select strategy based on size
+		if inputTokens < available*3 {
+			strategy = "summarize"
+		} else {
+			strategy = "batch"
+		}
+	}
+	return strategy
+}
+
+// prepareDiff applies the size-independent stages of the pipeline: the detailed diff,
+// sanitizing, lockfile and metadata reduction and move detection
+func prepareDiff(cfg *config.Config, files []string, changes string, record func(changes, name, detail string)) string {
+	tokenizerModel := tokenizerModelFor(cfg)
+
+	// Get more detailed git diff if requested
+	var detailedDiff string
+	var err error
+	if cfg.Context.IncludeDiff && !cfg.Context.UseProvidedDiff {
+		stopGit := cfg.Timings.Track("git")
+		if cfg.Context.DiffBase != "" {
+			detailedDiff, err = git.GetStagedChangesSince(cfg.Context.DiffBase)
+		} else {
+			detailedDiff, err = GetGitDiff(files)
+		}
+		stopGit()
+		if err == nil && detailedDiff != "" {
+			// Use the detailed diff instead of the basic changes
+			changes = detailedDiff
+		}
+	}
+
+	record(changes, "staged diff", fmt.Sprintf("%d files", len(files)))
+
+	// Paths in .commitronignore and context.exclude_paths never reach the model
+	var excluded int
+	changes, excluded = excludeFromDiff(contextExclusions(cfg), changes)
+	if excluded > 0 {
+		debugPrint(cfg, "FILES EXCLUDED", fmt.Sprintf("%d files left out of the diff", excluded))
+		record(changes, "exclusions applied", fmt.Sprintf("%d files excluded", excluded))
+	}
+
+	// Show what changed inside updated submodules, within half of the diff budget
+	if cfg.Context.ExpandSubmoduleDiffs && !cfg.Context.UseProvidedDiff {
+		stopGit := cfg.Timings.Track("git")
+		var expanded int
+		changes, expanded = ExpandSubmoduleDiffs(changes, tokenBudgetFor(cfg).ForChanges/2, tokenizerModel)
+		stopGit()
+		if expanded > 0 {
+			debugPrint(cfg, "SUBMODULES EXPANDED", fmt.Sprintf("%d submodule diffs added", expanded))
+		}
+		record(changes, "submodules expanded", fmt.Sprintf("%d submodules", expanded))
+	}
+
+	stopProcessing := cfg.Timings.Track("diff processing")
+
+	// Make the diff safe to encode and tokenize
+	var shortened int
+	changes, shortened = SanitizeDiff(changes, cfg.Context.MaxDiffLineLength)
+	if shortened > 0 {
+		debugPrint(cfg, "LONG LINES SHORTENED", fmt.Sprintf("%d diff lines capped at %d bytes", shortened, cfg.Context.MaxDiffLineLength))
+	}
+	record(changes, "sanitized", fmt.Sprintf("%d long lines shortened", shortened))
+
+	// Collapse generated lockfile diffs into one-line summaries
+	if cfg.Context.SummarizeLockfiles {
+		var collapsed int
+		changes, collapsed = SummarizeLockfiles(changes)
+		if collapsed > 0 {
+			debugPrint(cfg, "LOCKFILES SUMMARIZED", fmt.Sprintf("%d lockfile diffs replaced with summaries", collapsed))
+		}
+		record(changes, "lockfiles summarized", fmt.Sprintf("%d lockfiles collapsed", collapsed))
+	}
+
+	// Drop index/mode header lines that only cost tokens
+	if cfg.Context.StripDiffMetadata {
+		stripped := StripDiffMetadata(changes)
+		// Counting the savings costs two encodings, so only do it for the debug output
+		if cfg.AI.Debug {
+			if saved := tokenizer.CountTokens(changes, tokenizerModel) - tokenizer.CountTokens(stripped, tokenizerModel); saved > 0 {
+				debugPrint(cfg, "DIFF METADATA STRIPPED", fmt.Sprintf("%d tokens saved", saved))
+			}
+		}
+		changes = stripped
+		record(changes, "metadata stripped", "")
+	}
+
+	// Mark code that moved between files
+	if cfg.Context.DetectMoves {
+		var moves int
+		changes, moves = AnnotateMoves(changes)
+		if moves > 0 {
+			debugPrint(cfg, "MOVES DETECTED", fmt.Sprintf("%d blocks moved between files", moves))
+		}
+		record(changes, "moves annotated", fmt.Sprintf("%d blocks moved", moves))
+	}
+
+	stopProcessing()
+	return changes
+}
+
+// PreviewContext runs the diff pipeline without calling the model and returns the processed
+// context that would be sent, with the token count after each stage
+func PreviewContext(cfg *config.Config, files []string, changes string) (string, []ContextStage) {
+	processed := processDiff(generationConfig(cfg), files, changes, true)
+	// Show the diff as it is sent; an invalid pattern fails the real run with its error
+	if redacted, count, err := redact(cfg, processed.Changes); err == nil && count > 0 {
+		processed.Stages = append(processed.Stages, ContextStage{Name: "redacted", Tokens: tokenizer.CountTokens(redacted, processed.TokenizerModel), Detail: fmt.Sprintf("%d matches replaced", count)})
+		processed.Changes = redacted
+	}
+	return processed.Changes, processed.Stages
+}
+
+// GenerateCommitMessage generates a commit message using the configured AI provider
+func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (string, error) {
+	// Display staged files in TUI format if enabled
+	if cfg.UI.EnableTUI {
+		DisplayStagedFiles(files)
+	}
+
+	// Excluded files aren't named in the prompt either; their diff is dropped in processDiff
+	if kept := excludeFiles(contextExclusions(cfg), files); len(kept) < len(files) {
+		if len(kept) == 0 {
+			return "", ErrAllExcluded
+		}
+		files = kept
+	}
+
+	cfg = withSubjectPackage(cfg, files)
+	maxLength := cfg.Commit.MaxLength // Before generationConfig reserves room for the subject affixes
+	cfg = generationConfig(cfg)
+	stagedDiff := changes // Kept for the preview, before any summarization
+
+	processed, ok := takePrefetched(cfg, files, changes)
+	if !ok {
+		processed = processDiff(cfg, files, changes, false)
+	}
+	changes = processed.Changes
+	cfg = withDeletedFiles(cfg, processed.Deleted)
+	finalChangesTokens := processed.Tokens
+	tokenizerModel := processed.TokenizerModel
+	maxTokens := processed.MaxTokens
+	var err error
 
 	// Debug: Show input data
 	if cfg.AI.Debug {
@@ -979,7 +1668,9 @@ func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (
 	var prompt string
 
 	// Choose between JSON template approach and text prompt approach
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.QuickMode {
+		prompt = GenerateQuickPrompt(cfg, files, changes)
+	} else if cfg.Commit.Convention.IsConventional() {
 		// Use the more detailed text prompt for conventional commits
 		prompt = GenerateTextPrompt(cfg, files, changes)
 	} else {
@@ -991,12 +1682,15 @@ func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (
 	debugPrint(cfg, "AI PROMPT", prompt)
 
 	// Final safety check: ensure prompt doesn't exceed safe limit
-	promptTokens := tokenizer.CountTokens(prompt, tokenizerModel)
-	finalResponseTokens := cfg.AI.MaxTokens
+	finalResponseTokens := maxOutputTokens(cfg)
 	if finalResponseTokens == 0 {
 		finalResponseTokens = 5000
 	}
 	safeLimit := maxTokens - finalResponseTokens - 5000 // Extra buffer for safety
+	promptTokens := processed.countTokens(prompt)
+	if processed.Small && len(prompt) > safeLimit {
+		promptTokens = tokenizer.CountTokens(prompt, tokenizerModel) // The estimate could be too low
+	}
 
 	if cfg.AI.Debug {
 		debugPrint(cfg, "FINAL TOKEN CHECK", map[string]interface{}{
@@ -1013,7 +1707,7 @@ func GenerateCommitMessage(cfg *config.Config, files []string, changes string) (
 		debugPrint(cfg, "EMERGENCY TRUNCATION", fmt.Sprintf("Prompt %d tokens exceeds safe limit %d, using summary only", promptTokens, safeLimit))
 
 		// Extract just a summary of changes for emergency mode
-		summary := extractKeyDiffContent(changes)
+		summary := extractKeyDiffContent(cfg, changes)
 		summaryTokens := tokenizer.CountTokens(summary, tokenizerModel)
 		maxSummaryTokens := safeLimit / 2 // Use half the safe limit for summary
 
@@ -1040,75 +1734,119 @@ Output ONLY the commit message, nothing else. Keep subject under %d characters.`
 	}
 
 	var rawResponse string
+	var commitMsg CommitMessage
+	basePrompt := prompt
 
-	// Choose the AI provider based on the configuration
-	switch cfg.AI.Provider {
-	case config.OpenAI:
-		rawResponse, err = generateWithOpenAI(cfg, prompt)
-	case config.Gemini:
-		rawResponse, err = generateWithGemini(cfg, prompt)
-	case config.Ollama:
-		rawResponse, err = generateWithOllama(cfg, prompt)
-	case config.Claude:
-		rawResponse, err = generateWithClaude(cfg, prompt)
-	default:
-		return "", fmt.Errorf("unsupported AI provider: %s", cfg.AI.Provider)
-	}
+	debugPrint(cfg, "GENERATION STAGE", fmt.Sprintf("Commit message generated by %s/%s", cfg.AI.Provider, cfg.AI.Model))
 
-	if err != nil {
-		debugPrint(cfg, "AI ERROR", err.Error())
-		return "", err
-	}
+	lastUsage = Usage{Provider: string(cfg.AI.Provider), Model: cfg.AI.Model}
 
-	// Display that analysis is complete
-	if cfg.UI.EnableTUI {
-		DisplayAnalysisComplete()
-	}
+	// Call the provider, regenerating with feedback when the response violates a hard requirement
+	for attempt := 0; ; attempt++ {
+		started := time.Now()
+		rawResponse, err = callProvider(cfg, prompt)
+		lastUsage.Latency += time.Since(started)
+		cfg.Timings.Add("provider", time.Since(started))
+		lastUsage.Attempts++
+		if err != nil {
+			debugPrint(cfg, "AI ERROR", err.Error())
+			return "", err
+		}
+		rawResponse = normalizeNewlines(rawResponse)
+		stopCounting := cfg.Timings.Track("token counting")
+		lastUsage.PromptTokens += processed.countTokens(prompt)
+		lastUsage.CompletionTokens += processed.countTokens(rawResponse)
+		stopCounting()
+		if rawResponse, err = sanitizeResponse(cfg, rawResponse); err != nil {
+			return "", err
+		}
 
-	// Debug: Show the raw response from the AI
-	debugPrint(cfg, "AI RESPONSE", rawResponse)
+		// Display that analysis is complete
+		if cfg.UI.EnableTUI && attempt == 0 {
+			DisplayAnalysisComplete()
+		}
 
-	// Parse the response into a structured CommitMessage
-	commitMsg, err := ParseCommitMessageJSON(rawResponse)
-	if err != nil {
-		debugPrint(cfg, "PARSING ERROR", err.Error())
-		// For conventional commits, ensure we have at least a type
-		if cfg.Commit.Convention == config.ConventionalCommits {
-			// If parsing failed but we can extract something useful from the raw text
-			if strings.Contains(rawResponse, ": ") {
-				parts := strings.SplitN(rawResponse, ": ", 2)
-				if len(parts) == 2 {
-					potential_type := strings.TrimSpace(parts[0])
-					// Check if this could be a valid type
-					if isValidCommitType(potential_type) {
-						commitMsg.Type = potential_type
-						commitMsg.Subject = strings.TrimSpace(parts[1])
-						// Use the rest as body if applicable
-						if cfg.Commit.IncludeBody && strings.Contains(commitMsg.Subject, "\n\n") {
-							bodyParts := strings.SplitN(commitMsg.Subject, "\n\n", 2)
-							if len(bodyParts) == 2 {
-								commitMsg.Subject = bodyParts[0]
-								commitMsg.Body = bodyParts[1]
-							}
-						}
-						debugPrint(cfg, "MANUAL PARSING SUCCESSFUL", commitMsg)
-					} else {
-						// Default to a generic type
-						commitMsg.Type = "chore"
-						commitMsg.Subject = rawResponse
-					}
+		// Debug: Show the raw response from the AI
+		debugPrint(cfg, "AI RESPONSE", rawResponse)
+
+		// Parse the response into a structured CommitMessage
+		stopParsing := cfg.Timings.Track("parsing/validation")
+		var parsed bool
+		commitMsg, parsed = parseProviderResponse(cfg, rawResponse)
+		if !parsed {
+			// Never use the raw response without the user seeing it first
+			stopParsing()
+			return "", &RawResponseError{Response: strings.TrimSpace(rawResponse)}
+		}
+
+		// Drop any scope the model emitted when scopes are forbidden
+		if cfg.Commit.Scope == config.ScopeForbidden {
+			commitMsg.Scope = ""
+		}
+
+		// Clean up stuttered subjects before they are validated
+		if cfg.Commit.CollapseRepeatedWords {
+			if collapsed := collapseRepeatedWords(commitMsg.Subject); collapsed != strings.TrimSpace(commitMsg.Subject) {
+				debugPrint(cfg, "COLLAPSED REPEATED WORDS", fmt.Sprintf("%q → %q", commitMsg.Subject, collapsed))
+				commitMsg.Subject = collapsed
+			}
+		}
+
+		// Fill in a missing scope from the project's commit history
+		if cfg.Commit.Convention.IsConventional() && cfg.Commit.LearnScopesFromHistory &&
+			cfg.Commit.Scope != config.ScopeForbidden && commitMsg.Scope == "" {
+			commitMsg.Scope = SuggestScopeFromHistory(cfg, files)
+		}
+
+		reason := regenerationReason(commitMsg, cfg)
+		if reason == "" && cfg.Commit.VerifyTypeAgainstChanges == config.TypeVerifyEnforce {
+			reason = typeMismatch(commitMsg.Type, files, cfg)
+		}
+		stopParsing()
+		if reason == "" && cfg.Commit.OnLengthViolation == config.LengthRetry {
+			if length := subjectLineLength(commitMsg, cfg); length > cfg.Commit.MaxLength {
+				if attempt >= cfg.Commit.MaxRetries {
+					return "", fmt.Errorf("%w: the subject line was still %d characters after %d attempts, the limit is %d:\n\n%s",
+						ErrSubjectTooLong, length, attempt+1, cfg.Commit.MaxLength, FormatCommitMessage(commitMsg, cfg))
 				}
+				reason = fmt.Sprintf("your previous subject line was %d characters, the limit is %d. Write a shorter subject that keeps the key information.", length, cfg.Commit.MaxLength)
+			}
+		}
+		// Too many words always gets the retries; what happens after them follows on_length_violation
+		if limit := cfg.Commit.MaxSubjectWords; reason == "" && limit > 0 && attempt < cfg.Commit.MaxRetries {
+			if words := subjectWordCount(commitMsg.Subject); words > limit {
+				reason = fmt.Sprintf("your previous subject had %d words, the limit is %d. Write a subject of at most %d words that keeps the key information.", words, limit, limit)
+			}
+		}
+		if reason == "" {
+			// Ask for a shorter body instead of truncating it mid-sentence
+			bodyLength := utf8.RuneCountInString(strings.TrimSpace(commitMsg.Body))
+			totalLength := utf8.RuneCountInString(FormatCommitMessage(commitMsg, cfg))
+			bodyTooLong := bodyLength > cfg.Commit.MaxBodyLength
+			totalTooLong := cfg.Commit.MaxTotalLength > 0 && totalLength > cfg.Commit.MaxTotalLength
+			if cfg.Commit.TruncateStrategy != config.TruncateReprompt || !cfg.Commit.IncludeBody || (!bodyTooLong && !totalTooLong) {
+				break
+			}
+			if attempt >= cfg.Commit.MaxRetries {
+				debugPrint(cfg, "REPROMPT LIMIT REACHED", fmt.Sprintf("Message still too long after %d attempts (body %d, total %d characters), falling back to truncation", attempt+1, bodyLength, totalLength))
+				break
+			}
+			if bodyTooLong {
+				reason = fmt.Sprintf("the body was %d characters but MUST NOT exceed %d characters. Keep the same subject and write a shorter body that summarizes the changes in fewer words.", bodyLength, cfg.Commit.MaxBodyLength)
 			} else {
-				commitMsg.Type = "chore"
-				commitMsg.Subject = rawResponse
+				reason = fmt.Sprintf("the whole commit message was %d characters but MUST NOT exceed %d characters. Keep the same subject and write a shorter body that summarizes the changes in fewer words.", totalLength, cfg.Commit.MaxTotalLength)
 			}
-		} else {
-			return rawResponse, nil // Fall back to raw response if parsing fails for non-conventional format
+		} else if attempt >= cfg.Commit.MaxRetries {
+			return "", fmt.Errorf("generated commit message rejected after %d attempts: %s", attempt+1, reason)
 		}
+
+		debugPrint(cfg, "REGENERATING", fmt.Sprintf("Attempt %d rejected: %s", attempt+1, reason))
+		prompt = basePrompt + "\n\nYOUR PREVIOUS RESPONSE WAS REJECTED: " + reason + "\nGenerate the commit message again and fix this problem."
 	}
 
 	// Debug: Show the parsed commit message
 	debugPrint(cfg, "PARSED COMMIT", commitMsg)
+	stopParsing := cfg.Timings.Track("parsing/validation")
 
 	// Ensure the body is not empty if it's required
 	if cfg.Commit.IncludeBody && (commitMsg.Body == "" || strings.TrimSpace(commitMsg.Body) == "") {
@@ -1117,9 +1855,25 @@ Output ONLY the commit message, nothing else. Keep subject under %d characters.`
 		debugPrint(cfg, "GENERATED DEFAULT BODY", commitMsg.Body)
 	}
 
+	// Link the issues the added lines reference; excluded files stay out of the footer too
+	if cfg.Commit.AutoRefs {
+		diff, _ := excludeFromDiff(contextExclusions(cfg), stagedDiff)
+		commitMsg.Footers = addRefsFooter(commitMsg.Footers, referencedIssues(cfg, diff))
+	}
+
+	// Enforce commit.max_subject_words once the retries are used up
+	if limit := cfg.Commit.MaxSubjectWords; limit > 0 && subjectWordCount(commitMsg.Subject) > limit {
+		if !cfg.Commit.OnLengthViolation.Truncates() {
+			return "", fmt.Errorf("%w: the subject still had %d words after %d attempts, the limit is %d:\n\n%s",
+				ErrSubjectTooLong, subjectWordCount(commitMsg.Subject), lastUsage.Attempts, limit, FormatCommitMessage(commitMsg, cfg))
+		}
+		commitMsg.Subject = firstWords(commitMsg.Subject, limit)
+		debugPrint(cfg, "SUBJECT WORDS TRUNCATED", commitMsg.Subject)
+	}
+
 	// Verify message length constraints before formatting
 	subjectLength := 0
-	if cfg.Commit.Convention == config.ConventionalCommits && commitMsg.Type != "" {
+	if cfg.Commit.Convention.IsConventional() && commitMsg.Type != "" {
 		// For conventional commits, calculate full subject with type and scope
 		if commitMsg.Scope != "" {
 			subjectLength = len(commitMsg.Type) + len(commitMsg.Scope) + len(commitMsg.Subject) + 4 // +4 for "(): "
@@ -1130,10 +1884,10 @@ Output ONLY the commit message, nothing else. Keep subject under %d characters.`
 		subjectLength = len(commitMsg.Subject)
 	}
 
-	// Check if subject exceeds max length - hard enforce the limit
-	if subjectLength > cfg.Commit.MaxLength {
+	// Check if subject exceeds max length - hard enforce the limit unless the policy says otherwise
+	if subjectLength > cfg.Commit.MaxLength && cfg.Commit.OnLengthViolation.Truncates() {
 		// Always attempt to truncate the subject to meet the limit
-		if cfg.Commit.Convention == config.ConventionalCommits && commitMsg.Type != "" {
+		if cfg.Commit.Convention.IsConventional() && commitMsg.Type != "" {
 			// Calculate maximum space available for the subject
 			maxSubjectSpace := cfg.Commit.MaxLength
 			if commitMsg.Scope != "" {
@@ -1183,7 +1937,7 @@ Output ONLY the commit message, nothing else. Keep subject under %d characters.`
 
 		// If still too long after truncation, force more aggressive truncation
 		if subjectLength > cfg.Commit.MaxLength {
-			if cfg.Commit.Convention == config.ConventionalCommits && commitMsg.Type != "" {
+			if cfg.Commit.Convention.IsConventional() && commitMsg.Type != "" {
 				// For conventional commits, preserve type and scope, but severely truncate subject
 				fixedType := commitMsg.Type
 				fixedScope := commitMsg.Scope
@@ -1244,7 +1998,7 @@ Output ONLY the commit message, nothing else. Keep subject under %d characters.`
 	}
 
 	// Validate against conventional commit rules if needed
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		if err := validateConventionalCommit(commitMsg, cfg); err != nil {
 			debugPrint(cfg, "CONVENTIONAL COMMIT VALIDATION ERROR", err.Error())
 			// Try to fix common issues
@@ -1259,30 +2013,174 @@ Output ONLY the commit message, nothing else. Keep subject under %d characters.`
 		}
 	}
 
-	// Format the message according to the configuration
-	formattedMessage := FormatCommitMessage(commitMsg, cfg)
+	// Format the message according to the configuration; the prefix/suffix are only added here,
+	// so the first line is checked against the configured limit once more
+	var formattedMessage string
+	if cfg.Commit.OnLengthViolation.Truncates() {
+		formattedMessage = formatWithinLimit(commitMsg, cfg, maxLength)
+	} else {
+		formattedMessage = FormatCommitMessage(commitMsg, cfg)
+		firstLine, _, _ := strings.Cut(formattedMessage, "\n")
+		if length := utf8.RuneCountInString(firstLine); maxLength > 0 && length > maxLength {
+			return "", fmt.Errorf("%w: the subject line is %d characters, the limit is %d:\n\n%s", ErrSubjectTooLong, length, maxLength, formattedMessage)
+		}
+	}
+
+	// Guard the size of the whole message, shortening only the body
+	if cfg.Commit.MaxTotalLength > 0 && utf8.RuneCountInString(formattedMessage) > cfg.Commit.MaxTotalLength {
+		formattedMessage = fitTotalLength(cfg, commitMsg.Body, cfg.Commit.MaxTotalLength, func(body string) string {
+			shortened := commitMsg
+			shortened.Body = body
+			return formatWithinLimit(shortened, cfg, maxLength)
+		})
+	}
+
+	// Hand the message to the user's post-processing command, if any
+	if cfg.Commit.FormatCommand != "" {
+		formattedMessage, err = RunFormatCommand(cfg.Commit.FormatCommand, formattedMessage)
+		if err != nil {
+			return "", err
+		}
+		formattedMessage = SanitizeMessage(formattedMessage)
+	}
+
+	stopParsing()
 
 	// Debug: Show the final formatted message
 	debugPrint(cfg, "FINAL COMMIT MESSAGE", formattedMessage)
 
-	// Display the commit message but skip confirmation - auto-commit
+	// Display the commit message; confirming it is up to the caller
 	if cfg.UI.EnableTUI {
-		fmt.Println("\n\033[1;36m💬 Generated Commit Message\033[0m")
-		fmt.Println("\033[38;5;244m────────────────────────\033[0m")
-		
-		// Display the commit message with proper formatting
-		lines := strings.Split(formattedMessage, "\n")
-		for _, line := range lines {
-			if line == "" {
-				fmt.Println()
+		if cfg.UI.ShowDiffPreview {
+			DisplayDiffPreview(stagedDiff, cfg.UI.DiffPreviewLines)
+		}
+		PrintCommitMessage(formattedMessage)
+	}
+
+	return formattedMessage, nil
+}
+
+// Usage describes the provider calls made by the most recent GenerateCommitMessage
+type Usage struct {
+	Provider         string
+	Model            string
+	PromptTokens     int           // Tokens sent across all attempts (user prompt only)
+	CompletionTokens int           // Tokens received across all attempts
+	Attempts         int           // Provider calls, including regenerations
+	Latency          time.Duration // Total time spent waiting for the provider
+}
+
+// lastUsage holds the usage of the most recent generation
+var lastUsage Usage
+
+// LastUsage returns the provider usage of the most recent GenerateCommitMessage call
+func LastUsage() Usage {
+	return lastUsage
+}
+
+// callProvider sends the prompt to the configured AI provider and returns the raw response
+func callProvider(cfg *config.Config, prompt string) (string, error) {
+	// Redact last, so every part of the prompt is covered whichever request builds it; the
+	// system prompt is redacted where it is built (see systemMessage)
+	prompt, redacted, err := redact(cfg, prompt)
+	if err != nil {
+		return "", err
+	}
+	if redacted > 0 {
+		debugPrint(cfg, "REDACTED", fmt.Sprintf("%d matches of context.redact_patterns replaced with %s", redacted, redactionMarker))
+	}
+
+	response, err := requestCompletion(cfg, prompt)
+	if err != nil {
+		return "", err
+	}
+	// A refusal must never be taken for a commit message
+	if isRefusal(response) {
+		debugPrint(cfg, "REFUSAL", response)
+		return "", &RefusalError{Provider: string(cfg.AI.Provider), Text: strings.TrimSpace(response)}
+	}
+	return response, nil
+}
+
+// requestCompletion sends the prompt to the configured provider and returns its reply
+func requestCompletion(cfg *config.Config, prompt string) (string, error) {
+	switch cfg.AI.Provider {
+	case config.OpenAI:
+		return generateWithOpenAI(cfg, prompt)
+	case config.Gemini:
+		return generateWithGemini(cfg, prompt)
+	case config.Ollama:
+		return generateWithOllama(cfg, prompt)
+	case config.Claude:
+		return generateWithClaude(cfg, prompt)
+	default:
+		return "", fmt.Errorf("unsupported AI provider: %s", cfg.AI.Provider)
+	}
+}
+
+// parseProviderResponse turns a raw provider response into a CommitMessage.
+// Returns false when the response could not be parsed and should be used verbatim.
+func parseProviderResponse(cfg *config.Config, rawResponse string) (CommitMessage, bool) {
+	commitMsg, err := ParseCommitMessageJSON(rawResponse)
+	if err == nil && !cfg.Commit.Convention.IsConventional() && extractJSON(rawResponse) == "" && hasPreamble(rawResponse) {
+		// Text parsing would turn the chatter into the subject
+		err = errors.New("response is not JSON and starts with a preamble")
+	}
+	if err == nil {
+		return commitMsg, true
+	}
+
+	debugPrint(cfg, "PARSING ERROR", err.Error())
+	// For conventional commits, ensure we have at least a type
+	if !cfg.Commit.Convention.IsConventional() {
+		return commitMsg, false
+	}
+
+	// If parsing failed but we can extract something useful from the raw text
+	if strings.Contains(rawResponse, ": ") {
+		parts := strings.SplitN(rawResponse, ": ", 2)
+		if len(parts) == 2 {
+			potential_type := strings.TrimSpace(parts[0])
+			// Check if this could be a valid type
+			if isValidCommitType(potential_type, cfg) {
+				commitMsg.Type = potential_type
+				commitMsg.Subject = strings.TrimSpace(parts[1])
+				// Use the rest as body if applicable
+				if cfg.Commit.IncludeBody && strings.Contains(commitMsg.Subject, "\n\n") {
+					bodyParts := strings.SplitN(commitMsg.Subject, "\n\n", 2)
+					if len(bodyParts) == 2 {
+						commitMsg.Subject = bodyParts[0]
+						commitMsg.Body = bodyParts[1]
+					}
+				}
+				debugPrint(cfg, "MANUAL PARSING SUCCESSFUL", commitMsg)
 			} else {
-				fmt.Printf("   %s\n", line)
+				// Default to a generic type
+				commitMsg.Type = "chore"
+				commitMsg.Subject = rawResponse
 			}
 		}
-		fmt.Println("\033[38;5;244m────────────────────────\033[0m")
+	} else {
+		commitMsg.Type = "chore"
+		commitMsg.Subject = rawResponse
 	}
 
-	return formattedMessage, nil
+	return commitMsg, true
+}
+
+// regenerationReason returns feedback for the model when a parsed message violates
+// a requirement that local fixes cannot repair, or "" when the message is acceptable
+func regenerationReason(msg CommitMessage, cfg *config.Config) string {
+	if cfg.Commit.Convention == config.AngularConvention && !isValidCommitType(msg.Type, cfg) {
+		return fmt.Sprintf("the type '%s' is not allowed by the Angular convention. The type MUST be one of: %s.", msg.Type, commitTypeList(cfg))
+	}
+	if cfg.Commit.Convention.IsConventional() && cfg.Commit.Scope == config.ScopeRequired && strings.TrimSpace(msg.Scope) == "" {
+		return "a scope is required. Use the format type(scope): subject, for example 'fix(parser): handle empty input'."
+	}
+	if structuredNone(cfg) && cfg.Commit.IncludeBody && strings.TrimSpace(structureMessage(msg).Body) == "" {
+		return "the body was empty. Write a body of short bullet points explaining what changed and why, and put trailers in the footers list."
+	}
+	return ""
 }
 
 // generateDefaultBody creates a basic commit body when the AI doesn't provide one
@@ -1328,11 +2226,15 @@ func generateDefaultBody(cfg *config.Config, files []string, changes string) str
 
 // buildPrompt creates a prompt for the AI based on the configuration using JSON templates
 func buildPrompt(cfg *config.Config, files []string, changes string) string {
+	if prompt := customPrompt(cfg, files, changes); prompt != "" {
+		return prompt
+	}
+
 	// Debug which template is being used
 	if cfg.AI.Debug {
 		templateType := "Basic template"
 		switch cfg.Commit.Convention {
-		case config.ConventionalCommits:
+		case config.ConventionalCommits, config.AngularConvention:
 			templateType = "Conventional commits template"
 		case config.CustomConvention:
 			templateType = "Custom template: " + cfg.Commit.CustomTemplate
@@ -1343,10 +2245,13 @@ func buildPrompt(cfg *config.Config, files []string, changes string) string {
 	// Serialize files list to JSON
 	filesJSON, _ := json.Marshal(files)
 
+	// Collect touched tests, comment-only files, referenced issues and deletions before the diff is condensed
+	tests := testsSection(changes) + commentOnlyHint(changes, cfg.Commit.Convention.IsConventional()) + issuesSection(cfg, changes) + deletionsSection(cfg)
+
 	// Extract the most important changes from the diff if it's in our enhanced format
 	if strings.Contains(changes, "# Summary of changes") || strings.Contains(changes, "diff --git") {
 		// Prioritize the actual diff content and remove unnecessary headers
-		enhancedChanges := extractKeyDiffContent(changes)
+		enhancedChanges := extractKeyDiffContent(cfg, changes)
 		if enhancedChanges != "" {
 			changes = enhancedChanges
 			if cfg.AI.Debug {
@@ -1384,7 +2289,7 @@ func buildPrompt(cfg *config.Config, files []string, changes string) string {
 	// Select template based on commit convention
 	var template string
 	switch cfg.Commit.Convention {
-	case config.ConventionalCommits:
+	case config.ConventionalCommits, config.AngularConvention:
 		template = fmt.Sprintf(
 			ConventionalCommitsJSON,
 			cfg.Commit.MaxLength,
@@ -1418,8 +2323,26 @@ func buildPrompt(cfg *config.Config, files []string, changes string) string {
 		)
 	}
 
+	// Touched tests, comment-only files, referenced issues, squashed commits, project examples, the directory summary
+	// and the branch name follow the specification as hints
+	template += tests
+	template += contextFileSection(cfg)
+	if cfg.Context.UserContext != "" {
+		template += "\nContext from the author (use it to explain why): " + cfg.Context.UserContext
+	}
+	template += intentSection(cfg)
+	template += squashSection(cfg)
+	template += glossarySection(cfg)
+	template += examplesSection(cfg)
+	if summary := directorySummary(cfg, files); summary != "" {
+		template += "\n" + summary
+	}
+	if cfg.Context.IncludeBranchName {
+		template += branchContext()
+	}
+
 	// Check if we have a custom system prompt
-	hasCustomPrompt := cfg.AI.SystemPrompt != ""
+	hasCustomPrompt := cfg.AI.SystemPrompt != "" || cfg.AI.SystemPromptTemplate != nil
 
 	// Only add specific formatting instructions if no custom system prompt
 	if !hasCustomPrompt {
@@ -1432,18 +2355,39 @@ func buildPrompt(cfg *config.Config, files []string, changes string) string {
 		}
 
 		conventionalRulesInstructions := ""
-		if cfg.Commit.Convention == config.ConventionalCommits {
+		if cfg.Commit.Convention.IsConventional() {
 			conventionalRulesInstructions = "You MUST follow these conventional commit rules:\n" + ConventionalCommitRules + "\n"
 			conventionalRulesInstructions += fmt.Sprintf("\nCRITICAL: The TOTAL length of 'type(scope): subject' MUST be under %d characters.\nExamples of good length: 'fix: update validation logic', 'feat(auth): add login timeout'\n", cfg.Commit.MaxLength)
 			conventionalRulesInstructions += "\nALWAYS start your response with a valid type. NEVER start with just a colon.\n"
 			conventionalRulesInstructions += "CORRECT: 'feat: add feature'\nINCORRECT: ': add feature'\n"
 			conventionalRulesInstructions += "\nSTRICT REQUIREMENTS:\n"
-			conventionalRulesInstructions += "1. Type MUST be one of: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert\n"
+			conventionalRulesInstructions += "1. Type MUST be one of: " + commitTypeList(cfg) + "\n"
+			if instruction := typeConstraintInstruction(files, cfg); instruction != "" {
+				conventionalRulesInstructions += "   " + instruction + "\n"
+			}
 			conventionalRulesInstructions += "2. Type MUST be lowercase\n"
 			conventionalRulesInstructions += "3. Subject MUST be lowercase and not end with a period\n"
-			conventionalRulesInstructions += "4. Scope (if used) MUST be lowercase and not contain spaces or special characters\n"
+			switch cfg.Commit.Scope {
+			case config.ScopeRequired:
+				conventionalRulesInstructions += "4. Scope is REQUIRED and MUST be lowercase and not contain spaces or special characters\n"
+			case config.ScopeForbidden:
+				conventionalRulesInstructions += "4. Scope MUST NOT be used; leave the scope field empty\n"
+			default:
+				conventionalRulesInstructions += "4. Scope (if used) MUST be lowercase and not contain spaces or special characters\n"
+			}
 			conventionalRulesInstructions += "5. Body MUST be separated from subject by a blank line\n"
 			conventionalRulesInstructions += "6. Body MUST be meaningful and explain what changes were made and why\n"
+		} else if structuredNone(cfg) {
+			conventionalRulesInstructions = structuredRules(cfg.Commit.IncludeBody)
+		}
+
+		wordsInstruction := ""
+		if instruction := subjectWordsInstruction(cfg); instruction != "" {
+			wordsInstruction = instruction + "\n"
+		}
+		affixInstructions := ""
+		if instruction := subjectAffixInstruction(cfg); instruction != "" {
+			affixInstructions = instruction + "\n"
 		}
 
 		return "Your task is to create a CONCISE commit message based on the specifications below. " +
@@ -1454,14 +2398,10 @@ func buildPrompt(cfg *config.Config, files []string, changes string) string {
 			"Return JUST the JSON object and nothing else. " +
 			"IMPORTANT: Focus on the actual code changes in the diff and what they accomplish. Be BRIEF and CONCISE. " +
 			fmt.Sprintf("CRITICAL: Ensure total commit subject length is UNDER %d characters.\n", cfg.Commit.MaxLength) +
+			wordsInstruction +
+			affixInstructions +
 			"Format:\n\n" +
-			"For conventional commits, use this exact structure:\n" +
-			"{\n" +
-			"  \"type\": \"feat\", // One of: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert\n" +
-			"  \"scope\": \"optional scope\", // Optional, must be lowercase\n" +
-			"  \"subject\": \"concise subject line\", // Must be lowercase, no period\n" +
-			"  \"body\": \"" + bodyExample(cfg.Commit.IncludeBody) + "\"\n" +
-			"}\n\n" +
+			formatExample(cfg) +
 			"Here are the specifications:\n\n" + template
 	} else {
 		// With custom system prompt, just provide the template data
@@ -1469,8 +2409,29 @@ func buildPrompt(cfg *config.Config, files []string, changes string) string {
 	}
 }
 
+// formatExample shows the JSON structure the response must have
+func formatExample(cfg *config.Config) string {
+	footers := "  \"footers\": [] // Optional, e.g. [{\"token\": \"BREAKING CHANGE\", \"value\": \"config files must be migrated\"}]\n"
+	if structuredNone(cfg) {
+		return "Use this exact structure, without a type:\n" +
+			"{\n" +
+			"  \"subject\": \"Concise subject line\", // Imperative, capitalized, no period\n" +
+			"  \"body\": \"" + bodyExample(cfg.Commit.IncludeBody) + "\",\n" +
+			footers +
+			"}\n\n"
+	}
+	return "For conventional commits, use this exact structure:\n" +
+		"{\n" +
+		"  \"type\": \"feat\", // One of: " + commitTypeList(cfg) + "\n" +
+		"  \"scope\": " + scopeJSONExample(cfg) + "\n" +
+		"  \"subject\": \"concise subject line\", // Must be lowercase, no period\n" +
+		"  \"body\": \"" + bodyExample(cfg.Commit.IncludeBody) + "\",\n" +
+		footers +
+		"}\n\n"
+}
+
 // extractKeyDiffContent focuses on the most important parts of the diff using smart summarization
-func extractKeyDiffContent(diff string) string {
+func extractKeyDiffContent(cfg *config.Config, diff string) string {
 	// Use new smart summarization
 	fileDiffs := ParseDiffByFile(diff)
 	if len(fileDiffs) == 0 {
@@ -1503,6 +2464,7 @@ func extractKeyDiffContent(diff string) string {
 	}
 
 	// Generate summaries for all files
+	annotateIssues(cfg, fileDiffs)
 	var summaries []string
 	for _, fd := range fileDiffs {
 		summary := SummarizeFileDiff(fd)
@@ -1520,6 +2482,38 @@ func bodyExample(includeBody bool) string {
 	return "leave empty"
 }
 
+// buildLengthConstraintPreamble returns the instructions every provider puts in front of the
+// prompt: the subject length limit and, for conventional commits, the required type prefix
+// and body. It returns "" when ai.disable_length_preamble is set, e.g. for body-only and
+// summary requests.
+func buildLengthConstraintPreamble(cfg *config.Config) string {
+	if cfg.AI.DisableLengthPreamble {
+		return ""
+	}
+	lengthPrefix := fmt.Sprintf("CRITICAL INSTRUCTION: Your commit message subject MUST be under %d characters total. ", cfg.Commit.MaxLength)
+	if cfg.Commit.Convention.IsConventional() {
+		lengthPrefix += fmt.Sprintf("For conventional commits, this means the ENTIRE string 'type(scope): subject' must be under %d characters.", cfg.Commit.MaxLength)
+		lengthPrefix += "\n\nYOU MUST START YOUR RESPONSE WITH A CONVENTIONAL COMMIT TYPE. DO NOT START WITH JUST A COLON."
+		lengthPrefix += "\nCORRECT: 'feat: add new feature'"
+		lengthPrefix += "\nINCORRECT: ': add new feature'"
+		lengthPrefix += "\nValid types are: " + commitTypeList(cfg)
+
+		if cfg.Commit.IncludeBody {
+			lengthPrefix += "\n\nYOU MUST INCLUDE A COMMIT BODY AFTER THE SUBJECT. The body must be separated from the subject by a blank line."
+			lengthPrefix += "\nThe body MUST NOT be empty and should explain what changes were made and why."
+		}
+	}
+	return lengthPrefix
+}
+
+// withLengthPreamble puts the length constraint preamble in front of prompt
+func withLengthPreamble(cfg *config.Config, prompt string) string {
+	if preamble := buildLengthConstraintPreamble(cfg); preamble != "" {
+		return preamble + "\n\n" + prompt
+	}
+	return prompt
+}
+
 // generateWithOpenAI uses OpenAI to generate a commit message
 func generateWithOpenAI(cfg *config.Config, prompt string) (string, error) {
 	type Message struct {
@@ -1531,13 +2525,16 @@ func generateWithOpenAI(cfg *config.Config, prompt string) (string, error) {
 		Model       string    `json:"model"`
 		Messages    []Message `json:"messages"`
 		MaxTokens   int       `json:"max_tokens,omitempty"`
-		Temperature float64   `json:"temperature,omitempty"`
+		Temperature *float64  `json:"temperature,omitempty"`
+		Seed        *int      `json:"seed,omitempty"`
+		Stream      bool      `json:"stream,omitempty"`
 	}
 
 	type Response struct {
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
+				Refusal string `json:"refusal,omitempty"` // Set instead of content when the model declines
 			} `json:"message"`
 		} `json:"choices"`
 		Error json.RawMessage `json:"error,omitempty"`
@@ -1549,27 +2546,12 @@ func generateWithOpenAI(cfg *config.Config, prompt string) (string, error) {
 		Code    string `json:"code"`
 	}
 
-	// Get or create system prompt
-	systemPrompt := getSystemPrompt(cfg)
-
-	// Add a prefix emphasizing length requirements regardless of custom prompts
-	lengthPrefix := fmt.Sprintf("MOST IMPORTANT INSTRUCTION: Your commit message subject MUST be under %d characters total. ", cfg.Commit.MaxLength)
-	if cfg.Commit.Convention == config.ConventionalCommits {
-		lengthPrefix += fmt.Sprintf("For conventional commits, this means the ENTIRE string 'type(scope): subject' must be under %d characters. Be extremely brief.", cfg.Commit.MaxLength)
-		lengthPrefix += "\n\nYOU MUST START YOUR RESPONSE WITH A CONVENTIONAL COMMIT TYPE. DO NOT START WITH JUST A COLON."
-		lengthPrefix += "\nCORRECT FORMAT: 'feat: add new feature'"
-		lengthPrefix += "\nINCORRECT FORMAT: ': add new feature'"
-		lengthPrefix += "\nValid types are: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert"
-
-		if cfg.Commit.IncludeBody {
-			lengthPrefix += "\n\nYOU MUST INCLUDE A COMMIT BODY AFTER THE SUBJECT. The body must be separated from the subject by a blank line."
-			lengthPrefix += "\nThe body MUST NOT be empty and should explain what changes were made and why."
-		}
+	// Get or create the system prompt, with the length requirement in front of it
+	systemPrompt, err := systemMessage(cfg)
+	if err != nil {
+		return "", err
 	}
 
-	// Prepend the length requirement to any system prompt
-	systemPrompt = lengthPrefix + "\n\n" + systemPrompt
-
 	// Create request
 	reqBody := Request{
 		Model: cfg.AI.Model,
@@ -1583,8 +2565,10 @@ func generateWithOpenAI(cfg *config.Config, prompt string) (string, error) {
 				Content: prompt,
 			},
 		},
-		MaxTokens:   cfg.AI.MaxTokens,
-		Temperature: cfg.AI.Temperature,
+		MaxTokens:   maxOutputTokens(cfg),
+		Temperature: requestTemperature(cfg),
+		Seed:        requestSeed(cfg),
+		Stream:      cfg.AI.Stream,
 	}
 
 	// Debug: Show the request being sent to OpenAI
@@ -1601,77 +2585,75 @@ func generateWithOpenAI(cfg *config.Config, prompt string) (string, error) {
 		endpoint = "https://api.openai.com/v1/chat/completions"
 	}
 
-	// Make API request
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(reqData))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+cfg.AI.APIKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Make API request, spreading requests over ai.endpoints when configured
+	headers := map[string]string{"Authorization": "Bearer " + cfg.AI.APIKey}
+	resp, err := postJSON(cfg, "OpenAI", providerEndpoints(cfg, endpoint), reqData, headers)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	// Read response
-	respData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	var content string
+	if cfg.AI.Stream && resp.StatusCode == http.StatusOK {
+		// Errors are returned as a regular JSON body, so only successful responses are streamed
+		content, err = readOpenAIStream(cfg, resp.Body)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// Read response
+		respData, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
 
-	// Debug: Show the raw API response
-	debugPrint(cfg, "OPENAI RAW RESPONSE", string(respData))
+		// Debug: Show the raw API response
+		debugPrint(cfg, "OPENAI RAW RESPONSE", string(respData))
 
-	var response Response
-	err = json.Unmarshal(respData, &response)
-	if err != nil {
-		return "", err
-	}
+		var response Response
+		err = json.Unmarshal(respData, &response)
+		if err != nil {
+			if resp.StatusCode != http.StatusOK {
+				return "", providerError("OpenAI", resp.StatusCode, string(respData))
+			}
+			return "", err
+		}
 
-	// Check for API error
-	if len(response.Error) > 0 {
-		var errorMessage string
+		// Check for API error
+		if len(response.Error) > 0 {
+			var errorMessage string
 
-		// Try to parse as object first
-		var errResp ErrorResponse
-		if err := json.Unmarshal(response.Error, &errResp); err == nil && errResp.Message != "" {
-			errorMessage = errResp.Message
-		} else {
-			// Try to parse as string
-			var errStr string
-			if err := json.Unmarshal(response.Error, &errStr); err == nil && errStr != "" {
-				errorMessage = errStr
+			// Try to parse as object first
+			var errResp ErrorResponse
+			if err := json.Unmarshal(response.Error, &errResp); err == nil && errResp.Message != "" {
+				errorMessage = errResp.Message
 			} else {
-				// If neither works, use the raw error
-				errorMessage = string(response.Error)
+				// Try to parse as string
+				var errStr string
+				if err := json.Unmarshal(response.Error, &errStr); err == nil && errStr != "" {
+					errorMessage = errStr
+				} else {
+					// If neither works, use the raw error
+					errorMessage = string(response.Error)
+				}
 			}
-		}
 
-		// Enhanced error handling for token limit errors
-		if strings.Contains(errorMessage, "maximum context length") || strings.Contains(errorMessage, "context_length_exceeded") {
-			return "", fmt.Errorf("OpenAI API error: %s\n\nChangeset too large even after optimization. Consider:\n"+
-				"  1. Split into smaller commits\n"+
-				"  2. Set diff_strategy: 'batch' in your config\n"+
-				"  3. Reduce max_input_tokens in your config\n"+
-				"  4. Disable include_diff temporarily", errorMessage)
+			return "", providerError("OpenAI", resp.StatusCode, errorMessage)
 		}
 
-		return "", fmt.Errorf("OpenAI API error: %s", errorMessage)
-	}
+		// Check if we got results
+		if len(response.Choices) == 0 {
+			return "", fmt.Errorf("no response from OpenAI API")
+		}
+		if refusal := strings.TrimSpace(response.Choices[0].Message.Refusal); refusal != "" {
+			return "", &RefusalError{Provider: "OpenAI", Text: refusal}
+		}
 
-	// Check if we got results
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI API")
+		content = strings.TrimSpace(response.Choices[0].Message.Content)
 	}
 
-	content := strings.TrimSpace(response.Choices[0].Message.Content)
-
 	// For conventional commits, validate the response starts with a valid type
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		// Fix if the response starts with a colon instead of a type
 		if strings.HasPrefix(content, ": ") {
 			content = "chore" + content
@@ -1685,23 +2667,8 @@ func generateWithOpenAI(cfg *config.Config, prompt string) (string, error) {
 
 // generateWithGemini uses Google's Gemini to generate a commit message
 func generateWithGemini(cfg *config.Config, prompt string) (string, error) {
-	// Add a length requirement prefix to the prompt
-	lengthPrefix := fmt.Sprintf("CRITICAL INSTRUCTION: Your commit message subject MUST be under %d characters total. ", cfg.Commit.MaxLength)
-	if cfg.Commit.Convention == config.ConventionalCommits {
-		lengthPrefix += fmt.Sprintf("For conventional commits, this means the ENTIRE string 'type(scope): subject' must be under %d characters.", cfg.Commit.MaxLength)
-		lengthPrefix += "\n\nYOU MUST START YOUR RESPONSE WITH A CONVENTIONAL COMMIT TYPE. DO NOT START WITH JUST A COLON."
-		lengthPrefix += "\nCORRECT: 'feat: add new feature'"
-		lengthPrefix += "\nINCORRECT: ': add new feature'"
-		lengthPrefix += "\nValid types are: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert"
-
-		if cfg.Commit.IncludeBody {
-			lengthPrefix += "\n\nYOU MUST INCLUDE A COMMIT BODY AFTER THE SUBJECT. The body must be separated from the subject by a blank line."
-			lengthPrefix += "\nThe body MUST NOT be empty and should explain what changes were made and why."
-		}
-	}
-
 	// Prepend the length requirement to the prompt
-	enhancedPrompt := lengthPrefix + "\n\n" + prompt
+	enhancedPrompt := withLengthPreamble(cfg, prompt)
 
 	type Request struct {
 		Contents []struct {
@@ -1753,7 +2720,7 @@ func generateWithGemini(cfg *config.Config, prompt string) (string, error) {
 
 	// Make API request
 	apiURL := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", cfg.AI.Model, cfg.AI.APIKey)
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(reqData))
+	req, err := http.NewRequestWithContext(requestContext(cfg), "POST", apiURL, bytes.NewBuffer(reqData))
 	if err != nil {
 		return "", err
 	}
@@ -1763,7 +2730,7 @@ func generateWithGemini(cfg *config.Config, prompt string) (string, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return "", unreachableError("Gemini", err)
 	}
 	defer resp.Body.Close()
 
@@ -1779,12 +2746,15 @@ func generateWithGemini(cfg *config.Config, prompt string) (string, error) {
 	var response Response
 	err = json.Unmarshal(respData, &response)
 	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", providerError("Gemini", resp.StatusCode, string(respData))
+		}
 		return "", err
 	}
 
 	// Check for API error
 	if response.Error.Message != "" {
-		return "", fmt.Errorf("Gemini API error: %s", response.Error.Message)
+		return "", providerError("Gemini", resp.StatusCode, response.Error.Message)
 	}
 
 	// Check if we got results
@@ -1795,7 +2765,7 @@ func generateWithGemini(cfg *config.Config, prompt string) (string, error) {
 	content := strings.TrimSpace(response.Candidates[0].Content.Parts[0].Text)
 
 	// For conventional commits, validate the response starts with a valid type
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		// Fix if the response starts with a colon instead of a type
 		if strings.HasPrefix(content, ": ") {
 			content = "chore" + content
@@ -1809,30 +2779,16 @@ func generateWithGemini(cfg *config.Config, prompt string) (string, error) {
 
 // generateWithOllama uses Ollama (local) to generate a commit message
 func generateWithOllama(cfg *config.Config, prompt string) (string, error) {
-	// Add a length requirement prefix to the prompt
-	lengthPrefix := fmt.Sprintf("CRITICAL INSTRUCTION: Your commit message subject MUST be under %d characters total. ", cfg.Commit.MaxLength)
-	if cfg.Commit.Convention == config.ConventionalCommits {
-		lengthPrefix += fmt.Sprintf("For conventional commits, this means the ENTIRE string 'type(scope): subject' must be under %d characters.", cfg.Commit.MaxLength)
-		lengthPrefix += "\n\nYOU MUST START YOUR RESPONSE WITH A CONVENTIONAL COMMIT TYPE. DO NOT START WITH JUST A COLON."
-		lengthPrefix += "\nCORRECT: 'feat: add new feature'"
-		lengthPrefix += "\nINCORRECT: ': add new feature'"
-		lengthPrefix += "\nValid types are: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert"
-
-		if cfg.Commit.IncludeBody {
-			lengthPrefix += "\n\nYOU MUST INCLUDE A COMMIT BODY AFTER THE SUBJECT. The body must be separated from the subject by a blank line."
-			lengthPrefix += "\nThe body MUST NOT be empty and should explain what changes were made and why."
-		}
-	}
-
 	// Prepend the length requirement to the prompt
-	enhancedPrompt := lengthPrefix + "\n\n" + prompt
+	enhancedPrompt := withLengthPreamble(cfg, prompt)
 
 	type Request struct {
 		Model       string  `json:"model"`
 		Prompt      string  `json:"prompt"`
 		Stream      bool    `json:"stream"`
-		Temperature float64 `json:"temperature,omitempty"`
-		MaxTokens   int     `json:"max_tokens,omitempty"`
+		Temperature *float64               `json:"temperature,omitempty"`
+		MaxTokens   int                    `json:"max_tokens,omitempty"`
+		Options     map[string]interface{} `json:"options,omitempty"`
 	}
 
 	type Response struct {
@@ -1848,19 +2804,24 @@ func generateWithOllama(cfg *config.Config, prompt string) (string, error) {
 		Done      bool   `json:"done"`
 	}
 
-	// Set default host if not specified
-	ollamaHost := cfg.AI.OllamaHost
-	if ollamaHost == "" {
-		ollamaHost = "http://localhost:11434"
+	// Size the context to the prompt, since a prompt over num_ctx is cut by the server
+	enhancedPrompt, numCtx := fitOllamaPrompt(cfg, enhancedPrompt)
+	options := ollamaOptions(cfg)
+	if options == nil {
+		options = make(map[string]interface{})
 	}
+	options["num_ctx"] = numCtx
+
+	ollamaHost := ollamaHostFor(cfg)
 
 	// Create request for the /api/generate endpoint
 	reqBody := Request{
 		Model:       cfg.AI.Model,
 		Prompt:      enhancedPrompt, // Use the enhanced prompt
 		Stream:      false,
-		Temperature: cfg.AI.Temperature,
-		MaxTokens:   cfg.AI.MaxTokens,
+		Temperature: requestTemperature(cfg),
+		MaxTokens:   maxOutputTokens(cfg),
+		Options:     options,
 	}
 
 	// Debug: Show the request being sent to Ollama
@@ -1874,16 +2835,13 @@ func generateWithOllama(cfg *config.Config, prompt string) (string, error) {
 	// Debug: Show the Ollama host being used
 	debugPrint(cfg, "OLLAMA HOST", ollamaHost)
 
-	// Make API request - use the completion endpoint instead of generate
-	req, err := http.NewRequest("POST", ollamaHost+"/api/generate", bytes.NewBuffer(reqData))
-	if err != nil {
-		return "", err
+	// Make API request; with ai.endpoints each entry is an Ollama host
+	hosts := providerEndpoints(cfg, ollamaHost)
+	endpoints := make([]string, len(hosts))
+	for i, host := range hosts {
+		endpoints[i] = strings.TrimSuffix(host, "/") + "/api/generate"
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := postJSON(cfg, "Ollama", endpoints, reqData, nil)
 	if err != nil {
 		return "", err
 	}
@@ -1892,7 +2850,7 @@ func generateWithOllama(cfg *config.Config, prompt string) (string, error) {
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return "", providerError("Ollama", resp.StatusCode, string(bodyBytes))
 	}
 
 	// For non-streaming response, we can read the entire body
@@ -1913,7 +2871,7 @@ func generateWithOllama(cfg *config.Config, prompt string) (string, error) {
 	content := strings.TrimSpace(response.Response)
 
 	// For conventional commits, validate the response starts with a valid type
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		// Fix if the response starts with a colon instead of a type
 		if strings.HasPrefix(content, ": ") {
 			content = "chore" + content
@@ -1927,23 +2885,8 @@ func generateWithOllama(cfg *config.Config, prompt string) (string, error) {
 
 // generateWithClaude uses Anthropic's Claude to generate a commit message
 func generateWithClaude(cfg *config.Config, prompt string) (string, error) {
-	// Add a length requirement prefix to the prompt
-	lengthPrefix := fmt.Sprintf("CRITICAL INSTRUCTION: Your commit message subject MUST be under %d characters total. ", cfg.Commit.MaxLength)
-	if cfg.Commit.Convention == config.ConventionalCommits {
-		lengthPrefix += fmt.Sprintf("For conventional commits, this means the ENTIRE string 'type(scope): subject' must be under %d characters.", cfg.Commit.MaxLength)
-		lengthPrefix += "\n\nYOU MUST START YOUR RESPONSE WITH A CONVENTIONAL COMMIT TYPE. DO NOT START WITH JUST A COLON."
-		lengthPrefix += "\nCORRECT: 'feat: add new feature'"
-		lengthPrefix += "\nINCORRECT: ': add new feature'"
-		lengthPrefix += "\nValid types are: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert"
-
-		if cfg.Commit.IncludeBody {
-			lengthPrefix += "\n\nYOU MUST INCLUDE A COMMIT BODY AFTER THE SUBJECT. The body must be separated from the subject by a blank line."
-			lengthPrefix += "\nThe body MUST NOT be empty and should explain what changes were made and why."
-		}
-	}
-
 	// Prepend the length requirement to the prompt
-	enhancedPrompt := lengthPrefix + "\n\n" + prompt
+	enhancedPrompt := withLengthPreamble(cfg, prompt)
 
 	type Message struct {
 		Role    string `json:"role"`
@@ -1951,9 +2894,10 @@ func generateWithClaude(cfg *config.Config, prompt string) (string, error) {
 	}
 
 	type Request struct {
-		Model     string    `json:"model"`
-		Messages  []Message `json:"messages"`
-		MaxTokens int       `json:"max_tokens"`
+		Model       string    `json:"model"`
+		Messages    []Message `json:"messages"`
+		MaxTokens   int       `json:"max_tokens"`
+		Temperature *float64  `json:"temperature,omitempty"` // Only sent with --deterministic
 	}
 
 	type Response struct {
@@ -1975,7 +2919,10 @@ func generateWithClaude(cfg *config.Config, prompt string) (string, error) {
 				Content: enhancedPrompt, // Use the enhanced prompt
 			},
 		},
-		MaxTokens: cfg.AI.MaxTokens,
+		MaxTokens: maxOutputTokens(cfg),
+	}
+	if cfg.Deterministic {
+		reqBody.Temperature = requestTemperature(cfg)
 	}
 
 	// Debug: Show the request being sent to Claude
@@ -1987,7 +2934,7 @@ func generateWithClaude(cfg *config.Config, prompt string) (string, error) {
 	}
 
 	// Make API request
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(reqData))
+	req, err := http.NewRequestWithContext(requestContext(cfg), "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(reqData))
 	if err != nil {
 		return "", err
 	}
@@ -1999,7 +2946,7 @@ func generateWithClaude(cfg *config.Config, prompt string) (string, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return "", unreachableError("Claude", err)
 	}
 	defer resp.Body.Close()
 
@@ -2015,18 +2962,21 @@ func generateWithClaude(cfg *config.Config, prompt string) (string, error) {
 	var response Response
 	err = json.Unmarshal(respData, &response)
 	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", providerError("Claude", resp.StatusCode, string(respData))
+		}
 		return "", fmt.Errorf("error parsing Claude response: %w (response: %s)", err, string(respData))
 	}
 
 	// Check for API error
 	if response.Error.Message != "" {
-		return "", fmt.Errorf("Claude API error: %s", response.Error.Message)
+		return "", providerError("Claude", resp.StatusCode, response.Error.Message)
 	}
 
 	content := strings.TrimSpace(response.Content.Text)
 
 	// For conventional commits, validate the response starts with a valid type
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		// Fix if the response starts with a colon instead of a type
 		if strings.HasPrefix(content, ": ") {
 			content = "chore" + content
@@ -2044,12 +2994,19 @@ func getSystemPrompt(cfg *config.Config) string {
 	if cfg.AI.SystemPrompt != "" {
 		return cfg.AI.SystemPrompt
 	}
+	if cfg.AI.SystemPromptTemplate != nil {
+		prompt, err := renderPromptTemplate(cfg.AI.SystemPromptTemplate, newPromptData(cfg, nil, ""))
+		if err == nil {
+			return prompt
+		}
+		debugPrint(cfg, "SYSTEM PROMPT TEMPLATE FAILED", err.Error())
+	}
 
 	// For conventional commits, use a more specific prompt that matches text prompt style
-	if cfg.Commit.Convention == config.ConventionalCommits {
+	if cfg.Commit.Convention.IsConventional() {
 		promptParts := []string{
 			"Generate a concise git commit message written in present tense for the following code changes.",
-			"YOUR RESPONSE MUST START WITH A CONVENTIONAL COMMIT TYPE FOLLOWED BY A COLON. Valid types are: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert.",
+			"YOUR RESPONSE MUST START WITH A CONVENTIONAL COMMIT TYPE FOLLOWED BY A COLON. Valid types are: "+commitTypeList(cfg)+".",
 			"INCORRECT: ': description of changes' - This lacks a commit type",
 			"CORRECT: 'feat: add new feature' - This has a proper commit type",
 			fmt.Sprintf("CRITICAL REQUIREMENT: Commit message subject MUST NOT exceed %d characters total. YOU MUST COUNT THE CHARACTERS YOURSELF AND ENSURE THE TOTAL IS UNDER %d. This is a HARD REQUIREMENT.", cfg.Commit.MaxLength, cfg.Commit.MaxLength),
@@ -2057,6 +3014,10 @@ func getSystemPrompt(cfg *config.Config) string {
 			fmt.Sprintf("If using 'feat(scope): subject' format, the ENTIRE string including 'feat(scope): ' counts toward the %d character limit.", cfg.Commit.MaxLength),
 		}
 
+		if instruction := scopeInstruction(cfg); instruction != "" {
+			promptParts = append(promptParts, instruction)
+		}
+
 		// Add conventional commit rules
 		promptParts = append(promptParts, "You MUST follow these conventional commit rules:")
 		promptParts = append(promptParts, ConventionalCommitRules)
@@ -2083,10 +3044,11 @@ func getSystemPrompt(cfg *config.Config) string {
 - revert: Reverts a previous commit`)
 
 		// Add examples of good length subjects
-		promptParts = append(promptParts, fmt.Sprintf("Examples of good length subjects that meet the %d character limit:\n- fix: update validation logic (%d chars)\n- feat(auth): add login timeout (%d chars)",
+		promptParts = append(promptParts, fmt.Sprintf("Examples of good length subjects that meet the %d character limit:\n- fix: update validation logic (%d chars)\n- %s (%d chars)",
 			cfg.Commit.MaxLength,
 			len("fix: update validation logic"),
-			len("feat(auth): add login timeout")))
+			scopedExample(cfg),
+			len(scopedExample(cfg))))
 
 		return strings.Join(promptParts, "\n")
 	}
@@ -2095,45 +3057,60 @@ func getSystemPrompt(cfg *config.Config) string {
 	return "You are an expert developer who writes clear, concise, and descriptive git commit messages that do not exceed the specified character limits."
 }
 
-// debugPrint prints debug information if debug mode is enabled
-func debugPrint(cfg *config.Config, message string, data interface{}) {
-	if !cfg.AI.Debug {
-		return
+// scopeFormat returns the conventional subject format for the configured scope requirement
+func scopeFormat(cfg *config.Config) string {
+	switch cfg.Commit.Scope {
+	case config.ScopeRequired:
+		return "type(scope): subject"
+	case config.ScopeForbidden:
+		return "type: subject"
+	default:
+		return "type(optional-scope): subject"
 	}
+}
 
-	// Create a debug marker for visibility
-	debugMarker := "\n==== COMMITRON DEBUG ====\n"
-
-	// Format the data based on its type
-	var formattedData string
-	switch v := data.(type) {
-	case string:
-		formattedData = v
-	case []byte:
-		formattedData = string(v)
+// scopeInstruction returns the prompt instruction for a required or forbidden scope
+func scopeInstruction(cfg *config.Config) string {
+	switch cfg.Commit.Scope {
+	case config.ScopeRequired:
+		return "A SCOPE IS REQUIRED. Always include a lowercase scope in parentheses after the type, e.g. 'fix(parser): correct array parsing issue'."
+	case config.ScopeForbidden:
+		return "DO NOT INCLUDE A SCOPE. Write 'type: subject' with no parentheses after the type."
 	default:
-		if data != nil {
-			jsonData, err := json.MarshalIndent(data, "", "  ")
-			if err == nil {
-				formattedData = string(jsonData)
-			} else {
-				formattedData = fmt.Sprintf("%+v", data)
-			}
-		}
+		return ""
 	}
+}
+
+// scopedExample returns an example subject that follows the scope requirement
+func scopedExample(cfg *config.Config) string {
+	if cfg.Commit.Scope == config.ScopeForbidden {
+		return "feat: add login timeout"
+	}
+	return "feat(auth): add login timeout"
+}
 
-	// Print the debug information
-	fmt.Printf("%s%s:\n%s\n%s\n",
-		debugMarker,
-		message,
-		formattedData,
-		strings.Repeat("=", len(debugMarker)-1))
+// scopeJSONExample returns the annotated scope field used in the JSON response example
+func scopeJSONExample(cfg *config.Config) string {
+	switch cfg.Commit.Scope {
+	case config.ScopeRequired:
+		return `"scope", // Required, must be lowercase`
+	case config.ScopeForbidden:
+		return `"", // Must be empty, scopes are not allowed`
+	default:
+		return `"optional scope", // Optional, must be lowercase`
+	}
 }
 
 // GatherEnhancedFileInfo collects detailed information about the changed files
 func GatherEnhancedFileInfo(cfg *config.Config, files []string) ([]EnhancedFileInfo, error) {
 	var fileInfos []EnhancedFileInfo
 
+	// One numstat call covers every file
+	var lineCounts map[string]git.FileChange
+	if cfg.Context.IncludeFileStats {
+		lineCounts, _ = git.StagedNumstat(cfg.Context.DiffBase)
+	}
+
 	for _, file := range files {
 		info := EnhancedFileInfo{
 			Path: file,
@@ -2156,38 +3133,10 @@ func GatherEnhancedFileInfo(cfg *config.Config, files []string) ([]EnhancedFileI
 		}
 
 		// Get stats about line changes if enabled
-		if cfg.Context.IncludeFileStats {
-			// Use git diff --numstat to get line changes
-			cmd := exec.Command("git", "diff", "--staged", "--numstat", "--", file)
-			output, err := cmd.Output()
-			if err == nil {
-				// Parse the numstat output (format: <added> <removed> <file>)
-				parts := strings.Fields(string(output))
-				if len(parts) >= 2 {
-					// Extract added/removed counts, ignoring binary files (shown as "-")
-					if parts[0] != "-" {
-						fmt.Sscanf(parts[0], "%d", &info.AddedLines)
-					}
-					if parts[1] != "-" {
-						fmt.Sscanf(parts[1], "%d", &info.RemovedLines)
-					}
-
-					// Calculate percentage of file changed
-					if info.AddedLines > 0 || info.RemovedLines > 0 {
-						// Get total lines in file
-						cmd = exec.Command("wc", "-l", file)
-						wcOutput, err := cmd.Output()
-						if err == nil {
-							var totalLines int
-							fmt.Sscanf(string(wcOutput), "%d", &totalLines)
-							if totalLines > 0 {
-								changePercentage := float64(info.AddedLines+info.RemovedLines) / float64(totalLines) * 100
-								info.PercentageChange = fmt.Sprintf("%.1f%%", changePercentage)
-							}
-						}
-					}
-				}
-			}
+		if change, ok := lineCounts[file]; ok {
+			info.AddedLines = change.Added
+			info.RemovedLines = change.Removed
+			info.PercentageChange = percentageChange(change, file, cfg.Context.DiffBase)
 		}
 
 		// Get file summary if enabled
@@ -2281,6 +3230,39 @@ func GatherEnhancedFileInfo(cfg *config.Config, files []string) ([]EnhancedFileI
 	return fileInfos, nil
 }
 
+// percentageChange describes how much of a staged file changed, measured against its line
+// count before the change (at base, or HEAD) rather than the working tree, which may hold
+// unstaged edits or no file at all. New and deleted files are 100% changed.
+func percentageChange(change git.FileChange, path, base string) string {
+	switch change.Status {
+	case "A":
+		return "100% (new file)"
+	case "D":
+		return "100% (deleted)"
+	}
+	if change.Added == 0 && change.Removed == 0 {
+		return "" // Binary or mode-only change
+	}
+
+	if base == "" {
+		base = "HEAD"
+	}
+	if change.OldPath != "" {
+		path = change.OldPath
+	}
+	before, err := git.BlobLineCount(base, path)
+	if err != nil || before == 0 {
+		return ""
+	}
+
+	// A modified line is one removal plus one addition, so count the larger side
+	changed := max(change.Added, change.Removed)
+	if changed > before {
+		return fmt.Sprintf(">100%% (%d lines before, %d after)", before, before+change.Added-change.Removed)
+	}
+	return fmt.Sprintf("%.1f%%", float64(changed)/float64(before)*100)
+}
+
 // GetRepoStructure returns a high-level overview of the repository structure
 func GetRepoStructure(cfg *config.Config) (string, error) {
 	if !cfg.Context.IncludeRepoStructure {
@@ -2298,11 +3280,15 @@ func GetRepoStructure(cfg *config.Config) (string, error) {
 	var result strings.Builder
 	result.WriteString("Repository structure:\n")
 
+	exclusions := contextExclusions(cfg)
 	dirs := strings.Split(strings.TrimSpace(string(output)), "\n")
 	for _, dir := range dirs {
 		if dir == "." {
 			continue // Skip root
 		}
+		if exclusions.MatchDir(strings.TrimPrefix(dir, "./")) {
+			continue
+		}
 
 		// Count files in directory (using separate commands since pipes aren't directly supported)
 		findCmd := exec.Command("find", dir, "-type", "f", "-not", "-path", "*/\\.*", "-maxdepth", "1")
@@ -2329,21 +3315,29 @@ func GetRepoStructure(cfg *config.Config) (string, error) {
 	return result.String(), nil
 }
 
+// nonImperativeVerbs are past-tense and third-person forms of the verbs that start commit
+// subjects most often. A list rather than a suffix rule, since imperatives like "embed",
+// "proceed", "focus" and "alias" end in "ed" or "s" too.
+var nonImperativeVerbs = map[string]bool{
+	"added": true, "adds": true, "fixed": true, "fixes": true, "removed": true, "removes": true,
+	"updated": true, "updates": true, "changed": true, "changes": true, "improved": true, "improves": true,
+	"refactored": true, "refactors": true, "renamed": true, "renames": true, "moved": true, "moves": true,
+	"deleted": true, "deletes": true, "created": true, "creates": true, "implemented": true, "implements": true,
+	"introduced": true, "introduces": true, "replaced": true, "replaces": true, "dropped": true, "drops": true,
+	"bumped": true, "bumps": true, "upgraded": true, "upgrades": true, "cleaned": true, "cleans": true,
+	"corrected": true, "corrects": true, "enabled": true, "enables": true, "disabled": true, "disables": true,
+	"simplified": true, "simplifies": true, "supported": true, "supports": true, "allowed": true, "allows": true,
+	"made": true, "makes": true, "used": true, "uses": true, "handled": true, "handles": true,
+	"optimized": true, "optimizes": true, "documented": true, "documents": true, "tested": true, "tests": true,
+	"reverted": true, "reverts": true, "merged": true, "merges": true, "extracted": true, "extracts": true,
+}
+
 // validateConventionalCommit checks if a commit message follows conventional commit rules
 func validateConventionalCommit(msg CommitMessage, cfg *config.Config) error {
 	// Check if type is one of the allowed types
-	allowedTypes := map[string]bool{
-		"feat":     true,
-		"fix":      true,
-		"docs":     true,
-		"style":    true,
-		"refactor": true,
-		"perf":     true,
-		"test":     true,
-		"build":    true,
-		"ci":       true,
-		"chore":    true,
-		"revert":   true,
+	allowedTypes := make(map[string]bool)
+	for _, t := range cfg.Commit.Convention.CommitTypes() {
+		allowedTypes[t] = true
 	}
 
 	// Type is required and must be one of the allowed types
@@ -2358,7 +3352,7 @@ func validateConventionalCommit(msg CommitMessage, cfg *config.Config) error {
 
 	// Check if type is allowed
 	if !allowedTypes[msg.Type] {
-		return fmt.Errorf("commit type '%s' is not allowed for conventional commits; must be one of: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert", msg.Type)
+		return fmt.Errorf("commit type '%s' is not allowed for %s commits; must be one of: %s", msg.Type, cfg.Commit.Convention, commitTypeList(cfg))
 	}
 
 	// Subject is required
@@ -2366,8 +3360,8 @@ func validateConventionalCommit(msg CommitMessage, cfg *config.Config) error {
 		return fmt.Errorf("commit subject is required for conventional commits")
 	}
 
-	// Subject should not end with a period
-	if strings.HasSuffix(msg.Subject, ".") {
+	// Subject should not end with a period; the "..." of a shortened subject isn't one
+	if strings.HasSuffix(msg.Subject, ".") && !strings.HasSuffix(msg.Subject, "...") {
 		return fmt.Errorf("commit subject should not end with a period")
 	}
 
@@ -2376,6 +3370,16 @@ func validateConventionalCommit(msg CommitMessage, cfg *config.Config) error {
 		return fmt.Errorf("commit subject should not start with a capital letter")
 	}
 
+	// Angular subjects use the imperative, present tense: "change" not "changed" nor "changes"
+	if cfg.Commit.Convention == config.AngularConvention {
+		if fields := strings.Fields(strings.ToLower(msg.Subject)); len(fields) > 0 {
+			first := fields[0]
+			if nonImperativeVerbs[first] {
+				return fmt.Errorf("angular commit subjects must use the imperative, present tense: %q", first)
+			}
+		}
+	}
+
 	// Subject should not contain newlines
 	if strings.Contains(msg.Subject, "\n") {
 		return fmt.Errorf("commit subject should not contain newlines")
@@ -2434,6 +3438,14 @@ func validateConventionalCommit(msg CommitMessage, cfg *config.Config) error {
 		}
 	}
 
+	// Enforce the configured scope requirement
+	if cfg.Commit.Scope == config.ScopeRequired && msg.Scope == "" {
+		return fmt.Errorf("commit scope is required by configuration")
+	}
+	if cfg.Commit.Scope == config.ScopeForbidden && msg.Scope != "" {
+		return fmt.Errorf("commit scope is forbidden by configuration: %s", msg.Scope)
+	}
+
 	// Validate scope format if present
 	if msg.Scope != "" {
 		// Scope should be lowercase
@@ -2484,8 +3496,8 @@ func fixConventionalCommitIssues(msg CommitMessage) CommitMessage {
 		msg.Type = correctedType
 	}
 
-	// Remove trailing period from subject
-	if strings.HasSuffix(msg.Subject, ".") {
+	// Remove trailing period from subject, keeping the "..." of a shortened one
+	if strings.HasSuffix(msg.Subject, ".") && !strings.HasSuffix(msg.Subject, "...") {
 		msg.Subject = msg.Subject[:len(msg.Subject)-1]
 	}
 
@@ -2569,20 +3581,17 @@ func fixConventionalCommitIssues(msg CommitMessage) CommitMessage {
 	return msg
 }
 
-// isValidCommitType checks if a string is a valid conventional commit type
-func isValidCommitType(t string) bool {
-	validTypes := map[string]bool{
-		"feat":     true,
-		"fix":      true,
-		"docs":     true,
-		"style":    true,
-		"refactor": true,
-		"perf":     true,
-		"test":     true,
-		"build":    true,
-		"ci":       true,
-		"chore":    true,
-		"revert":   true,
-	}
-	return validTypes[t]
+// isValidCommitType checks if a string is a valid commit type for the configured convention
+func isValidCommitType(t string, cfg *config.Config) bool {
+	for _, valid := range cfg.Commit.Convention.CommitTypes() {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// commitTypeList returns the allowed commit types as a comma-separated list for prompts
+func commitTypeList(cfg *config.Config) string {
+	return strings.Join(cfg.Commit.Convention.CommitTypes(), ", ")
 }
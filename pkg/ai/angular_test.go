@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func angularConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.ParseConfig([]byte("commit:\n  convention: angular\n  include_body: false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestAngularRequiresScope(t *testing.T) {
+	cfg := angularConfig(t)
+	if cfg.Commit.Scope != config.ScopeRequired {
+		t.Fatalf("scope = %q, want %q", cfg.Commit.Scope, config.ScopeRequired)
+	}
+	if reason := regenerationReason(CommitMessage{Type: "fix", Subject: "handle empty input"}, cfg); !strings.Contains(reason, "scope is required") {
+		t.Errorf("missing scope: reason = %q", reason)
+	}
+	if reason := regenerationReason(CommitMessage{Type: "fix", Scope: "parser", Subject: "handle empty input"}, cfg); reason != "" {
+		t.Errorf("with scope: reason = %q, want none", reason)
+	}
+}
+
+func TestAngularTypes(t *testing.T) {
+	cfg := angularConfig(t)
+	for _, tt := range []struct {
+		typ string
+		ok  bool
+	}{
+		{"feat", true}, {"fix", true}, {"perf", true}, {"build", true},
+		{"chore", false}, {"style", false}, {"revert", false}, {"Feat", false},
+	} {
+		err := validateConventionalCommit(CommitMessage{Type: tt.typ, Scope: "core", Subject: "handle empty input"}, cfg)
+		if (err == nil) != tt.ok {
+			t.Errorf("type %q: err = %v, want ok = %v", tt.typ, err, tt.ok)
+		}
+	}
+}
+
+func TestAngularImperativeSubject(t *testing.T) {
+	cfg := angularConfig(t)
+	for _, tt := range []struct {
+		subject string
+		ok      bool
+	}{
+		{"add retry to uploads", true},
+		{"embed the default config", true},
+		{"seed the test database", true},
+		{"proceed after a failed fetch", true},
+		{"focus the search box on load", true},
+		{"alias the old flag", true},
+		{"address review comments", true},
+		{"added retry to uploads", false},
+		{"fixes the empty input crash", false},
+		{"Updated dependencies", false},
+		{"removes dead code", false},
+	} {
+		err := validateConventionalCommit(CommitMessage{Type: "fix", Scope: "core", Subject: tt.subject}, cfg)
+		if (err == nil) != tt.ok {
+			t.Errorf("subject %q: err = %v, want ok = %v", tt.subject, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckMessageAcceptsAngularImperatives(t *testing.T) {
+	cfg := angularConfig(t)
+	if warnings := CheckMessage("build(assets): embed the templates", cfg); len(warnings) > 0 {
+		t.Errorf("warnings = %q, want none", warnings)
+	}
+	if warnings := CheckMessage("build(assets): added the templates", cfg); len(warnings) == 0 {
+		t.Error("past tense subject: no warning")
+	}
+}
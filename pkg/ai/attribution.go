@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/version"
+)
+
+// attributionTrailer expands commit.attribution_trailer, or returns "" when it is off
+func attributionTrailer(cfg *config.Config) string {
+	template := strings.TrimSpace(string(cfg.Commit.AttributionTrailer))
+	if template == "" {
+		return ""
+	}
+
+	replacer := strings.NewReplacer(
+		"{{provider}}", string(cfg.AI.Provider),
+		"{{model}}", cfg.AI.Model,
+		"{{version}}", version.Version,
+	)
+	return replacer.Replace(template)
+}
+
+// EnsureAttribution appends the attribution trailer to a message that lost it, such as one
+// edited by the user, rewritten by format_command or taken from a raw response. The trailer
+// joins an existing trailer block so git still reads the footers as trailers.
+func EnsureAttribution(message string, cfg *config.Config) string {
+	trailer := attributionTrailer(cfg)
+	if trailer == "" {
+		return message
+	}
+
+	message = strings.TrimRight(normalizeNewlines(message), "\n")
+	for _, line := range strings.Split(message, "\n") {
+		if strings.TrimSpace(line) == trailer {
+			return message
+		}
+	}
+
+	if _, rest, found := strings.Cut(message, "\n\n"); found && trailerParagraph(rest) != "" {
+		return message + "\n" + trailer
+	}
+	return message + "\n\n" + trailer
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// trailerLinePattern matches a git trailer line such as "Refs: #12" or "BREAKING CHANGE: ..."
+var trailerLinePattern = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][A-Za-z0-9-]*): \S`)
+
+// RegenerateBody asks the provider for a new body for message, keeping its subject line
+// and any trailers, and returns the rebuilt message
+func RegenerateBody(cfg *config.Config, files []string, changes, message string) (string, error) {
+	subject, rest, _ := strings.Cut(normalizeNewlines(message), "\n\n")
+	subject = strings.TrimSpace(subject)
+	trailers := trailerParagraph(rest)
+
+	bodyCfg := *generationConfig(cfg)
+	bodyCfg.AI.DisableLengthPreamble = true
+	processed := processDiff(&bodyCfg, files, changes, false)
+	diff := processed.Changes
+
+	prompt := fmt.Sprintf("Given this commit subject: %q\n\n", subject) +
+		"Write only the body of the commit message for the changes below: a few short bullet points " +
+		"explaining what changed and why. Do not repeat the subject, do not add a subject line, " +
+		fmt.Sprintf("keep it under %d characters, and return only the body text.\n", cfg.Commit.MaxBodyLength)
+	if section := contextFileSection(cfg); section != "" {
+		prompt += strings.TrimPrefix(section, "\n") + "\n"
+	}
+	if cfg.Context.UserContext != "" {
+		prompt += fmt.Sprintf("Context from the author: %s\n", cfg.Context.UserContext)
+	}
+	if intent := intentSection(cfg); intent != "" {
+		prompt += strings.TrimPrefix(intent, "\n") + "\n"
+	}
+	if glossary := glossarySection(cfg); glossary != "" {
+		prompt += strings.TrimPrefix(glossary, "\n") + "\n"
+	}
+	if deletions := deletionsSection(withDeletedFiles(&bodyCfg, processed.Deleted)); deletions != "" {
+		prompt += strings.TrimPrefix(deletions, "\n")
+	}
+	prompt += fmt.Sprintf("\nChanges:\n```\n%s\n```", diff)
+	debugPrint(cfg, "BODY PROMPT", prompt)
+
+	response, err := callProvider(&bodyCfg, prompt)
+	if err != nil {
+		return "", err
+	}
+	debugPrint(cfg, "BODY RESPONSE", response)
+
+	body, err := sanitizeResponse(cfg, normalizeNewlines(response))
+	if err != nil {
+		return "", err
+	}
+	if match := codeFencePattern.FindStringSubmatch(body); match != nil {
+		body = strings.TrimSpace(match[1])
+	}
+	// Some models repeat the subject anyway
+	if first, remainder, ok := strings.Cut(body, "\n"); ok && strings.TrimSpace(first) == subject {
+		body = strings.TrimSpace(remainder)
+	}
+	if runes := []rune(body); cfg.Commit.MaxBodyLength > 3 && len(runes) > cfg.Commit.MaxBodyLength {
+		body = string(runes[:cfg.Commit.MaxBodyLength-3]) + "..."
+	}
+
+	build := func(body string) string {
+		result := subject
+		if formatted := formatBody(body); formatted != "" {
+			result += "\n\n" + formatted
+		}
+		if trailers != "" {
+			result += "\n\n" + trailers
+		}
+		return result
+	}
+	if cfg.Commit.MaxTotalLength > 0 && utf8.RuneCountInString(build(body)) > cfg.Commit.MaxTotalLength {
+		return fitTotalLength(cfg, body, cfg.Commit.MaxTotalLength, build), nil
+	}
+	return build(body), nil
+}
+
+// trailerParagraph returns the last paragraph of text if every line in it is a git trailer
+func trailerParagraph(text string) string {
+	paragraphs := strings.Split(strings.TrimSpace(text), "\n\n")
+	last := strings.TrimSpace(paragraphs[len(paragraphs)-1])
+	if last == "" {
+		return ""
+	}
+	for _, line := range strings.Split(last, "\n") {
+		if !trailerLinePattern.MatchString(line) {
+			return ""
+		}
+	}
+	return last
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Model bodies and their expected items and bullets
+var bodyGoldens = []struct {
+	name    string
+	body    string
+	items   []string
+	bullets string
+}{
+	{
+		name:    "one line",
+		body:    "Return early when the input is empty.",
+		items:   []string{"Return early when the input is empty."},
+		bullets: "- Return early when the input is empty.",
+	},
+	{
+		name:    "soft-wrapped sentence",
+		body:    "Return early when the input is empty so the\nparser never indexes past the end of the\nslice.",
+		items:   []string{"Return early when the input is empty so the parser never indexes past the end of the slice."},
+		bullets: "- Return early when the input is empty so the parser never indexes past the end of the slice.",
+	},
+	{
+		name:    "one sentence per line",
+		body:    "Guard the empty input.\nAdd a regression test!\nWhy now?\nSee the issue:\nIt crashed;\nno longer",
+		items:   []string{"Guard the empty input.", "Add a regression test!", "Why now?", "See the issue:", "It crashed;", "no longer"},
+		bullets: "- Guard the empty input.\n- Add a regression test!\n- Why now?\n- See the issue:\n- It crashed;\n- no longer",
+	},
+	{
+		name:    "existing bullets",
+		body:    "- Guard the empty input\n- Add a regression test\n* Update the docs",
+		items:   []string{"- Guard the empty input", "- Add a regression test", "* Update the docs"},
+		bullets: "- Guard the empty input\n- Add a regression test\n* Update the docs",
+	},
+	{
+		name:    "wrapped bullets",
+		body:    "- Guard the empty input so the parser\n  never indexes past the end\n- Add a regression test",
+		items:   []string{"- Guard the empty input so the parser never indexes past the end", "- Add a regression test"},
+		bullets: "- Guard the empty input so the parser never indexes past the end\n- Add a regression test",
+	},
+	{
+		name:    "other list markers",
+		body:    "1. Guard the empty input\n2) Add a test\n+ Update docs\n• Bump the version",
+		items:   []string{"1. Guard the empty input", "2) Add a test", "+ Update docs", "• Bump the version"},
+		bullets: "- 1. Guard the empty input\n- 2) Add a test\n- + Update docs\n- • Bump the version",
+	},
+	{
+		name:    "paragraphs",
+		body:    "\nGuard the empty input\n\nAdd a regression test\nfor it\n\n\n",
+		items:   []string{"Guard the empty input", "Add a regression test for it"},
+		bullets: "- Guard the empty input\n- Add a regression test for it",
+	},
+	{
+		name:    "indented lines",
+		body:    "\tGuard the empty input\n    and the nil input.",
+		items:   []string{"Guard the empty input and the nil input."},
+		bullets: "- Guard the empty input and the nil input.",
+	},
+	{
+		name:    "empty",
+		body:    "  \n",
+		items:   nil,
+		bullets: "",
+	},
+}
+
+func TestUnwrapBody(t *testing.T) {
+	for _, tt := range bodyGoldens {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unwrapBody(tt.body); !reflect.DeepEqual(got, tt.items) {
+				t.Errorf("unwrapBody(%q) =\n%q\nwant\n%q", tt.body, got, tt.items)
+			}
+		})
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	for _, tt := range bodyGoldens {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatBody(tt.body); got != tt.bullets {
+				t.Errorf("formatBody(%q) =\n%s\nwant\n%s", tt.body, got, tt.bullets)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// commentSyntax describes how a language marks comments and doc strings
+type commentSyntax struct {
+	line  []string    // Line comment prefixes
+	block [][2]string // Block comment or doc string delimiters
+}
+
+var cStyleComments = commentSyntax{line: []string{"//"}, block: [][2]string{{"/*", "*/"}}}
+var hashComments = commentSyntax{line: []string{"#"}}
+
+// commentSyntaxes maps file extensions to their comment syntax; other files are not classified
+var commentSyntaxes = map[string]commentSyntax{
+	".go":    cStyleComments,
+	".js":    cStyleComments,
+	".jsx":   cStyleComments,
+	".mjs":   cStyleComments,
+	".cjs":   cStyleComments,
+	".ts":    cStyleComments,
+	".tsx":   cStyleComments,
+	".java":  cStyleComments,
+	".kt":    cStyleComments,
+	".scala": cStyleComments,
+	".swift": cStyleComments,
+	".c":     cStyleComments,
+	".h":     cStyleComments,
+	".cc":    cStyleComments,
+	".cpp":   cStyleComments,
+	".hpp":   cStyleComments,
+	".cs":    cStyleComments,
+	".rs":    cStyleComments,
+	".php":   {line: []string{"//", "#"}, block: [][2]string{{"/*", "*/"}}},
+	".py":    {line: []string{"#"}, block: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}},
+	".rb":    {line: []string{"#"}, block: [][2]string{{"=begin", "=end"}}},
+	".sh":    hashComments,
+	".bash":  hashComments,
+	".pl":    hashComments,
+	".sql":   {line: []string{"--"}, block: [][2]string{{"/*", "*/"}}},
+	".lua":   {line: []string{"--"}, block: [][2]string{{"--[[", "]]"}}},
+}
+
+// countCommentLines classifies the changed lines of a file diff as comment or code lines.
+// Blank lines outside comments are not counted. Block comments are followed separately on
+// the old and new side of each hunk; a hunk starting inside a block comment is not detected.
+func countCommentLines(path, diff string) (comments int, code int) {
+	syntax, ok := commentSyntaxes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return 0, 0
+	}
+
+	// Open block comment closer per side: 0 = old file, 1 = new file
+	var closers [2]string
+	inHunk := false
+
+	// classify reports whether a line on one side is part of a comment, and whether it is blank
+	classify := func(side int, text string) (bool, bool) {
+		text = strings.TrimSpace(text)
+		if closers[side] != "" {
+			if strings.Contains(text, closers[side]) {
+				closers[side] = ""
+			}
+			return true, false
+		}
+		if text == "" {
+			return false, true
+		}
+		for _, prefix := range syntax.line {
+			if strings.HasPrefix(text, prefix) {
+				return true, false
+			}
+		}
+		for _, block := range syntax.block {
+			if strings.HasPrefix(text, block[0]) {
+				if !strings.Contains(text[len(block[0]):], block[1]) {
+					closers[side] = block[1]
+				}
+				return true, false
+			}
+		}
+		return false, false
+	}
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			closers = [2]string{}
+			continue
+		}
+		if !inHunk || line == "" {
+			continue
+		}
+
+		var isComment, blank bool
+		switch line[0] {
+		case ' ':
+			// Context lines move both sides forward
+			classify(0, line[1:])
+			classify(1, line[1:])
+			continue
+		case '+':
+			isComment, blank = classify(1, line[1:])
+		case '-':
+			isComment, blank = classify(0, line[1:])
+		default:
+			continue
+		}
+
+		if isComment {
+			comments++
+		} else if !blank {
+			code++
+		}
+	}
+
+	return comments, code
+}
+
+// commentOnlyHint tells the model which files only change comments and doc strings.
+// When every changed file is comment-only, the docs type is suggested for conventional commits.
+func commentOnlyHint(changes string, conventional bool) string {
+	files := ParseDiffByFile(changes)
+	var commentOnly []string
+	for _, fd := range files {
+		if fd.CommentOnly() {
+			commentOnly = append(commentOnly, fd.Path)
+		}
+	}
+
+	if len(commentOnly) == 0 {
+		return ""
+	}
+	if len(commentOnly) == len(files) {
+		hint := "\nEvery changed file only touches comments and doc strings; no code changed."
+		if conventional {
+			hint += " Use the docs type."
+		}
+		return hint
+	}
+	return fmt.Sprintf("\nThese files only change comments and doc strings: %s", strings.Join(commentOnly, ", "))
+}
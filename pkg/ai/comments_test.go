@@ -0,0 +1,136 @@
+package ai
+
+import "testing"
+
+func TestCountCommentLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		diff     string
+		comments int
+		code     int
+	}{
+		{
+			name: "go line comments",
+			path: "parser.go",
+			diff: "@@ -1,3 +1,4 @@\n" +
+				" // Parse reads the input\n" +
+				"-// old wording\n" +
+				"+// Parse reads the input and returns its tokens\n" +
+				"+\n" +
+				" func Parse() {}\n",
+			comments: 2,
+		},
+		{
+			name: "go block comment",
+			path: "parser.go",
+			diff: "@@ -1,2 +1,6 @@\n" +
+				"+/*\n" +
+				"+Package parser reads input.\n" +
+				"+\n" +
+				"+*/\n" +
+				" package parser\n" +
+				"+var x = 1 /* trailing */\n",
+			comments: 4,
+			code:     1,
+		},
+		{
+			name: "go code",
+			path: "parser.go",
+			diff: "@@ -1,2 +1,2 @@\n" +
+				"-func Parse() {}\n" +
+				"+func Parse() error { return nil } // now fails\n",
+			code: 2,
+		},
+		{
+			name: "python comments and docstring",
+			path: "parser.py",
+			diff: "@@ -1,3 +1,7 @@\n" +
+				" def parse(text):\n" +
+				"+    \"\"\"Parse the text.\n" +
+				"+\n" +
+				"+    Returns the tokens.\n" +
+				"+    \"\"\"\n" +
+				"+    # Fast path for empty input\n" +
+				"+    '''single quoted docstring'''\n",
+			comments: 6,
+		},
+		{
+			name: "python code",
+			path: "parser.py",
+			diff: "@@ -1,2 +1,2 @@\n" +
+				"-    return []\n" +
+				"+    return tokens  # the tokens\n",
+			code: 2,
+		},
+		{
+			name: "js comments",
+			path: "src/parser.js",
+			diff: "@@ -1,2 +1,6 @@\n" +
+				"+/**\n" +
+				"+ * Parses the input.\n" +
+				"+ * @param {string} input\n" +
+				"+ */\n" +
+				" export function parse(input) {\n" +
+				"+  // TODO: handle unicode\n",
+			comments: 5,
+		},
+		{
+			name: "js code",
+			path: "src/parser.jsx",
+			diff: "@@ -1,2 +1,2 @@\n" +
+				"-  return null\n" +
+				"+  return <Parser input={input} /> // render\n",
+			code: 2,
+		},
+		{
+			name: "block comment on the removed side only",
+			path: "parser.go",
+			diff: "@@ -1,4 +1,2 @@\n" +
+				"-/* removed\n" +
+				"-   comment */\n" +
+				"+x := 1\n" +
+				"+y := 2\n",
+			comments: 2,
+			code:     2,
+		},
+		{
+			name: "unknown extension",
+			path: "notes.txt",
+			diff: "@@ -1 +1 @@\n+# heading\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments, code := countCommentLines(tt.path, tt.diff)
+			if comments != tt.comments || code != tt.code {
+				t.Errorf("countCommentLines = %d comments, %d code, want %d, %d", comments, code, tt.comments, tt.code)
+			}
+		})
+	}
+}
+
+func TestCommentOnlyHint(t *testing.T) {
+	goComment := "diff --git a/parser.go b/parser.go\n--- a/parser.go\n+++ b/parser.go\n@@ -1 +1 @@\n-// Parse parses\n+// Parse parses the input\n"
+	pyComment := "diff --git a/parser.py b/parser.py\n--- a/parser.py\n+++ b/parser.py\n@@ -1 +1 @@\n-# old\n+# new\n"
+	jsCode := "diff --git a/parser.js b/parser.js\n--- a/parser.js\n+++ b/parser.js\n@@ -1 +1 @@\n-return 1\n+return 2\n"
+
+	tests := []struct {
+		name         string
+		changes      string
+		conventional bool
+		want         string
+	}{
+		{"all comments, conventional", goComment + pyComment, true, "\nEvery changed file only touches comments and doc strings; no code changed. Use the docs type."},
+		{"all comments", goComment + pyComment, false, "\nEvery changed file only touches comments and doc strings; no code changed."},
+		{"some comments", goComment + jsCode + pyComment, true, "\nThese files only change comments and doc strings: parser.go, parser.py"},
+		{"no comments", jsCode, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commentOnlyHint(tt.changes, tt.conventional); got != tt.want {
+				t.Errorf("commentOnlyHint = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
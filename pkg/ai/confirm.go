@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ConfirmChoice is the user's answer to the commit confirmation prompt
+type ConfirmChoice int
+
+const (
+	// ConfirmAccept commits the message as shown
+	ConfirmAccept ConfirmChoice = iota
+	// ConfirmReject cancels the commit
+	ConfirmReject
+	// ConfirmRegenerate asks the provider for a new message
+	ConfirmRegenerate
+	// ConfirmRegenerateBody keeps the subject and asks the provider for a new body
+	ConfirmRegenerateBody
+)
+
+// PrintCommitMessage shows a generated commit message between separators
+func PrintCommitMessage(message string) {
+	fmt.Println("\n\033[1;36m💬 Generated Commit Message\033[0m")
+	fmt.Println("\033[38;5;244m────────────────────────\033[0m")
+	for _, line := range strings.Split(message, "\n") {
+		if line == "" {
+			fmt.Println()
+		} else {
+			fmt.Printf("   %s\n", line)
+		}
+	}
+	fmt.Println("\033[38;5;244m────────────────────────\033[0m")
+}
+
+// confirmAnswer is a line read from the terminal, or the error that ended reading
+type confirmAnswer struct {
+	line string
+	err  error
+}
+
+// readAnswer reads a line from stdin in the background, so a prompt can stop waiting for it.
+// After a timeout the read stays pending until the next line or the end of stdin.
+func readAnswer() <-chan confirmAnswer {
+	answer := make(chan confirmAnswer, 1)
+	go func() {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer <- confirmAnswer{line, err}
+	}()
+	return answer
+}
+
+// AskConfirmation asks whether to use the message shown above. The regenerate options
+// are only offered when canRegenerate is set; an empty answer accepts the message. With a
+// timeout, no answer in time is taken as onTimeout.
+func AskConfirmation(canRegenerate bool, timeout time.Duration, onTimeout ConfirmChoice) (ConfirmChoice, error) {
+	for {
+		fmt.Println("\n\033[1;36m❓ Use this commit message?\033[0m")
+		if canRegenerate {
+			fmt.Print("\033[38;5;244m   [Y] Yes  [N] No  [R] Regenerate  [B] Regenerate body\033[0m\n")
+		} else {
+			fmt.Print("\033[38;5;244m   [Y] Yes  [N] No\033[0m\n")
+		}
+		var expired <-chan time.Time
+		if timeout > 0 {
+			verb := "cancelling"
+			if onTimeout == ConfirmAccept {
+				verb = "accepting"
+			}
+			fmt.Printf("\033[38;5;244m   No answer in %s: %s the commit\033[0m\n", timeout, verb)
+			expired = time.After(timeout)
+		}
+		fmt.Print("\n\033[1;36m> \033[0m")
+
+		var answer confirmAnswer
+		select {
+		case answer = <-readAnswer():
+		case <-expired:
+			fmt.Println("\n\033[1;33m⏱️  No answer, timed out\033[0m")
+			return onTimeout, nil
+		}
+		if answer.err != nil && (answer.err != io.EOF || answer.line == "") {
+			if answer.err == io.EOF {
+				return ConfirmReject, nil
+			}
+			return ConfirmReject, answer.err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer.line)) {
+		case "", "y", "yes":
+			return ConfirmAccept, nil
+		case "n", "no":
+			return ConfirmReject, nil
+		case "r":
+			if canRegenerate {
+				return ConfirmRegenerate, nil
+			}
+		case "b":
+			if canRegenerate {
+				return ConfirmRegenerateBody, nil
+			}
+		}
+		fmt.Println("\033[1;33m⚠️  Unknown choice\033[0m")
+	}
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// fileDiff returns a git diff adding lines to path
+func fileDiff(path string, lines []string) string {
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n@@ -1,1 +1,%d @@\n package ai\n", path, path, path, path, len(lines)+1)
+	for _, line := range lines {
+		diff.WriteString("+" + line + "\n")
+	}
+	return diff.String()
+}
+
+func TestBuildContextFromDiffCapsEachFile(t *testing.T) {
+	var huge []string
+	for i := 0; i < 400; i++ {
+		huge = append(huge, fmt.Sprintf("\thugeValue%03d := computeSomethingExpensive(%d)", i, i))
+	}
+	diff := fileDiff("pkg/ai/huge.go", huge)
+	for i := 0; i < 4; i++ {
+		diff += fileDiff(fmt.Sprintf("pkg/ai/small%d.go", i), []string{fmt.Sprintf("\tsmallFlag%d := true", i)})
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Context.SummarizationEnabled = true
+	cfg.Context.MaxTokensPerFile = 300
+	model := cfg.AI.Model
+
+	// The budget is large enough that the huge file would go in whole without the cap
+	maxTokens := 20000
+	hugeTokens := tokenizer.CountTokens(fileDiff("pkg/ai/huge.go", huge), "gpt-4")
+	if hugeTokens <= cfg.Context.MaxTokensPerFile || hugeTokens >= maxTokens/2 {
+		t.Fatalf("huge file is %d tokens; the test needs it over the %d-token cap and under half the budget", hugeTokens, cfg.Context.MaxTokensPerFile)
+	}
+
+	context, err := BuildContextFromDiff(diff, maxTokens, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(context, "hugeValue399") {
+		t.Errorf("the huge file went in whole despite max_tokens_per_file %d:\n%s", cfg.Context.MaxTokensPerFile, context)
+	}
+	for i := 0; i < 4; i++ {
+		if line := fmt.Sprintf("+\tsmallFlag%d := true", i); !strings.Contains(context, line) {
+			t.Errorf("small file %d is missing its full diff %q:\n%s", i, line, context)
+		}
+	}
+
+	// Everything but the small files' diffs is the huge file's share and the header
+	rest := context
+	for i := 0; i < 4; i++ {
+		small := fileDiff(fmt.Sprintf("pkg/ai/small%d.go", i), []string{fmt.Sprintf("\tsmallFlag%d := true", i)})
+		rest = strings.Replace(rest, small, "", 1)
+	}
+	if tokens := tokenizer.CountTokens(rest, model); tokens > cfg.Context.MaxTokensPerFile+20 {
+		t.Errorf("the huge file takes %d tokens, over the %d-token cap:\n%s", tokens, cfg.Context.MaxTokensPerFile, rest)
+	}
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// ReadContextFile returns the trimmed content of context.context_file, resolved against the
+// repository root unless it is absolute or starts with "~/". A missing, unreadable or empty
+// file gives "", as does running outside a repository with a relative path.
+func ReadContextFile(cfg *config.Config) string {
+	path := cfg.Context.ContextFile
+	if path == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(homeDir, path[2:])
+	} else if !filepath.IsAbs(path) {
+		root, err := git.TopLevel()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(root, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			debugPrint(cfg, "CONTEXT FILE", err.Error())
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// contextFileSection gives the repository's standing context from context.context_file, or
+// returns "" when there is none
+func contextFileSection(cfg *config.Config) string {
+	if cfg.Context.FileContext == "" {
+		return ""
+	}
+	return "\nBackground from the repository's context file (applies to every commit; use it to explain why): " + cfg.Context.FileContext
+}
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// significantChangeShare is the share of all changed lines above which a file the message
+// doesn't mention is flagged
+const significantChangeShare = 0.2
+
+// minCoverageKeyword is the shortest file stem or function name matched against the message,
+// so names like "a" or "io" don't count as mentions
+const minCoverageKeyword = 4
+
+// FileCoverage tells whether a commit message appears to mention a staged file
+type FileCoverage struct {
+	Path        string
+	Mentioned   bool
+	Significant bool // A large share of the change, or the only file
+}
+
+// MessageCoverage matches the message against each file's path, name, name without
+// extension and changed functions. It is a heuristic for spotting messages that ignore
+// part of the change, not a judgement of the message.
+func MessageCoverage(message string, files []string, changes string) []FileCoverage {
+	diffs := make(map[string]FileDiff)
+	total := 0
+	for _, fd := range ParseDiffByFile(changes) {
+		diffs[fd.Path] = fd
+		total += fd.Added + fd.Removed
+	}
+
+	lower := strings.ToLower(message)
+	coverage := make([]FileCoverage, 0, len(files))
+	for _, file := range files {
+		fd := diffs[file]
+		changed := fd.Added + fd.Removed
+		coverage = append(coverage, FileCoverage{
+			Path:        file,
+			Mentioned:   mentionsFile(lower, file, fd.Symbols),
+			Significant: !IsLockfile(file) && (len(files) == 1 || (total > 0 && float64(changed) >= significantChangeShare*float64(total))),
+		})
+	}
+	return coverage
+}
+
+// mentionsFile reports whether the lowercased message names the file or one of its functions
+func mentionsFile(message, path string, symbols []string) bool {
+	base := filepath.Base(path)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	keywords := []string{strings.ToLower(path), strings.ToLower(base)}
+	if len(stem) >= minCoverageKeyword {
+		keywords = append(keywords, strings.ToLower(stem))
+		// "scope_history" is often written "scope history"
+		if words := strings.NewReplacer("_", " ", "-", " ").Replace(stem); words != stem {
+			keywords = append(keywords, strings.ToLower(words))
+		}
+	}
+	for _, symbol := range symbols {
+		if len(symbol) >= minCoverageKeyword {
+			keywords = append(keywords, strings.ToLower(symbol))
+		}
+	}
+
+	for _, keyword := range keywords {
+		if regexp.MustCompile(`(^|[^\w])` + regexp.QuoteMeta(keyword) + `($|[^\w])`).MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayCoverage lists the staged files, marking the ones the message mentions and
+// flagging significant files it doesn't
+func DisplayCoverage(message string, files []string, changes string, limit int) {
+	coverage := MessageCoverage(message, files, changes)
+	if len(coverage) == 0 {
+		return
+	}
+
+	fmt.Println("\n\033[1;36m🧭 Message Coverage\033[0m")
+	fmt.Println("\033[38;5;244m────────────────────────\033[0m")
+
+	mentioned, flagged := 0, 0
+	for i, file := range coverage {
+		if file.Mentioned {
+			mentioned++
+		} else if file.Significant {
+			flagged++
+		}
+		if limit > 0 && i >= limit {
+			continue
+		}
+		switch {
+		case file.Mentioned:
+			fmt.Printf("   \033[1;32m✓\033[0m %s\n", file.Path)
+		case file.Significant:
+			fmt.Printf("   \033[1;33m!\033[0m %s \033[38;5;244m(large change, not mentioned)\033[0m\n", file.Path)
+		default:
+			fmt.Printf("   \033[38;5;244m· %s\033[0m\n", file.Path)
+		}
+	}
+	if limit > 0 && len(coverage) > limit {
+		fmt.Printf("\033[38;5;244m   … %d more files\033[0m\n", len(coverage)-limit)
+	}
+
+	summary := fmt.Sprintf("   %d of %d files mentioned", mentioned, len(coverage))
+	if flagged == 1 {
+		summary += ", 1 large change not mentioned"
+	} else if flagged > 1 {
+		summary += fmt.Sprintf(", %d large changes not mentioned", flagged)
+	}
+	fmt.Printf("\033[38;5;244m%s\033[0m\n", summary)
+}
@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// debugMarker frames each debug entry in text output
+const debugMarker = "==== COMMITRON DEBUG"
+
+// debugSink writes debug entries whole and one at a time, so entries made from concurrent
+// goroutines are never interleaved and appear in the order they were made
+type debugSink struct {
+	mu sync.Mutex
+}
+
+// debugLog is the sink every debugPrint goes through
+var debugLog = &debugSink{}
+
+// debugEntry is one line of --log-format json output
+type debugEntry struct {
+	Time      string `json:"time,omitempty"`
+	Stage     string `json:"stage"`
+	Goroutine int    `json:"goroutine"`
+	Data      string `json:"data"`
+}
+
+// write formats the entry and writes it with a single call while holding the lock. Text goes
+// to stdout as before; JSON lines go to stderr so they don't mix with the message. Without
+// timestamps (--deterministic) the entries are the same on every run.
+func (s *debugSink) write(format, stage, data string, timestamps bool) {
+	now := time.Now()
+	goroutine := goroutineID()
+
+	var out io.Writer = os.Stdout
+	var entry string
+	if format == config.LogFormatJSON {
+		record := debugEntry{Stage: stage, Goroutine: goroutine, Data: data}
+		if timestamps {
+			record.Time = now.Format(time.RFC3339Nano)
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return
+		}
+		out = os.Stderr
+		entry = string(line) + "\n"
+	} else {
+		stamp := ""
+		if timestamps {
+			stamp = now.Format("15:04:05.000") + " "
+		}
+		header := fmt.Sprintf("%s %s[goroutine %d] ====", debugMarker, stamp, goroutine)
+		entry = fmt.Sprintf("\n%s\n%s:\n%s\n%s\n", header, stage, data, strings.Repeat("=", len(header)))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	io.WriteString(out, entry)
+}
+
+// goroutineID returns the id of the calling goroutine from its stack header ("goroutine 18 [running]:")
+func goroutineID() int {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i > 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.Atoi(string(header))
+	return id
+}
+
+// debugPrint prints debug information if debug mode is enabled. It is safe to call from
+// several goroutines at once.
+func debugPrint(cfg *config.Config, message string, data interface{}) {
+	if !cfg.AI.Debug {
+		return
+	}
+
+	// Format the data based on its type
+	var formattedData string
+	switch v := data.(type) {
+	case string:
+		formattedData = v
+	case []byte:
+		formattedData = string(v)
+	default:
+		if data != nil {
+			jsonData, err := json.MarshalIndent(data, "", "  ")
+			if err == nil {
+				formattedData = string(jsonData)
+			} else {
+				formattedData = fmt.Sprintf("%+v", data)
+			}
+		}
+	}
+
+	debugLog.write(cfg.LogFormat, message, formattedData, !cfg.Deterministic)
+}
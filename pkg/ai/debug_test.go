@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// captureOutput redirects *stream (os.Stdout or os.Stderr) while fn runs and returns what was written
+func captureOutput(t *testing.T, stream **os.File, fn func()) string {
+	t.Helper()
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := *stream
+	*stream = write
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(read)
+		output <- string(data)
+	}()
+
+	fn()
+	write.Close()
+	*stream = original
+	return <-output
+}
+
+// writeConcurrently makes perWriter debug entries from each of writers goroutines at once.
+// The data spans several lines so an interleaved write would split an entry.
+func writeConcurrently(cfg *config.Config, writers, perWriter int) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < perWriter; i++ {
+				debugPrint(cfg, "STAGE", fmt.Sprintf("writer %d entry %d\nsecond line\nthird line", w, i))
+			}
+		}(w)
+	}
+	close(start)
+	wg.Wait()
+}
+
+func TestDebugConcurrentJSONWrites(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AI.Debug = true
+	cfg.LogFormat = config.LogFormatJSON
+	const writers, perWriter = 16, 50
+
+	output := captureOutput(t, &os.Stderr, func() { writeConcurrently(cfg, writers, perWriter) })
+
+	next := make(map[int]int) // Next entry expected from each writer
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		var entry debugEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %d isn't one whole JSON entry: %v\n%s", lines, err, scanner.Text())
+		}
+		var writer, index int
+		if _, err := fmt.Sscanf(entry.Data, "writer %d entry %d", &writer, &index); err != nil || entry.Stage != "STAGE" {
+			t.Fatalf("unexpected entry %+v", entry)
+		}
+		if index != next[writer] {
+			t.Errorf("writer %d: entry %d came when %d was expected", writer, index, next[writer])
+		}
+		next[writer] = index + 1
+	}
+	if lines != writers*perWriter {
+		t.Errorf("got %d entries, want %d", lines, writers*perWriter)
+	}
+}
+
+func TestDebugConcurrentTextWrites(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AI.Debug = true
+	cfg.Deterministic = true
+	const writers, perWriter = 16, 50
+
+	output := captureOutput(t, &os.Stdout, func() { writeConcurrently(cfg, writers, perWriter) })
+
+	entries := strings.Split(output, "\n"+debugMarker)[1:]
+	if len(entries) != writers*perWriter {
+		t.Fatalf("got %d entries, want %d", len(entries), writers*perWriter)
+	}
+	for _, entry := range entries {
+		lines := strings.Split(strings.TrimSuffix(entry, "\n"), "\n")
+		if len(lines) != 6 || lines[1] != "STAGE:" || !strings.HasPrefix(lines[2], "writer ") ||
+			lines[3] != "second line" || lines[4] != "third line" || strings.Trim(lines[5], "=") != "" {
+			t.Fatalf("entry was split or mixed with another:\n%s%s", debugMarker, entry)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// maxListedDeletions caps the deleted files named in the prompt
+const maxListedDeletions = 20
+
+// deletedPaths returns the files a diff deletes, in diff order
+func deletedPaths(diff string) []string {
+	var deleted []string
+	for _, fd := range ParseDiffByFile(diff) {
+		if fd.Status == "deleted" {
+			deleted = append(deleted, fd.Path)
+		}
+	}
+	return deleted
+}
+
+// withDeletedFiles records the deleted files for the prompt when context.highlight_deletions
+// is on. They are taken from the full diff, since summarizing or truncating it for the budget
+// is what drops deletions: an all-removed file gets a low priority.
+func withDeletedFiles(cfg *config.Config, deleted []string) *config.Config {
+	if !cfg.Context.HighlightDeletions || len(deleted) == 0 {
+		return cfg
+	}
+	deletionsCfg := *cfg
+	deletionsCfg.Context.DeletedFiles = deleted
+	return &deletionsCfg
+}
+
+// deletionsSection names the deleted files so the message mentions them, or returns ""
+func deletionsSection(cfg *config.Config) string {
+	deleted := cfg.Context.DeletedFiles
+	if len(deleted) == 0 {
+		return ""
+	}
+	list := strings.Join(deleted, ", ")
+	if len(deleted) > maxListedDeletions {
+		list = fmt.Sprintf("%s and %d more", strings.Join(deleted[:maxListedDeletions], ", "), len(deleted)-maxListedDeletions)
+	}
+	return "\nDeleted: " + list + "\nThese files were removed entirely; if the removal is significant, the message MUST mention it.\n"
+}
@@ -0,0 +1,37 @@
+package ai
+
+import "github.com/johnstilia/commitron/pkg/config"
+
+// deterministicSeed is the sampling seed sent with --deterministic to providers that accept one
+const deterministicSeed = 42
+
+// requestTemperature returns the temperature to send, or nil to leave the provider's default.
+// A configured 0 has always meant the default; --deterministic sends an explicit 0.
+func requestTemperature(cfg *config.Config) *float64 {
+	if cfg.Deterministic {
+		zero := 0.0
+		return &zero
+	}
+	if cfg.AI.Temperature == 0 {
+		return nil
+	}
+	temperature := cfg.AI.Temperature
+	return &temperature
+}
+
+// requestSeed returns the seed to send with --deterministic, or nil
+func requestSeed(cfg *config.Config) *int {
+	if !cfg.Deterministic {
+		return nil
+	}
+	seed := deterministicSeed
+	return &seed
+}
+
+// ollamaOptions are the sampling options for Ollama, which only reads them from "options"
+func ollamaOptions(cfg *config.Config) map[string]interface{} {
+	if !cfg.Deterministic {
+		return nil
+	}
+	return map[string]interface{}{"temperature": 0, "seed": deterministicSeed}
+}
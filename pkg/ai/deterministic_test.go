@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// stageDeterminismFixture stages edits, additions, a deletion and a move across several
+// directories, so any map iteration in the context would change the order between runs
+func stageDeterminismFixture(t *testing.T) ([]string, string) {
+	t.Helper()
+	block := "func shared() {\n\treturn computeTheSharedValueWithAFairlyLongName(1, 2, 3)\n}\n"
+	for i := 0; i < 6; i++ {
+		writeFile(t, fmt.Sprintf("pkg/mod%d/mod.go", i), fmt.Sprintf("// Package mod%d is a fixture\npackage mod%d\n", i, i))
+	}
+	writeFile(t, "pkg/old/old.go", "package old\n\n"+block)
+	writeFile(t, "pkg/gone/gone.go", "package gone\n")
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "add fixtures")
+
+	for i := 0; i < 6; i++ {
+		writeFile(t, fmt.Sprintf("pkg/mod%d/mod.go", i), fmt.Sprintf("// Package mod%d is a fixture\npackage mod%d\n\n// Fixes #%d\nfunc Run%d() {}\n", i, i, i+1, i))
+		writeFile(t, fmt.Sprintf("pkg/mod%d/mod_test.go", i), fmt.Sprintf("package mod%d\n\nfunc TestRun%d(t *testing.T) {}\n", i, i))
+	}
+	writeFile(t, "pkg/old/old.go", "package old\n")
+	writeFile(t, "pkg/new/new.go", "package new\n\n"+block)
+	runGit(t, "rm", "-q", "pkg/gone/gone.go")
+	runGit(t, "add", ".")
+
+	files, err := git.GetStagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := git.GetStagedChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files, changes
+}
+
+func TestPromptsAreDeterministic(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+	}{
+		{"conventional", func(cfg *config.Config) { cfg.Commit.Convention = config.ConventionalCommits }},
+		{"angular", func(cfg *config.Config) { cfg.Commit.Convention = config.AngularConvention }},
+		{"none", func(cfg *config.Config) { cfg.Commit.Convention = config.NoConvention }},
+		{"structured none", func(cfg *config.Config) {
+			cfg.Commit.Convention = config.NoConvention
+			cfg.Commit.StructuredNone = true
+		}},
+		{"quick", func(cfg *config.Config) { cfg.Commit.QuickMode = true }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			runGit(t, "checkout", "-q", "-b", "feature/ABC-123-run")
+			files, changes := stageDeterminismFixture(t)
+
+			var requests [][]byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests = append(requests, body)
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"{\"type\":\"feat\",\"scope\":\"mod\",\"subject\":\"add run functions\",\"body\":\"Add Run to each module.\"}"}}]}`)
+			}))
+			defer server.Close()
+
+			cfg := config.DefaultConfig()
+			cfg.AI.APIKey = "test-key"
+			cfg.AI.OpenAIEndpoint = server.URL + "/v1/chat/completions"
+			cfg.AI.Model = "gpt-4o"
+			cfg.UI.EnableTUI = false
+			cfg.Deterministic = true
+			cfg.Commit.IncludeBody = true
+			cfg.Context.IncludeFileStats = true
+			cfg.Context.IncludeFileSummaries = true
+			cfg.Context.DetectMoves = true
+			cfg.Context.UserContext = "split the runner"
+			tt.configure(cfg)
+
+			var messages []string
+			for run := 0; run < 2; run++ {
+				message, err := GenerateCommitMessage(cfg, files, changes)
+				if err != nil {
+					t.Fatal(err)
+				}
+				messages = append(messages, message)
+			}
+			if len(requests) != 2 {
+				t.Fatalf("made %d requests, want one per run", len(requests))
+			}
+			if !bytes.Equal(requests[0], requests[1]) {
+				t.Errorf("the request differs between runs\nfirst:\n%s\nsecond:\n%s", requests[0], requests[1])
+			}
+			if !bytes.Contains(requests[0], []byte(`"seed":42`)) {
+				t.Errorf("the request has no fixed seed:\n%s", requests[0])
+			}
+			if messages[0] != messages[1] {
+				t.Errorf("the message differs between runs: %q and %q", messages[0], messages[1])
+			}
+		})
+	}
+}
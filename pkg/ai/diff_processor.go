@@ -2,9 +2,12 @@ package ai
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/johnstilia/commitron/pkg/config"
 	"github.com/johnstilia/commitron/pkg/tokenizer"
@@ -12,12 +15,23 @@ import (
 
 // FileDiff represents a single file's diff information
 type FileDiff struct {
-	Path    string // File path
-	Status  string // "added", "modified", "deleted", "renamed"
-	Added   int    // Lines added
-	Removed int    // Lines removed
-	Content string // Raw diff content for this file
-	Summary string // Generated summary
+	Path    string   // File path
+	Status  string   // "added", "modified", "deleted", "renamed", "copied"
+	Added   int      // Lines added
+	Removed int      // Lines removed
+	Content string   // Raw diff content for this file
+	Summary string   // Generated summary
+	Moves   []string // Code moved to or from other files (see AnnotateMoves)
+	Symbols []string // Functions and types the hunks are in, from the hunk headers
+	Issues  []string // Issues referenced on added lines (see annotateIssues)
+
+	CommentLines int // Changed lines that are comments or doc strings
+	CodeLines    int // Changed non-blank lines outside comments (both 0 for unknown languages)
+}
+
+// CommentOnly reports whether the file's change only touches comments and doc strings
+func (fd FileDiff) CommentOnly() bool {
+	return fd.CommentLines > 0 && fd.CodeLines == 0
 }
 
 // FileWithPriority represents a file with its priority score and token count
@@ -27,9 +41,213 @@ type FileWithPriority struct {
 	Tokens   int // Token count for this file's diff
 }
 
+// lockfileNames lists generated dependency lockfiles whose diffs carry little information
+var lockfileNames = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"Gemfile.lock":      true,
+	"composer.lock":     true,
+	"poetry.lock":       true,
+}
+
+// IsLockfile reports whether the path is a generated dependency lockfile
+func IsLockfile(path string) bool {
+	return lockfileNames[filepath.Base(path)]
+}
+
+// SummarizeLockfiles replaces each lockfile's diff with a one-line summary.
+// Returns the rewritten diff and the number of lockfiles that were collapsed.
+func SummarizeLockfiles(diff string) (string, int) {
+	files := ParseDiffByFile(diff)
+
+	var result strings.Builder
+	collapsed := 0
+
+	// Preserve anything that appears before the first file diff
+	if idx := strings.Index(diff, "diff --git"); idx > 0 {
+		result.WriteString(diff[:idx])
+	}
+
+	for _, fd := range files {
+		if !IsLockfile(fd.Path) {
+			result.WriteString(fd.Content)
+			continue
+		}
+
+		description := "dependency lockfile updated"
+		if filepath.Base(fd.Path) == "go.sum" {
+			description = "dependency hashes updated"
+		}
+		result.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n%s: %s (+%d/-%d)\n", fd.Path, fd.Path, fd.Path, description, fd.Added, fd.Removed))
+		collapsed++
+	}
+
+	if collapsed == 0 {
+		return diff, 0
+	}
+
+	return result.String(), collapsed
+}
+
+// normalizeNewlines converts CRLF (and stray CR) line endings to LF so diffs of
+// Windows files and responses from Windows-hosted models parse the same way
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+// SanitizeDiff normalizes line endings, replaces invalid UTF-8 (e.g. Latin-1 files) and caps
+// each line at maxLineLength bytes so minified files can't stall token counting or break the request.
+// Returns the sanitized diff and the number of lines that were shortened.
+func SanitizeDiff(diff string, maxLineLength int) (string, int) {
+	diff = strings.ToValidUTF8(normalizeNewlines(diff), "\uFFFD")
+	if maxLineLength <= 0 {
+		return diff, 0
+	}
+
+	lines := strings.Split(diff, "\n")
+	shortened := 0
+	for i, line := range lines {
+		if len(line) <= maxLineLength {
+			continue
+		}
+
+		// Cut on a rune boundary
+		cut := maxLineLength
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		lines[i] = fmt.Sprintf("%s… [line truncated, %d more bytes]", line[:cut], len(line)-cut)
+		shortened++
+	}
+
+	return strings.Join(lines, "\n"), shortened
+}
+
+// diffMetadataPrefixes are git extended header lines that carry no meaning for the model.
+// "new file mode", "deleted file mode" and rename lines are kept as they describe the change.
+var diffMetadataPrefixes = []string{
+	"index ",
+	"old mode ",
+	"new mode ",
+	"similarity index ",
+	"dissimilarity index ",
+}
+
+// StripDiffMetadata removes extended header lines such as "index abc..def 100644"
+// from each file header, keeping "diff --git", hunk headers and changed lines
+func StripDiffMetadata(diff string) string {
+	lines := strings.Split(diff, "\n")
+	result := make([]string, 0, len(lines))
+	inHeader := false
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "diff --git") {
+			inHeader = true
+		} else if strings.HasPrefix(line, "@@") {
+			inHeader = false
+		}
+
+		if inHeader && hasDiffMetadataPrefix(line) {
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// hasDiffMetadataPrefix reports whether a header line is droppable metadata
+func hasDiffMetadataPrefix(line string) bool {
+	for _, prefix := range diffMetadataPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hunkHeader matches "@@ -start[,count] +start[,count] @@"
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// ParsePatch validates a patch (git diff or git format-patch output) and splits it
+// into per-file diffs. Errors name the 1-based line that could not be parsed.
+func ParsePatch(patch string) ([]FileDiff, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	inFile := false
+	oldLeft, newLeft := 0, 0
+	for i, line := range lines {
+		lineNo := i + 1
+
+		// Inside a hunk every line must be context, a change or a "\ No newline" marker
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, " ") || line == "":
+				oldLeft--
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+			case strings.HasPrefix(line, "\\"):
+			default:
+				return nil, fmt.Errorf("line %d: unexpected content inside a hunk: %q", lineNo, line)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("line %d: hunk has more lines than its header declares", lineNo)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inFile = true
+		case strings.HasPrefix(line, "@@"):
+			if !inFile {
+				return nil, fmt.Errorf("line %d: hunk header before any \"diff --git\" line", lineNo)
+			}
+			matches := hunkHeader.FindStringSubmatch(line)
+			if matches == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header: %q", lineNo, line)
+			}
+			oldLeft, newLeft = hunkCount(matches[1]), hunkCount(matches[2])
+		case line == "-- ":
+			// git format-patch signature; nothing after it belongs to the diff
+			inFile = false
+		case inFile && !strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "+++") &&
+			(strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")):
+			return nil, fmt.Errorf("line %d: change line outside of a hunk: %q", lineNo, line)
+		}
+	}
+
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, fmt.Errorf("line %d: patch ends in the middle of a hunk", len(lines))
+	}
+
+	files := ParseDiffByFile(patch)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no \"diff --git\" sections found in patch")
+	}
+	return files, nil
+}
+
+// hunkCount parses the optional line count of a hunk range, which defaults to 1
+func hunkCount(count string) int {
+	if count == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(count)
+	return n
+}
+
 // ParseDiffByFile splits a git diff into per-file chunks
 func ParseDiffByFile(diff string) []FileDiff {
 	var files []FileDiff
+	diff = normalizeNewlines(diff)
 
 	// Split by "diff --git" markers
 	parts := regexp.MustCompile(`(?m)^diff --git`).Split(diff, -1)
@@ -77,6 +295,18 @@ func parseSingleFileDiff(diff string) FileDiff {
 			}
 		}
 
+		// The context after a hunk header names the enclosing function
+		if strings.HasPrefix(line, "@@") {
+			if symbol := hunkSymbol(line); symbol != "" && !containsString(file.Symbols, symbol) {
+				file.Symbols = append(file.Symbols, symbol)
+			}
+		}
+
+		// Collect move annotations
+		if strings.HasPrefix(line, moveMarker) {
+			file.Moves = append(file.Moves, strings.TrimPrefix(line, moveMarker))
+		}
+
 		// Detect file status
 		if strings.HasPrefix(line, "new file mode") {
 			file.Status = "added"
@@ -84,6 +314,8 @@ func parseSingleFileDiff(diff string) FileDiff {
 			file.Status = "deleted"
 		} else if strings.HasPrefix(line, "rename from") {
 			file.Status = "renamed"
+		} else if strings.HasPrefix(line, "copy from") {
+			file.Status = "copied"
 		}
 
 		// Count added/removed lines
@@ -94,6 +326,8 @@ func parseSingleFileDiff(diff string) FileDiff {
 		}
 	}
 
+	file.CommentLines, file.CodeLines = countCommentLines(file.Path, diff)
+
 	return file
 }
 
@@ -111,12 +345,29 @@ func SummarizeFileDiff(fd FileDiff) string {
 		summary.WriteString("(deleted, ")
 	case "renamed":
 		summary.WriteString("(renamed, ")
+	case "copied":
+		summary.WriteString("(copied, ")
 	default:
 		summary.WriteString("(")
 	}
 
 	summary.WriteString(fmt.Sprintf("+%d, -%d)\n", fd.Added, fd.Removed))
 
+	// Moved code is described as such rather than as removals and additions
+	for _, move := range fd.Moves {
+		summary.WriteString(fmt.Sprintf("  Moved: %s\n", move))
+	}
+
+	// Functions the changes are in, as git attributes them in the hunk headers
+	if len(fd.Symbols) > 0 {
+		summary.WriteString(fmt.Sprintf("  Changes in: %s\n", capSymbols(fd.Symbols)))
+	}
+
+	// Issues the added lines point to often explain why the change exists
+	if len(fd.Issues) > 0 {
+		summary.WriteString(fmt.Sprintf("  Referenced issues: %s\n", capIssues(fd.Issues)))
+	}
+
 	// Extract function/class names and key changes
 	funcNames := extractFunctionNames(fd.Content)
 	if len(funcNames) > 0 {
@@ -139,6 +390,22 @@ func SummarizeFileDiff(fd FileDiff) string {
 		}
 	}
 
+	// Comment-only changes point to the docs type; mixed files report the share of comments
+	if fd.CommentOnly() {
+		summary.WriteString("  Comments/doc strings only\n")
+	} else if fd.CommentLines > 0 {
+		summary.WriteString(fmt.Sprintf("  Comments/doc strings: %d%% of changed lines\n", 100*fd.CommentLines/(fd.CommentLines+fd.CodeLines)))
+	}
+
+	// Tests are listed by name as they make good body material
+	addedTests, removedTests := extractTestNames(fd.Content)
+	if len(addedTests) > 0 {
+		summary.WriteString(fmt.Sprintf("  Tests added/changed: %s\n", capTestNames(addedTests)))
+	}
+	if len(removedTests) > 0 {
+		summary.WriteString(fmt.Sprintf("  Tests removed: %s\n", capTestNames(removedTests)))
+	}
+
 	// Add a few key code snippets (max 5 lines of actual changes)
 	keyChanges := extractKeyChanges(fd.Content, 5)
 	if len(keyChanges) > 0 {
@@ -273,8 +540,8 @@ func PrioritizeFiles(files []FileDiff) []FileWithPriority {
 		})
 	}
 
-	// Sort by priority (highest first)
-	sort.Slice(prioritized, func(i, j int) bool {
+	// Sort by priority (highest first); ties keep the diff's order so the context is reproducible
+	sort.SliceStable(prioritized, func(i, j int) bool {
 		return prioritized[i].Priority > prioritized[j].Priority
 	})
 
@@ -325,6 +592,9 @@ func calculateFilePriority(file FileDiff) int {
 	return max(score, 0)
 }
 
+// defaultFileTokenShare limits one file to 1/4 of the budget when context.max_tokens_per_file is unset
+const defaultFileTokenShare = 4
+
 // BuildContextFromDiff intelligently builds context within token limits
 func BuildContextFromDiff(diff string, maxTokens int, cfg *config.Config) (string, error) {
 	model := cfg.Context.TokenizerModel
@@ -333,8 +603,8 @@ func BuildContextFromDiff(diff string, maxTokens int, cfg *config.Config) (strin
 	}
 
 	if !cfg.Context.SummarizationEnabled {
-		// Fallback to simple truncation
-		return tokenizer.TruncateToTokenLimit(diff, maxTokens, model), nil
+		// Fallback to truncation
+		return truncateDiff(cfg, diff, maxTokens, model), nil
 	}
 
 	// Parse and prioritize files
@@ -344,8 +614,15 @@ func BuildContextFromDiff(diff string, maxTokens int, cfg *config.Config) (strin
 		return tokenizer.TruncateToTokenLimit(diff, maxTokens, model), nil
 	}
 
+	annotateIssues(cfg, files)
 	prioritized := PrioritizeFiles(files)
 
+	// No single file may take more than its share, so one huge file can't starve the rest
+	perFileCap := cfg.Context.MaxTokensPerFile
+	if perFileCap <= 0 {
+		perFileCap = maxTokens / defaultFileTokenShare
+	}
+
 	// Allocate token budget
 	var result strings.Builder
 	remainingTokens := maxTokens
@@ -363,8 +640,8 @@ func BuildContextFromDiff(diff string, maxTokens int, cfg *config.Config) (strin
 
 		var fileContent string
 
-		// High priority files: try to include full diff
-		if file.Priority >= 100 && file.Tokens < remainingTokens/2 {
+		// High priority files: try to include full diff, within the per-file cap
+		if file.Priority >= 100 && file.Tokens < remainingTokens/2 && file.Tokens <= perFileCap {
 			fileContent = file.Content
 		} else {
 			// Medium/low priority: use summary
@@ -372,6 +649,12 @@ func BuildContextFromDiff(diff string, maxTokens int, cfg *config.Config) (strin
 		}
 
 		contentTokens := tokenizer.CountTokens(fileContent, model)
+		if contentTokens > perFileCap {
+			// Even the summary is too large (e.g. a regenerated snapshot with many key changes)
+			debugPrint(cfg, "FILE CAPPED", fmt.Sprintf("%s: %d tokens > %d per-file cap", file.Path, contentTokens, perFileCap))
+			fileContent = tokenizer.TruncateToTokenLimit(fileContent, perFileCap, model)
+			contentTokens = tokenizer.CountTokens(fileContent, model)
+		}
 
 		if contentTokens <= remainingTokens {
 			result.WriteString(fileContent)
@@ -411,8 +694,19 @@ func BatchSummarize(diff string, batchTokenSize int, cfg *config.Config) (string
 		return tokenizer.TruncateToTokenLimit(diff, batchTokenSize*3, model), nil
 	}
 
+	annotateIssues(cfg, files)
 	prioritized := PrioritizeFiles(files)
 
+	// Batches condensed by a summary model must fit that model's own limits
+	summaryCfg := summaryStageConfig(cfg)
+	if summaryCfg != nil {
+		summaryLimit := tokenizer.GetProviderTokenLimit(string(summaryCfg.AI.Provider), summaryCfg.AI.Model) / 2
+		if batchTokenSize > summaryLimit {
+			batchTokenSize = summaryLimit
+		}
+		debugPrint(cfg, "SUMMARIZATION STAGE", fmt.Sprintf("Batches condensed by %s/%s (batch size %d tokens)", summaryCfg.AI.Provider, summaryCfg.AI.Model, batchTokenSize))
+	}
+
 	// Group files into batches
 	var batches [][]FileWithPriority
 	var currentBatch []FileWithPriority
@@ -444,17 +738,70 @@ func BatchSummarize(diff string, batchTokenSize int, cfg *config.Config) (string
 
 	for i, batch := range batches {
 		result.WriteString(fmt.Sprintf("--- Batch %d/%d ---\n", i+1, len(batches)))
+
+		var batchSummary strings.Builder
 		for _, file := range batch {
 			summary := SummarizeFileDiff(file.FileDiff)
-			result.WriteString(summary)
-			result.WriteString("\n")
+			batchSummary.WriteString(summary)
+			batchSummary.WriteString("\n")
+		}
+
+		// Condense the batch with the summary model when one is configured
+		if summaryCfg != nil {
+			condensed, err := condenseBatch(summaryCfg, batchSummary.String())
+			if err == nil && condensed != "" {
+				result.WriteString(condensed)
+				result.WriteString("\n\n")
+				continue
+			}
+			if err != nil {
+				debugPrint(cfg, "SUMMARIZATION ERROR", fmt.Sprintf("Batch %d (%s/%s): %s", i+1, summaryCfg.AI.Provider, summaryCfg.AI.Model, err.Error()))
+			}
 		}
+
+		result.WriteString(batchSummary.String())
 		result.WriteString("\n")
 	}
 
 	return result.String(), nil
 }
 
+// summaryStageConfig returns the configuration used to condense batches, or nil
+// when no separate summary model is configured
+func summaryStageConfig(cfg *config.Config) *config.Config {
+	if cfg.AI.SummaryModel == "" && cfg.AI.SummaryProvider == "" {
+		return nil
+	}
+
+	summaryCfg := *cfg
+	if cfg.AI.SummaryProvider != "" {
+		summaryCfg.AI.Provider = cfg.AI.SummaryProvider
+	}
+	if cfg.AI.SummaryModel != "" {
+		summaryCfg.AI.Model = cfg.AI.SummaryModel
+	}
+	summaryCfg.AI.SystemPrompt = "You summarize code changes for another model that writes the commit message. Be factual and brief."
+	summaryCfg.Commit.Convention = config.NoConvention
+	// Summaries aren't commit messages, so the subject length preamble doesn't apply
+	summaryCfg.AI.DisableLengthPreamble = true
+	return &summaryCfg
+}
+
+// condenseBatch asks the summary model to condense one batch of file summaries
+func condenseBatch(summaryCfg *config.Config, batch string) (string, error) {
+	prompt := "Summarize the following file changes in a few short sentences. " +
+		"Mention what was added, removed and changed, and name the affected files or components. " +
+		"Output ONLY the summary, not a commit message.\n\n" + batch
+
+	response, err := callProvider(summaryCfg, prompt)
+	if err != nil {
+		return "", err
+	}
+	debugPrint(summaryCfg, fmt.Sprintf("BATCH SUMMARY (%s/%s)", summaryCfg.AI.Provider, summaryCfg.AI.Model), response)
+
+	return strings.TrimSpace(response), nil
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func TestNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"LF untouched", "a\nb\n", "a\nb\n"},
+		{"CRLF", "a\r\nb\r\n", "a\nb\n"},
+		{"stray CR", "a\rb", "a\nb"},
+		{"mixed", "a\r\nb\nc\rd", "a\nb\nc\nd"},
+		{"blank CRLF lines", "a\r\n\r\nb", "a\n\nb"},
+		{"CR before CRLF", "a\r\r\nb", "a\n\nb"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeNewlines(tt.in); got != tt.want {
+				t.Errorf("normalizeNewlines(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCRLFResponseGivesCleanMessage(t *testing.T) {
+	responses := map[string]string{
+		"JSON with escaped CRLF": "{\"type\":\"fix\",\"scope\":\"io\",\"subject\":\"handle CRLF input\\r\\n\",\"body\":\"Windows files end lines with CRLF.\\r\\n\\r\\nStrip the CR before parsing.\"}",
+		"fenced JSON":            "```json\r\n{\"type\":\"fix\",\"scope\":\"io\",\"subject\":\"handle CRLF input\",\"body\":\"Windows files end lines with CRLF.\\r\\n\\r\\nStrip the CR before parsing.\"}\r\n```\r\n",
+		"plain text":             "fix(io): handle CRLF input\r\n\r\nWindows files end lines with CRLF.\r\n\r\nStrip the CR before parsing.\r\n",
+	}
+	cfg := config.DefaultConfig()
+	cfg.Commit.Convention = config.ConventionalCommits
+	cfg.Commit.IncludeBody = true
+
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			msg, err := ParseCommitMessageJSON(response)
+			if err != nil {
+				t.Fatalf("ParseCommitMessageJSON: %v", err)
+			}
+			formatted := FormatCommitMessage(msg, cfg)
+			if strings.Contains(formatted, "\r") {
+				t.Fatalf("formatted message still has a CR: %q", formatted)
+			}
+			subject, body, _ := strings.Cut(formatted, "\n\n")
+			if subject != "fix(io): handle CRLF input" {
+				t.Errorf("subject = %q, want %q", subject, "fix(io): handle CRLF input")
+			}
+			if want := "- Windows files end lines with CRLF.\n- Strip the CR before parsing."; body != want {
+				t.Errorf("body = %q, want %q", body, want)
+			}
+		})
+	}
+}
+
+func TestCRLFDiffParsesClean(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/io.go b/io.go",
+		"index 1111111..2222222 100644",
+		"--- a/io.go",
+		"+++ b/io.go",
+		"@@ -1,3 +1,4 @@",
+		" package io",
+		"-func Read() {}",
+		"+func Read() error {",
+		"+\treturn nil",
+		"+}",
+		"diff --git a/new.txt b/new.txt",
+		"new file mode 100644",
+		"index 0000000..3333333",
+		"--- /dev/null",
+		"+++ b/new.txt",
+		"@@ -0,0 +1 @@",
+		"+hello",
+		"",
+	}, "\r\n")
+
+	sanitized, _ := SanitizeDiff(diff, 0)
+	if strings.Contains(sanitized, "\r") {
+		t.Errorf("SanitizeDiff left a CR: %q", sanitized)
+	}
+
+	files := ParseDiffByFile(diff)
+	if len(files) != 2 {
+		t.Fatalf("ParseDiffByFile found %d files, want 2", len(files))
+	}
+	want := []struct {
+		path           string
+		added, removed int
+	}{{"io.go", 3, 1}, {"new.txt", 1, 0}}
+	for i, file := range files {
+		if strings.Contains(file.Path+file.Content, "\r") {
+			t.Errorf("file %d still has a CR: path %q content %q", i, file.Path, file.Content)
+		}
+		if file.Path != want[i].path || file.Added != want[i].added || file.Removed != want[i].removed {
+			t.Errorf("file %d = %s +%d -%d, want %s +%d -%d", i, file.Path, file.Added, file.Removed, want[i].path, want[i].added, want[i].removed)
+		}
+	}
+}
+
+func TestSanitizeDiffLatin1(t *testing.T) {
+	// "café naïve" saved as Latin-1, as git shows files without a UTF-8 encoding
+	diff := "diff --git a/notes.txt b/notes.txt\n@@ -1 +1 @@\n-caf\xe9\n+caf\xe9 na\xefve\n"
+
+	sanitized, shortened := SanitizeDiff(diff, 1000)
+	if !utf8.ValidString(sanitized) {
+		t.Fatalf("SanitizeDiff returned invalid UTF-8: %q", sanitized)
+	}
+	if want := "diff --git a/notes.txt b/notes.txt\n@@ -1 +1 @@\n-caf�\n+caf� na�ve\n"; sanitized != want {
+		t.Errorf("SanitizeDiff = %q, want %q", sanitized, want)
+	}
+	if shortened != 0 {
+		t.Errorf("shortened = %d, want 0", shortened)
+	}
+
+	// The run of Latin-1 bytes at the cut becomes one three-byte replacement rune, which must not be split
+	sanitized, shortened = SanitizeDiff("+"+strings.Repeat("a", 8)+"\xe9\xe9\xe9", 10)
+	if !utf8.ValidString(sanitized) || shortened != 1 {
+		t.Errorf("SanitizeDiff = %q, %d, want valid UTF-8 and one shortened line", sanitized, shortened)
+	}
+	if want := "+aaaaaaaa… [line truncated, 3 more bytes]"; sanitized != want {
+		t.Errorf("SanitizeDiff = %q, want %q", sanitized, want)
+	}
+}
+
+func TestSanitizeDiffLongLine(t *testing.T) {
+	const megabyte = 1 << 20
+	tests := []struct {
+		name string
+		line string
+		kept int
+	}{
+		{"ascii", "+" + strings.Repeat("a", megabyte), 1000},
+		{"multibyte", "+" + strings.Repeat("é", megabyte/2), 999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := "diff --git a/app.min.js b/app.min.js\n@@ -0,0 +1 @@\n" + tt.line + "\n"
+
+			sanitized, shortened := SanitizeDiff(diff, 1000)
+			if shortened != 1 {
+				t.Errorf("shortened = %d, want 1", shortened)
+			}
+			if !utf8.ValidString(sanitized) {
+				t.Error("SanitizeDiff split a rune")
+			}
+			lines := strings.Split(sanitized, "\n")
+			if len(lines) != 4 || lines[0] != "diff --git a/app.min.js b/app.min.js" || lines[3] != "" {
+				t.Fatalf("SanitizeDiff changed the other lines: %q", lines[0])
+			}
+			want := tt.line[:tt.kept] + fmt.Sprintf("… [line truncated, %d more bytes]", len(tt.line)-tt.kept)
+			if lines[2] != want {
+				t.Errorf("line = %q…, want %q…", lines[2][tt.kept:], want[tt.kept:])
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// maxDirectoryGroups caps the directories named in the concentration line; the rest are counted
+const maxDirectoryGroups = 8
+
+// rootDirectoryGroup names the group for files at the top of the repository
+const rootDirectoryGroup = "(repository root)"
+
+// directoryGroup totals the staged files under one directory
+type directoryGroup struct {
+	Dir     string
+	Files   int
+	Added   int
+	Removed int
+}
+
+// groupByDirectory groups files by their first depth path components, largest groups first.
+// counts may be nil, in which case the line totals stay zero
+func groupByDirectory(files []string, counts map[string]git.FileChange, depth int) []directoryGroup {
+	index := make(map[string]int)
+	var groups []directoryGroup
+	for _, file := range files {
+		dir := rootDirectoryGroup
+		if parts := strings.Split(file, "/"); len(parts) > 1 {
+			if len(parts)-1 > depth {
+				parts = parts[:depth+1]
+			}
+			dir = strings.Join(parts[:len(parts)-1], "/")
+		}
+
+		i, ok := index[dir]
+		if !ok {
+			i = len(groups)
+			index[dir] = i
+			groups = append(groups, directoryGroup{Dir: dir})
+		}
+		groups[i].Files++
+		groups[i].Added += counts[file].Added
+		groups[i].Removed += counts[file].Removed
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Files != groups[j].Files {
+			return groups[i].Files > groups[j].Files
+		}
+		return groups[i].Added+groups[i].Removed > groups[j].Added+groups[j].Removed
+	})
+	return groups
+}
+
+// directorySummary returns "Changes concentrated in: services/billing (12 files, +340/-120), ..."
+// for the staged files, or "" when grouping is disabled or there is a single file
+func directorySummary(cfg *config.Config, files []string) string {
+	if cfg.Context.DirectoryGroupDepth <= 0 || len(files) < 2 {
+		return ""
+	}
+
+	// Totals are best effort; without them the groups still carry the file counts
+	counts, _ := git.StagedNumstat(cfg.Context.DiffBase)
+	groups := groupByDirectory(files, counts, cfg.Context.DirectoryGroupDepth)
+
+	var parts []string
+	for i, group := range groups {
+		if i == maxDirectoryGroups {
+			parts = append(parts, fmt.Sprintf("%d more directories", len(groups)-i))
+			break
+		}
+		noun := "files"
+		if group.Files == 1 {
+			noun = "file"
+		}
+		detail := fmt.Sprintf("%d %s", group.Files, noun)
+		if group.Added > 0 || group.Removed > 0 {
+			detail += fmt.Sprintf(", +%d/-%d", group.Added, group.Removed)
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", group.Dir, detail))
+	}
+	return "Changes concentrated in: " + strings.Join(parts, ", ")
+}
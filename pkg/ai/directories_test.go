@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+func TestGroupByDirectory(t *testing.T) {
+	files := []string{"README.md", "services/billing/invoice.go", "services/billing/tax.go", "services/auth/login.go", "web/app.js", "go.mod"}
+	counts := map[string]git.FileChange{
+		"services/billing/invoice.go": {Added: 30, Removed: 10},
+		"services/billing/tax.go":     {Added: 5},
+		"services/auth/login.go":      {Added: 1, Removed: 1},
+		"web/app.js":                  {Added: 100, Removed: 50},
+	}
+	tests := []struct {
+		name   string
+		depth  int
+		counts map[string]git.FileChange
+		want   []directoryGroup
+	}{
+		{"depth 1", 1, counts, []directoryGroup{
+			{"services", 3, 36, 11},
+			{rootDirectoryGroup, 2, 0, 0},
+			{"web", 1, 100, 50},
+		}},
+		{"depth 2", 2, counts, []directoryGroup{
+			{"services/billing", 2, 35, 10},
+			{rootDirectoryGroup, 2, 0, 0},
+			{"web", 1, 100, 50},
+			{"services/auth", 1, 1, 1},
+		}},
+		{"without counts", 1, nil, []directoryGroup{
+			{"services", 3, 0, 0},
+			{rootDirectoryGroup, 2, 0, 0},
+			{"web", 1, 0, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupByDirectory(files, tt.counts, tt.depth); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupByDirectory =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirectorySummary(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, "services/billing/invoice.go", "package billing\n")
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "add billing")
+
+	writeFile(t, "services/billing/invoice.go", "package billing\n\nfunc Total() {}\n")
+	writeFile(t, "services/billing/tax.go", "// Rates change yearly\nconst vatRate = 0.2\n\n")
+	writeFile(t, "services/auth/login.go", "package auth\n")
+	writeFile(t, "README.md", "# billing\n")
+	var many []string
+	for i := 0; i < maxDirectoryGroups+2; i++ {
+		many = append(many, fmt.Sprintf("tools/tool%02d/main.go", i))
+		writeFile(t, many[i], fmt.Sprintf("package main // tool %d\n", i))
+	}
+	runGit(t, "add", ".")
+
+	tests := []struct {
+		name  string
+		files []string
+		depth int
+		want  string
+	}{
+		{"depth 1", []string{"services/billing/invoice.go", "services/billing/tax.go", "services/auth/login.go", "README.md"}, 1,
+			"Changes concentrated in: services (3 files, +6/-0), (repository root) (1 file, +1/-1)"},
+		{"depth 2", []string{"services/billing/invoice.go", "services/billing/tax.go", "services/auth/login.go", "README.md"}, 2,
+			"Changes concentrated in: services/billing (2 files, +5/-0), (repository root) (1 file, +1/-1), services/auth (1 file, +1/-0)"},
+		{"more directories than listed", many, 2,
+			"Changes concentrated in: tools/tool00 (1 file, +1/-0), tools/tool01 (1 file, +1/-0), tools/tool02 (1 file, +1/-0), tools/tool03 (1 file, +1/-0), " +
+				"tools/tool04 (1 file, +1/-0), tools/tool05 (1 file, +1/-0), tools/tool06 (1 file, +1/-0), tools/tool07 (1 file, +1/-0), 2 more directories"},
+		{"single file", []string{"README.md"}, 1, ""},
+		{"grouping disabled", []string{"services/billing/tax.go", "README.md"}, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Context.DirectoryGroupDepth = tt.depth
+			if got := directorySummary(cfg, tt.files); got != tt.want {
+				t.Errorf("directorySummary =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// endpointCursor advances once per request so ai.endpoints are used round-robin
+var endpointCursor atomic.Uint32
+
+// providerEndpoints returns the endpoints to try for one request: ai.endpoints rotated
+// so each request starts at the next one, or just the single configured endpoint
+func providerEndpoints(cfg *config.Config, single string) []string {
+	if len(cfg.AI.Endpoints) == 0 {
+		return []string{single}
+	}
+
+	start := int(endpointCursor.Add(1)-1) % len(cfg.AI.Endpoints)
+	endpoints := make([]string, 0, len(cfg.AI.Endpoints))
+	endpoints = append(endpoints, cfg.AI.Endpoints[start:]...)
+	return append(endpoints, cfg.AI.Endpoints[:start]...)
+}
+
+// requestContext returns the context that bounds provider requests, cancelled when
+// the --timeout deadline passes
+func requestContext(cfg *config.Config) context.Context {
+	if cfg.Ctx != nil {
+		return cfg.Ctx
+	}
+	return context.Background()
+}
+
+// postJSON sends a JSON request body to the first reachable endpoint. Connection errors
+// fail over to the next endpoint; HTTP error responses are returned to the caller as-is.
+func postJSON(cfg *config.Config, provider string, endpoints []string, body []byte, headers map[string]string) (*http.Response, error) {
+	client := &http.Client{}
+	var lastErr error
+	for _, endpoint := range endpoints {
+		req, err := http.NewRequestWithContext(requestContext(cfg), "POST", endpoint, bytes.NewBuffer(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		debugPrint(cfg, "ENDPOINT", endpoint)
+		resp, err := client.Do(req)
+		if err == nil {
+			return resp, nil
+		}
+
+		debugPrint(cfg, "ENDPOINT FAILED", fmt.Sprintf("%s: %v", endpoint, err))
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no endpoint configured")
+	}
+	return nil, unreachableError(provider, lastErr)
+}
@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Provider failures that callers can detect with errors.Is
+var (
+	// ErrAuthFailed means the provider rejected the API key
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrTokenLimit means the prompt exceeded the model's context window
+	ErrTokenLimit = errors.New("context window exceeded")
+	// ErrProviderUnavailable means the provider could not be reached or had a server error
+	ErrProviderUnavailable = errors.New("provider unavailable")
+)
+
+// ErrSubjectTooLong is returned when the subject line exceeds max_length and
+// commit.on_length_violation is "retry" or "error"
+var ErrSubjectTooLong = errors.New("subject line too long")
+
+// ErrUnparsedResponse means the provider's response couldn't be parsed into a commit message
+var ErrUnparsedResponse = errors.New("could not parse the response")
+
+// RawResponseError carries the raw response that couldn't be parsed, so it can be shown
+// to the user instead of being committed unseen
+type RawResponseError struct {
+	Response string
+}
+
+func (e *RawResponseError) Error() string {
+	return fmt.Sprintf("%v, the raw response was:\n\n%s", ErrUnparsedResponse, e.Response)
+}
+
+func (e *RawResponseError) Unwrap() error {
+	return ErrUnparsedResponse
+}
+
+// ErrRefused means the model declined to describe the changes, which happens with diffs that
+// trip a provider's safety filters
+var ErrRefused = errors.New("the model refused to write a commit message")
+
+// RefusalError carries the provider's refusal, so it is shown as such instead of becoming the
+// subject of a commit
+type RefusalError struct {
+	Provider string
+	Text     string
+}
+
+func (e *RefusalError) Error() string {
+	return fmt.Sprintf("%v; %s replied:\n\n%s", ErrRefused, e.Provider, e.Text)
+}
+
+func (e *RefusalError) Unwrap() error {
+	return ErrRefused
+}
+
+// refusalPattern matches replies that open by declining, e.g. "I'm sorry, but I can't help with that"
+var refusalPattern = regexp.MustCompile(`(?i)^(?:(?:i'm|i am) sorry|sorry|unfortunately|apologies)?[,.!]?\s*(?:but\s*,?\s*)?i(?:'m| am)?\s*(?:can't|cannot|can not|won't|will not|unable to|not able to)\s+(?:help|assist|comply|provide|fulfill|create|generate|write|complete|do that)\b`)
+
+// isRefusal reports whether a response declines the request instead of answering it: either
+// the text itself or the subject of a JSON answer opens with a refusal
+func isRefusal(response string) bool {
+	normalize := strings.NewReplacer("\u2019", "'", "\u2018", "'").Replace
+	if refusalPattern.MatchString(normalize(strings.TrimSpace(response))) {
+		return true
+	}
+	if extractJSON(response) == "" {
+		return false
+	}
+	msg, err := ParseCommitMessageJSON(response)
+	return err == nil && refusalPattern.MatchString(normalize(strings.TrimSpace(msg.Subject)))
+}
+
+// preamblePattern matches the chatty openings models put before the actual message
+var preamblePattern = regexp.MustCompile(`(?i)^(sure|certainly|of course|okay|ok|absolutely|here(?:'s| is| are))\b|commit message.*:$`)
+
+// hasPreamble reports whether the first line of a response is chatter rather than a subject
+func hasPreamble(response string) bool {
+	first, _, _ := strings.Cut(strings.TrimSpace(response), "\n")
+	return preamblePattern.MatchString(strings.TrimSpace(first))
+}
+
+// tokenLimitMessages are fragments providers use when the prompt is too long
+var tokenLimitMessages = []string{
+	"maximum context length",
+	"context_length_exceeded",
+	"prompt is too long",
+	"too many tokens",
+	"exceeds the maximum number of tokens",
+}
+
+// authMessages are fragments providers use when the API key is missing or invalid
+var authMessages = []string{
+	"incorrect api key",
+	"invalid api key",
+	"invalid_api_key",
+	"invalid x-api-key",
+	"api key not valid",
+	"authentication_error",
+}
+
+// providerError builds the error for a failed API call, wrapping the matching
+// sentinel error based on the HTTP status and the provider's message
+func providerError(provider string, status int, message string) error {
+	lower := strings.ToLower(message)
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || containsAny(lower, authMessages):
+		return fmt.Errorf("%s API error: %s: %w", provider, message, ErrAuthFailed)
+	case containsAny(lower, tokenLimitMessages):
+		return fmt.Errorf("%s API error: %s: %w", provider, message, ErrTokenLimit)
+	case status >= http.StatusInternalServerError:
+		return fmt.Errorf("%s API error (status %d): %s: %w", provider, status, message, ErrProviderUnavailable)
+	default:
+		return fmt.Errorf("%s API error: %s", provider, message)
+	}
+}
+
+// unreachableError wraps a transport failure (DNS, refused connection, timeout)
+func unreachableError(provider string, err error) error {
+	return fmt.Errorf("%s API request failed: %w (%w)", provider, err, ErrProviderUnavailable)
+}
+
+// containsAny reports whether s contains any of the fragments
+func containsAny(s string, fragments []string) bool {
+	for _, fragment := range fragments {
+		if strings.Contains(s, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// maxPromptExamples caps how many of commit.examples are added to the prompt
+const maxPromptExamples = 5
+
+// maxExampleTokens caps the estimated tokens of the examples block; examples past it are dropped
+const maxExampleTokens = 800
+
+// examplesSection lists commit.examples as few-shot examples of the project's style, in the
+// configured order, or returns "" when there are none
+func examplesSection(cfg *config.Config) string {
+	var examples []string
+	for _, example := range cfg.Commit.Examples {
+		if example = strings.TrimSpace(normalizeNewlines(example)); example != "" {
+			examples = append(examples, example)
+		}
+	}
+	if len(examples) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString("\nHere are examples of good commits in this project. Match their tone, format and level of detail, but describe only the changes below:")
+	tokens := tokenizer.EstimateTokens(section.String())
+	for i, example := range examples {
+		entry := fmt.Sprintf("\n\nExample %d:\n%s", i+1, example)
+		if i == maxPromptExamples || tokens+tokenizer.EstimateTokens(entry) > maxExampleTokens {
+			debugPrint(cfg, "EXAMPLES CAPPED", fmt.Sprintf("%d of %d examples left out", len(examples)-i, len(examples)))
+			if i == 0 {
+				return ""
+			}
+			break
+		}
+		tokens += tokenizer.EstimateTokens(entry)
+		section.WriteString(entry)
+	}
+	return section.String()
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+	"github.com/johnstilia/commitron/pkg/ignore"
+)
+
+// ContextIgnoreFile lists, in gitignore syntax, paths whose changes are never sent to the
+// model. It can be committed in any directory of the repository, like .gitignore.
+const ContextIgnoreFile = ".commitronignore"
+
+// ErrAllExcluded is returned when every staged file is excluded from the context
+var ErrAllExcluded = errors.New("every staged file is excluded by context.exclude_paths or " + ContextIgnoreFile)
+
+// contextExclusions combines the repository's .commitronignore files with context.exclude_paths,
+// which come last so a developer can re-include a path with "!"
+func contextExclusions(cfg *config.Config) *ignore.Matcher {
+	exclusions := &ignore.Matcher{}
+	if !cfg.Context.UseProvidedDiff {
+		files, err := git.ReadIgnoreFiles(ContextIgnoreFile)
+		if err != nil {
+			debugPrint(cfg, "IGNORE FILES", fmt.Sprintf("could not list %s files: %v", ContextIgnoreFile, err))
+		}
+		for _, file := range files {
+			exclusions.Add(file.Dir, file.Content)
+		}
+	}
+	exclusions.Add("", strings.Join(cfg.Context.ExcludePaths, "\n"))
+	return exclusions
+}
+
+// excludeFiles returns the files that aren't excluded
+func excludeFiles(exclusions *ignore.Matcher, files []string) []string {
+	if exclusions.Empty() {
+		return files
+	}
+	kept := make([]string, 0, len(files))
+	for _, file := range files {
+		if !exclusions.Match(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// excludeFromDiff drops the sections of excluded files from a diff and returns how many were dropped
+func excludeFromDiff(exclusions *ignore.Matcher, diff string) (string, int) {
+	if exclusions.Empty() {
+		return diff, 0
+	}
+
+	var result strings.Builder
+	dropped := 0
+
+	// Preserve anything that appears before the first file diff
+	if idx := strings.Index(diff, "diff --git"); idx > 0 {
+		result.WriteString(diff[:idx])
+	}
+	for _, fd := range ParseDiffByFile(diff) {
+		if exclusions.Match(fd.Path) {
+			dropped++
+			continue
+		}
+		result.WriteString(fd.Content)
+	}
+
+	if dropped == 0 {
+		return diff, 0
+	}
+	return result.String(), dropped
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		subject  string // Subject of the extracted object, "" when nothing should be extracted
+		body     string // Expected body when it is worth checking
+	}{
+		{"bare object", `{"type":"fix","subject":"handle nil map"}`, "handle nil map", ""},
+		{"surrounding whitespace", "\n\n  {\"type\":\"fix\",\"subject\":\"trim input\"}  \n", "trim input", ""},
+		{"json fence", "```json\n{\"type\":\"feat\",\"subject\":\"add cache\"}\n```", "add cache", ""},
+		{"untagged fence", "```\n{\"type\":\"feat\",\"subject\":\"add cache\"}\n```", "add cache", ""},
+		{"uppercase fence in prose", "Here is the message:\n```JSON\n{\"type\":\"docs\",\"subject\":\"update readme\"}\n```\nLet me know!", "update readme", ""},
+		{"prose around an unfenced object", "Sure, here it is: {\"type\":\"fix\",\"subject\":\"close file\"} Hope that helps.", "close file", ""},
+		{"array of one", `[{"type":"fix","subject":"only one"}]`, "only one", ""},
+		{"array of two takes the first", `[{"type":"fix","subject":"first"},{"type":"feat","subject":"second"}]`, "first", ""},
+		{"fenced array", "```json\n[{\"type\":\"chore\",\"subject\":\"bump deps\"}]\n```", "bump deps", ""},
+		{"braces in the body", `{"type":"fix","subject":"guard map","body":"Use map[string]struct{}{} instead of } stray braces {"}`, "guard map", "Use map[string]struct{}{} instead of } stray braces {"},
+		{"escaped quotes and braces in the subject", `{"type":"fix","subject":"handle \"{\" tokens"}`, `handle "{" tokens`, ""},
+		{"escaped backslash before the closing quote", `{"type":"fix","subject":"fix paths","body":"Trailing C:\\"} and more`, "fix paths", `Trailing C:\`},
+		{"unrelated object first", `Config: {"debug":true} Message: {"type":"fix","subject":"second object"}`, "second object", ""},
+		{"non-commit fence then object", "```json\n{\"files\":3}\n```\n{\"type\":\"test\",\"subject\":\"cover parser\"}", "cover parser", ""},
+		{"code fence then json fence", "```go\nfunc f() { return }\n```\n```json\n{\"type\":\"refactor\",\"subject\":\"split f\"}\n```", "split f", ""},
+		{"nested footers", `{"type":"feat","subject":"add flag","footers":[{"token":"Refs","value":"#12"}]}`, "add flag", ""},
+		{"subject only", `{"subject":"no type given"}`, "no type given", ""},
+		{"multibyte with braces", `{"type":"feat","subject":"日本語 {テスト} を追加","body":"絵文字 🚀 も {保持}"}`, "日本語 {テスト} を追加", "絵文字 🚀 も {保持}"},
+		{"trailing prose with braces", `{"type":"fix","subject":"stop leak"} (I used {braces} here)`, "stop leak", ""},
+		{"no JSON", "fix: handle nil map\n\nGuard the lookup.", "", ""},
+		{"truncated object", `{"type":"fix","subject":"cut off`, "", ""},
+		{"object without commit keys", `{"message":"fix: something"}`, "", ""},
+		{"empty array", "[]", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := extractJSON(tt.response)
+			if tt.subject == "" {
+				if object != "" {
+					t.Fatalf("extractJSON(%q) = %q, want nothing", tt.response, object)
+				}
+				return
+			}
+			var msg CommitMessage
+			if err := json.Unmarshal([]byte(object), &msg); err != nil {
+				t.Fatalf("extractJSON(%q) = %q, which doesn't parse: %v", tt.response, object, err)
+			}
+			if msg.Subject != tt.subject {
+				t.Errorf("subject = %q, want %q", msg.Subject, tt.subject)
+			}
+			if tt.body != "" && msg.Body != tt.body {
+				t.Errorf("body = %q, want %q", msg.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestCommitJSONObject(t *testing.T) {
+	tests := []struct {
+		candidate string
+		want      string
+		ok        bool
+	}{
+		{`{"type":"fix"}`, `{"type":"fix"}`, true},
+		{"  {\"subject\":\"x\"}\n", `{"subject":"x"}`, true},
+		{` [ {"type":"fix"} , {"type":"feat"} ] `, `{"type":"fix"}`, true},
+		{`{"scope":"ai"}`, "", false},
+		{`[]`, "", false},
+		{`["fix: not an object"]`, "", false},
+		{`{"type":`, "", false},
+		{"not json", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := commitJSONObject(tt.candidate)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("commitJSONObject(%q) = %q, %v, want %q, %v", tt.candidate, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExtractJSONThinkBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		subject  string
+	}{
+		{"think then object", "<think>\nThe diff adds a guard for empty input.\n</think>\n{\"type\":\"fix\",\"subject\":\"guard empty input\"}", "guard empty input"},
+		{"draft object inside think", "<think>Draft: {\"type\":\"feat\",\"subject\":\"draft subject\"}. Better as a fix.</think>\n\n{\"type\":\"fix\",\"subject\":\"final subject\"}", "final subject"},
+		{"draft fence inside think", "<think>\n```json\n{\"type\":\"feat\",\"subject\":\"draft subject\"}\n```\n</think>\n```json\n{\"type\":\"fix\",\"subject\":\"final subject\"}\n```", "final subject"},
+		{"uppercase tags", "<THINK>braces { and } in the reasoning</THINK>{\"type\":\"docs\",\"subject\":\"update readme\"}", "update readme"},
+		{"thinking tag", "<thinking>weighing feat against fix</thinking>\n{\"type\":\"feat\",\"subject\":\"add cache\"}", "add cache"},
+		{"two think blocks", "<think>first</think>\n<think>second {\"subject\":\"no\"}</think>\n{\"type\":\"chore\",\"subject\":\"bump deps\"}", "bump deps"},
+		{"think then prose", "<think>Let me look at the diff.</think>\nSure! Here's the message:\n\n{\"type\":\"fix\",\"subject\":\"close file\"}", "close file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaned, err := sanitizeResponse(config.DefaultConfig(), tt.response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(strings.ToLower(cleaned), "think") {
+				t.Errorf("the reasoning survived sanitizing: %q", cleaned)
+			}
+			msg, err := ParseCommitMessageJSON(cleaned)
+			if err != nil {
+				t.Fatalf("ParseCommitMessageJSON(%q): %v", cleaned, err)
+			}
+			if msg.Subject != tt.subject {
+				t.Errorf("subject = %q, want %q", msg.Subject, tt.subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func TestParseCommitMessageJSONFooters(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []Footer
+	}{
+		{"objects", `{"type":"feat","subject":"add flag","footers":[{"token":"Refs","value":"#12"},{"token":"BREAKING CHANGE","value":"the flag is required"}]}`,
+			[]Footer{{"Refs", "#12"}, {"BREAKING CHANGE", "the flag is required"}}},
+		{"strings", `{"type":"fix","subject":"close file","footers":["Refs: #7", " Reviewed-by : Ana <ana@example.com> "]}`,
+			[]Footer{{"Refs", "#7"}, {"Reviewed-by", "Ana <ana@example.com>"}}},
+		{"mixed", `{"type":"fix","subject":"close file","footers":[{"token":"Refs","value":"#7"},"Fixes: #8"]}`,
+			[]Footer{{"Refs", "#7"}, {"Fixes", "#8"}}},
+		{"value with a colon", `{"type":"fix","subject":"close file","footers":["See-also: https://example.com/issues/9"]}`,
+			[]Footer{{"See-also", "https://example.com/issues/9"}}},
+		{"absent", `{"type":"fix","subject":"close file"}`, nil},
+		{"empty", `{"type":"fix","subject":"close file","footers":[]}`, []Footer{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseCommitMessageJSON(tt.response)
+			if err != nil {
+				t.Fatalf("ParseCommitMessageJSON: %v", err)
+			}
+			if !reflect.DeepEqual(msg.Footers, tt.want) {
+				t.Errorf("footers = %#v, want %#v", msg.Footers, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCommitMessageFooters(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         CommitMessage
+		includeBody bool
+		recordModel bool
+		want        string
+	}{
+		{"after the body",
+			CommitMessage{Type: "feat", Subject: "add flag", Body: "Add the flag.", Footers: []Footer{{"Refs", "#12"}}},
+			true, false, "feat: add flag\n\n- Add the flag.\n\nRefs: #12"},
+		{"without a body",
+			CommitMessage{Type: "feat", Subject: "add flag", Body: "Add the flag.", Footers: []Footer{{"Refs", "#12"}}},
+			false, false, "feat: add flag\n\nRefs: #12"},
+		{"breaking change token",
+			CommitMessage{Type: "feat", Subject: "drop v1", Footers: []Footer{{"BREAKING-CHANGE", "v1 is gone"}}},
+			true, false, "feat: drop v1\n\nBREAKING CHANGE: v1 is gone"},
+		{"empty token or value skipped",
+			CommitMessage{Type: "fix", Subject: "close file", Footers: []Footer{{"", "#1"}, {"Refs", "  "}, {" Fixes ", " #2 "}}},
+			true, false, "fix: close file\n\nFixes: #2"},
+		{"CRLF in a value",
+			CommitMessage{Type: "fix", Subject: "close file", Footers: []Footer{{"Refs", "#3\r\n"}}},
+			true, false, "fix: close file\n\nRefs: #3"},
+		{"model trailer joins the footers",
+			CommitMessage{Type: "fix", Subject: "close file", Footers: []Footer{{"Refs", "#4"}}},
+			true, true, "fix: close file\n\nRefs: #4\n" + ModelTrailer + ": openai/gpt-4o"},
+		{"all footers empty",
+			CommitMessage{Type: "fix", Subject: "close file", Footers: []Footer{{"", ""}}},
+			true, false, "fix: close file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Commit.Convention = config.ConventionalCommits
+			cfg.Commit.IncludeBody = tt.includeBody
+			cfg.Commit.RecordModel = tt.recordModel
+			cfg.AI.Provider = config.OpenAI
+			cfg.AI.Model = "gpt-4o"
+			if got := FormatCommitMessage(tt.msg, cfg); got != tt.want {
+				t.Errorf("FormatCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// attributionConfig returns a config whose attribution trailer names the provider and model
+func attributionConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Commit.Convention = config.ConventionalCommits
+	cfg.Commit.IncludeBody = true
+	cfg.Commit.AttributionTrailer = "Generated-by: commitron ({{provider}}/{{model}})"
+	cfg.AI.Provider = config.OpenAI
+	cfg.AI.Model = "gpt-4o"
+	return cfg
+}
+
+func TestFormatCommitMessageAttribution(t *testing.T) {
+	const trailer = "Generated-by: commitron (openai/gpt-4o)"
+	tests := []struct {
+		name        string
+		msg         CommitMessage
+		recordModel bool
+		want        string
+	}{
+		{"without footers",
+			CommitMessage{Type: "fix", Subject: "close file", Body: "Close the file."},
+			false, "fix: close file\n\n- Close the file.\n\n" + trailer},
+		{"after the footers",
+			CommitMessage{Type: "fix", Subject: "close file", Body: "Close the file.", Footers: []Footer{{"Refs", "#4"}}},
+			false, "fix: close file\n\n- Close the file.\n\nRefs: #4\n" + trailer},
+		{"after the model trailer",
+			CommitMessage{Type: "fix", Subject: "close file", Footers: []Footer{{"Refs", "#4"}}},
+			true, "fix: close file\n\nRefs: #4\n" + ModelTrailer + ": openai/gpt-4o\n" + trailer},
+		{"subject only",
+			CommitMessage{Type: "fix", Subject: "close file"},
+			false, "fix: close file\n\n" + trailer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := attributionConfig()
+			cfg.Commit.RecordModel = tt.recordModel
+			if got := FormatCommitMessage(tt.msg, cfg); got != tt.want {
+				t.Errorf("FormatCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureAttribution(t *testing.T) {
+	const trailer = "Generated-by: commitron (openai/gpt-4o)"
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"subject only", "fix: close file", "fix: close file\n\n" + trailer},
+		{"body without footers", "fix: close file\n\n- Close the file.\n", "fix: close file\n\n- Close the file.\n\n" + trailer},
+		{"existing footer block", "fix: close file\n\n- Close the file.\n\nRefs: #4\nReviewed-by: Ana <ana@example.com>", "fix: close file\n\n- Close the file.\n\nRefs: #4\nReviewed-by: Ana <ana@example.com>\n" + trailer},
+		{"footer block without a body", "fix: close file\n\nRefs: #4", "fix: close file\n\nRefs: #4\n" + trailer},
+		{"prose that isn't a footer block", "fix: close file\n\nNote: the file was\nleft open on errors.", "fix: close file\n\nNote: the file was\nleft open on errors.\n\n" + trailer},
+		{"already attributed", "fix: close file\n\nRefs: #4\n" + trailer + "\n", "fix: close file\n\nRefs: #4\n" + trailer},
+		{"CRLF footer block", "fix: close file\r\n\r\nRefs: #4\r\n", "fix: close file\n\nRefs: #4\n" + trailer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EnsureAttribution(tt.message, attributionConfig()); got != tt.want {
+				t.Errorf("EnsureAttribution() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	cfg := attributionConfig()
+	cfg.Commit.AttributionTrailer = ""
+	if got := EnsureAttribution("fix: close file\n", cfg); got != "fix: close file\n" {
+		t.Errorf("EnsureAttribution without a trailer = %q, want the message unchanged", got)
+	}
+}
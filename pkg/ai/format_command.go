@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// RunFormatCommand pipes the message through an external command and returns its output.
+// A failing command or empty output aborts generation.
+func RunFormatCommand(command, message string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var out bytes.Buffer
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("format command %q failed: %w", command, err)
+	}
+
+	formatted := strings.TrimSpace(out.String())
+	if formatted == "" {
+		return "", fmt.Errorf("format command %q produced an empty message", command)
+	}
+
+	return formatted, nil
+}
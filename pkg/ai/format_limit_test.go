@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// randomSubject builds a subject of ASCII, accented, CJK and emoji words, sometimes with runs
+// of spaces and trailing punctuation that the shortening trims
+func randomSubject(r *rand.Rand) string {
+	words := []string{"fix", "handle", "parser", "naïve", "café", "überprüfen", "日本語", "処理を追加", "🚀", "👩‍💻", "emoji", "a", "supercalifragilistic", "x,", "y;", "  "}
+	var subject strings.Builder
+	for n := r.Intn(40); n >= 0; n-- {
+		if subject.Len() > 0 {
+			subject.WriteString(" ")
+		}
+		subject.WriteString(words[r.Intn(len(words))])
+	}
+	return subject.String()
+}
+
+func TestFormatWithinLimitKeepsFirstLineUnderLimit(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	conventions := []config.CommitConvention{config.ConventionalCommits, config.NoConvention, config.CustomConvention}
+	affixes := []string{"", "[WIP] ", "{{date}} ", "🎫 ", " (#42)", " — 日本"}
+
+	for i := 0; i < 5000; i++ {
+		cfg := config.DefaultConfig()
+		cfg.Commit.Convention = conventions[r.Intn(len(conventions))]
+		cfg.Commit.SubjectPrefix = affixes[r.Intn(len(affixes))]
+		cfg.Commit.SubjectSuffix = affixes[r.Intn(len(affixes))]
+		if cfg.Commit.Convention == config.NoConvention && r.Intn(2) == 0 {
+			cfg.Commit.SubjectPackage = []string{"ai", "config", "パッケージ"}[r.Intn(3)]
+		}
+		msg := CommitMessage{Type: "fix", Subject: randomSubject(r), Body: "Explain the change."}
+		if r.Intn(2) == 0 {
+			msg.Scope = []string{"parser", "ü", "核心"}[r.Intn(3)]
+		}
+		maxLength := 10 + r.Intn(110)
+
+		// Everything but the subject stays, so the limit only holds when it leaves room for a
+		// one-rune subject and the "..." marker
+		bare := msg
+		bare.Subject = "x"
+		fixed := utf8.RuneCountInString(firstLine(FormatCommitMessage(bare, cfg))) - 1
+		if maxLength < fixed+4 {
+			continue
+		}
+
+		formatted := formatWithinLimit(msg, cfg, maxLength)
+		if length := utf8.RuneCountInString(firstLine(formatted)); length > maxLength {
+			t.Fatalf("case %d: first line is %d runes, limit %d\nsubject: %q\nprefix %q suffix %q package %q convention %s\nfirst line: %q",
+				i, length, maxLength, msg.Subject, cfg.Commit.SubjectPrefix, cfg.Commit.SubjectSuffix, cfg.Commit.SubjectPackage, cfg.Commit.Convention, firstLine(formatted))
+		}
+		if !utf8.ValidString(formatted) {
+			t.Fatalf("case %d: shortening split a multibyte character: %q", i, formatted)
+		}
+	}
+}
+
+// firstLine returns the text before the first newline
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
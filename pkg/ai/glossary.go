@@ -0,0 +1,38 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// maxGlossaryBytes caps the terminology section (roughly 500 tokens); terms past it are dropped
+const maxGlossaryBytes = 2000
+
+// glossarySection lists commit.glossary as "Project terminology:" for the prompt, sorted by
+// term so the prompt is stable, or returns "" when there is no glossary
+func glossarySection(cfg *config.Config) string {
+	if len(cfg.Commit.Glossary) == 0 {
+		return ""
+	}
+
+	terms := make([]string, 0, len(cfg.Commit.Glossary))
+	for term := range cfg.Commit.Glossary {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	var section strings.Builder
+	section.WriteString("\nProject terminology (use these terms with these meanings; do not expand or reinterpret them):")
+	for i, term := range terms {
+		entry := fmt.Sprintf("\n- %s: %s", term, strings.TrimSpace(cfg.Commit.Glossary[term]))
+		if section.Len()+len(entry) > maxGlossaryBytes {
+			debugPrint(cfg, "GLOSSARY CAPPED", fmt.Sprintf("%d of %d terms left out", len(terms)-i, len(terms)))
+			break
+		}
+		section.WriteString(entry)
+	}
+	return section.String()
+}
@@ -0,0 +1,13 @@
+package ai
+
+import "github.com/johnstilia/commitron/pkg/config"
+
+// intentSection gives the author's own reason for the change from --interactive-body, which
+// the body is written around, or returns "" when there is none
+func intentSection(cfg *config.Config) string {
+	if cfg.Context.Intent == "" {
+		return ""
+	}
+	return "\nWhy the author made this change, in their own words. This is authoritative: the body MUST be built around this reason, " +
+		"phrased well and supported by the diff, never contradicting or leaving it out: " + cfg.Context.Intent
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// maxIssueRefs caps the issues listed per file and in the Refs footer
+const maxIssueRefs = 10
+
+// issuePattern compiles context.issue_pattern, or returns nil when it is empty
+func issuePattern(cfg *config.Config) (*regexp.Regexp, error) {
+	if cfg.Context.IssuePattern == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(cfg.Context.IssuePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context.issue_pattern %q: %w", cfg.Context.IssuePattern, err)
+	}
+	return pattern, nil
+}
+
+// CheckIssuePattern reports an invalid context.issue_pattern with the configuration rather
+// than silently finding no issues
+func CheckIssuePattern(cfg *config.Config) error {
+	_, err := issuePattern(cfg)
+	return err
+}
+
+// issueReferences returns the issues referenced on the added lines of a file's diff, in order
+// of appearance and without duplicates. The pattern's first group is the reference when it
+// has one (so it can require a boundary before "#123"), otherwise the whole match.
+func issueReferences(pattern *regexp.Regexp, diff string) []string {
+	var refs []string
+	for _, line := range strings.Split(diff, "\n") {
+		if !strings.HasPrefix(line, "+") || strings.HasPrefix(line, "+++") {
+			continue
+		}
+		for _, match := range pattern.FindAllStringSubmatch(line[1:], -1) {
+			ref := match[0]
+			if len(match) > 1 && match[1] != "" {
+				ref = match[1]
+			}
+			if ref = strings.TrimSpace(ref); ref != "" && !containsString(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// annotateIssues fills in the issues each file references, for SummarizeFileDiff
+func annotateIssues(cfg *config.Config, files []FileDiff) {
+	pattern, err := issuePattern(cfg)
+	if err != nil || pattern == nil {
+		return
+	}
+	for i := range files {
+		files[i].Issues = issueReferences(pattern, files[i].Content)
+	}
+}
+
+// capIssues joins at most maxIssueRefs references and notes how many were left out
+func capIssues(refs []string) string {
+	if len(refs) <= maxIssueRefs {
+		return strings.Join(refs, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(refs[:maxIssueRefs], ", "), len(refs)-maxIssueRefs)
+}
+
+// issuesSection lists the issues referenced per file for the prompt, or "" when the added
+// lines reference none
+func issuesSection(cfg *config.Config, changes string) string {
+	files := ParseDiffByFile(changes)
+	annotateIssues(cfg, files)
+
+	var section strings.Builder
+	for _, fd := range files {
+		if len(fd.Issues) > 0 {
+			section.WriteString(fmt.Sprintf("* %s: %s\n", fd.Path, capIssues(fd.Issues)))
+		}
+	}
+	if section.Len() == 0 {
+		return ""
+	}
+	return "\nReferenced issues (they may explain why the change was made):\n" + section.String()
+}
+
+// referencedIssues returns the issues referenced anywhere in the added lines, deduplicated
+// and capped at maxIssueRefs, for commit.auto_refs
+func referencedIssues(cfg *config.Config, changes string) []string {
+	pattern, err := issuePattern(cfg)
+	if err != nil || pattern == nil {
+		return nil
+	}
+	refs := issueReferences(pattern, changes)
+	if len(refs) > maxIssueRefs {
+		refs = refs[:maxIssueRefs]
+	}
+	return refs
+}
+
+// addRefsFooter adds a "Refs:" footer for the issues no footer mentions yet
+func addRefsFooter(footers []Footer, refs []string) []Footer {
+	var mentioned []string
+	for _, footer := range footers {
+		mentioned = append(mentioned, strings.FieldsFunc(footer.Value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+
+	var missing []string
+	for _, ref := range refs {
+		if !containsString(mentioned, ref) {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) == 0 {
+		return footers
+	}
+	return append(footers, Footer{Token: "Refs", Value: strings.Join(missing, ", ")})
+}
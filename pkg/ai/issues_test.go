@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func TestBranchTicket(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"feature/ABC-123-login-timeout", "ABC-123"},
+		{"abc-123-lowercase", "ABC-123"},
+		{"bugfix/PROJ2-7", "PROJ2-7"},
+		{"JIRA-1/and/OTHER-2", "JIRA-1"},
+		{"release/1.2-3", ""},
+		{"feature/login-timeout", ""},
+		{"A-1", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := branchTicket(tt.branch); got != tt.want {
+			t.Errorf("branchTicket(%q) = %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+}
+
+// issueDiff references issues in code comments, a changelog and a commit message template
+const issueDiff = `diff --git a/parser.go b/parser.go
+--- a/parser.go
++++ b/parser.go
+@@ -1,3 +1,5 @@
+-// Workaround for #7
++// Fixes #12, see #13 and #12 again
++var color = "#fff" // Not an issue: no digits
+ func Parse() {}
++// Upstream: https://github.com/acme/parser/issues/42
+diff --git a/CHANGELOG.md b/CHANGELOG.md
+--- a/CHANGELOG.md
++++ b/CHANGELOG.md
+@@ -1 +1,3 @@
++- Close the file on errors (#12)
++- Merged https://gitlab.example.com/acme/parser/-/merge_requests/5
+diff --git a/docs/message.txt b/docs/message.txt
+--- a/docs/message.txt
++++ b/docs/message.txt
+@@ -0,0 +1,3 @@
++fix(parser): close the file
++
++Refs: ABC-9, PROJ-10
+`
+
+func TestIssueReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"default", config.DefaultIssuePattern, []string{"#12", "#13", "https://github.com/acme/parser/issues/42", "https://gitlab.example.com/acme/parser/-/merge_requests/5"}},
+		{"jira keys", `\b[A-Z][A-Z0-9]+-\d+\b`, []string{"ABC-9", "PROJ-10"}},
+		{"first group", `(?:Refs|Fixes):? (#\d+|[A-Z]+-\d+)`, []string{"#12", "ABC-9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Context.IssuePattern = tt.pattern
+			pattern, err := issuePattern(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := issueReferences(pattern, issueDiff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issueReferences = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssuesSection(t *testing.T) {
+	cfg := config.DefaultConfig()
+	want := `
+Referenced issues (they may explain why the change was made):
+* parser.go: #12, #13, https://github.com/acme/parser/issues/42
+* CHANGELOG.md: #12, https://gitlab.example.com/acme/parser/-/merge_requests/5
+`
+	if got := issuesSection(cfg, issueDiff); got != want {
+		t.Errorf("issuesSection =\n%s\nwant\n%s", got, want)
+	}
+
+	cfg.Context.IssuePattern = ""
+	if got := issuesSection(cfg, issueDiff); got != "" {
+		t.Errorf("issuesSection with no pattern = %q, want \"\"", got)
+	}
+
+	cfg.Context.IssuePattern = `#(`
+	if err := CheckIssuePattern(cfg); err == nil || !strings.Contains(err.Error(), "context.issue_pattern") {
+		t.Errorf("CheckIssuePattern = %v, want an error naming context.issue_pattern", err)
+	}
+	if got := referencedIssues(cfg, issueDiff); got != nil {
+		t.Errorf("referencedIssues with an invalid pattern = %q, want none", got)
+	}
+}
+
+func TestReferencedIssuesCap(t *testing.T) {
+	var diff strings.Builder
+	diff.WriteString("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1,12 @@\n")
+	for i := 1; i <= maxIssueRefs+2; i++ {
+		diff.WriteString("+// See #" + strings.Repeat("1", i) + "\n")
+	}
+	refs := referencedIssues(config.DefaultConfig(), diff.String())
+	if len(refs) != maxIssueRefs || refs[0] != "#1" {
+		t.Errorf("referencedIssues = %q, want the first %d", refs, maxIssueRefs)
+	}
+	if got := capIssues(append(refs, "#a", "#b")); !strings.HasSuffix(got, " and 2 more") {
+		t.Errorf("capIssues = %q, want a count of the rest", got)
+	}
+}
+
+func TestAddRefsFooter(t *testing.T) {
+	tests := []struct {
+		name    string
+		footers []Footer
+		refs    []string
+		want    []Footer
+	}{
+		{"no footers", nil, []string{"#12", "#13"}, []Footer{{"Refs", "#12, #13"}}},
+		{"already in a footer", []Footer{{"Fixes", "#12"}}, []string{"#12", "#13"}, []Footer{{"Fixes", "#12"}, {"Refs", "#13"}}},
+		{"all mentioned", []Footer{{"Refs", "#12,#13"}}, []string{"#12", "#13"}, []Footer{{"Refs", "#12,#13"}}},
+		{"no refs", []Footer{{"Fixes", "#12"}}, nil, []Footer{{"Fixes", "#12"}}},
+		{"prefix of a mentioned ref", []Footer{{"Refs", "#123"}}, []string{"#12"}, []Footer{{"Refs", "#123"}, {"Refs", "#12"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addRefsFooter(tt.footers, tt.refs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("addRefsFooter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// redirectTransport sends every request to target, so providers with fixed API URLs can be
+// pointed at a test server
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	req.Host = rt.target.Host
+	return rt.next.RoundTrip(req)
+}
+
+// providerServer answers as all four providers and records the raw request body by path
+func providerServer(t *testing.T) (string, map[string][]byte) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests[r.URL.Path] = body
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/chat/completions":
+			io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"feat: add login timeout"}}]}`)
+		case r.URL.Path == "/v1/messages":
+			io.WriteString(w, `{"content":{"type":"text","text":"feat: add login timeout"}}`)
+		case strings.HasPrefix(r.URL.Path, "/v1beta/models/"):
+			io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"feat: add login timeout"}]}}]}`)
+		case r.URL.Path == "/api/generate":
+			io.WriteString(w, `{"model":"llama3","response":"feat: add login timeout","done":true}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := http.DefaultTransport
+	http.DefaultTransport = redirectTransport{target: target, next: original}
+	t.Cleanup(func() { http.DefaultTransport = original })
+	return server.URL, requests
+}
+
+// sentPreamble returns what the provider's request put in front of rest, failing when the
+// text carrying the instructions doesn't end with rest
+func sentPreamble(t *testing.T, provider config.AIProvider, body []byte, rest string) string {
+	t.Helper()
+	var request struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		Contents []struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"contents"`
+		Prompt string `json:"prompt"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("%s: %v in %s", provider, err, body)
+	}
+	var text string
+	switch provider {
+	case config.OpenAI, config.Claude:
+		if len(request.Messages) == 0 {
+			t.Fatalf("%s: no messages in %s", provider, body)
+		}
+		text = request.Messages[0].Content
+	case config.Gemini:
+		if len(request.Contents) == 0 || len(request.Contents[0].Parts) == 0 {
+			t.Fatalf("%s: no contents in %s", provider, body)
+		}
+		text = request.Contents[0].Parts[0].Text
+	case config.Ollama:
+		text = request.Prompt
+	}
+	if !strings.HasSuffix(text, rest) {
+		t.Fatalf("%s: request text does not end with the prompt:\n%s", provider, text)
+	}
+	return strings.TrimSuffix(text, rest)
+}
+
+func TestLengthPreambleSameForAllProviders(t *testing.T) {
+	const prompt = "Write a commit message for these changes:\n\n+func Timeout() {}"
+	providers := []struct {
+		provider config.AIProvider
+		model    string
+		path     string
+	}{
+		{config.OpenAI, "gpt-4o", "/v1/chat/completions"},
+		{config.Claude, "claude-3-5-sonnet-latest", "/v1/messages"},
+		{config.Gemini, "gemini-1.5-pro", "/v1beta/models/gemini-1.5-pro:generateContent"},
+		{config.Ollama, "llama3", "/api/generate"},
+	}
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		want      string
+	}{
+		{
+			name: "conventional with body",
+			configure: func(cfg *config.Config) {
+				cfg.Commit.Convention = config.ConventionalCommits
+				cfg.Commit.IncludeBody = true
+				cfg.Commit.MaxLength = 72
+			},
+			want: "CRITICAL INSTRUCTION: Your commit message subject MUST be under 72 characters total. For conventional commits",
+		},
+		{
+			name: "no convention",
+			configure: func(cfg *config.Config) {
+				cfg.Commit.Convention = config.NoConvention
+				cfg.Commit.MaxLength = 50
+			},
+			want: "CRITICAL INSTRUCTION: Your commit message subject MUST be under 50 characters total. ",
+		},
+		{
+			name: "disabled",
+			configure: func(cfg *config.Config) {
+				cfg.AI.DisableLengthPreamble = true
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, requests := providerServer(t)
+			preambles := make(map[config.AIProvider]string)
+			for _, p := range providers {
+				cfg := config.DefaultConfig()
+				cfg.AI.Provider = p.provider
+				cfg.AI.Model = p.model
+				cfg.AI.APIKey = "test-key"
+				cfg.AI.OpenAIEndpoint = host + "/v1/chat/completions"
+				cfg.AI.OllamaHost = host
+				tt.configure(cfg)
+
+				if _, err := requestCompletion(cfg, prompt); err != nil {
+					t.Fatalf("%s: %v", p.provider, err)
+				}
+				body, ok := requests[p.path]
+				if !ok {
+					t.Fatalf("%s: no request to %s", p.provider, p.path)
+				}
+				// OpenAI carries the preamble in the system message, the others in the prompt
+				rest := prompt
+				if p.provider == config.OpenAI {
+					rest = getSystemPrompt(cfg)
+				}
+				preambles[p.provider] = sentPreamble(t, p.provider, body, rest)
+
+				want := ""
+				if preamble := buildLengthConstraintPreamble(cfg); preamble != "" {
+					want = preamble + "\n\n"
+				}
+				if preambles[p.provider] != want {
+					t.Errorf("%s sent the preamble\n%q\nwant\n%q", p.provider, preambles[p.provider], want)
+				}
+			}
+			for _, p := range providers[1:] {
+				if preambles[p.provider] != preambles[config.OpenAI] {
+					t.Errorf("%s and openai sent different preambles:\n%q\n%q", p.provider, preambles[p.provider], preambles[config.OpenAI])
+				}
+			}
+			if !strings.HasPrefix(preambles[config.OpenAI], tt.want) || (tt.want == "") != (preambles[config.OpenAI] == "") {
+				t.Errorf("preamble = %q, want it to start with %q", preambles[config.OpenAI], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// Limits describes the token budget of the configured provider and model, and how the
+// staged diff would be handled right now. It is computed by the same helpers as
+// GenerateCommitMessage so the numbers can't drift from a real run.
+type Limits struct {
+	Provider       string
+	Model          string
+	ProviderLimit  int    // Built-in safe input limit for the provider and model (60% of it when tokens are estimated)
+	MaxInputTokens int    // context.max_input_tokens (0 = not set)
+	InputBudget    int    // Effective input limit
+	PromptOverhead int    // Reserved for instructions, file info, etc.
+	ResponseTokens int    // Reserved for the response
+	ForChanges     int    // Tokens the diff may use
+	TokenizerModel string // Model whose encoding counts tokens
+	Encoding       string // Encoding name, or the estimate used when there is none
+	DiffTokens     int    // Staged diff after sanitizing and reduction, before any strategy
+	Strategy       string // Strategy that would shrink the diff, "" when it fits
+}
+
+// ComputeLimits reports the token budget and, when files are staged, the size of their diff
+// and the strategy that would be selected. No model is called.
+func ComputeLimits(cfg *config.Config, files []string, changes string) Limits {
+	cfg = generationConfig(cfg)
+	budget := tokenBudgetFor(cfg)
+	model := tokenizerModelFor(cfg)
+
+	limits := Limits{
+		Provider:       string(cfg.AI.Provider),
+		Model:          cfg.AI.Model,
+		ProviderLimit:  budget.ProviderLimit,
+		MaxInputTokens: cfg.Context.MaxInputTokens,
+		InputBudget:    budget.MaxTokens,
+		PromptOverhead: budget.PromptOverhead,
+		ResponseTokens: budget.ResponseTokens,
+		ForChanges:     budget.ForChanges,
+		TokenizerModel: model,
+		Encoding:       tokenizer.Method(model),
+	}
+	if len(files) > 0 {
+		prepared := prepareDiff(cfg, files, changes, func(string, string, string) {})
+		limits.DiffTokens = tokenizer.CountTokens(prepared, model)
+		limits.Strategy = selectStrategy(cfg, limits.DiffTokens, budget.ForChanges)
+	}
+	return limits
+}
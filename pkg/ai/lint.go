@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// conventionalSubject matches "type(scope)!: subject"
+var conventionalSubject = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?!?: (.*)$`)
+
+// CheckMessage validates a finished (e.g. user-edited) message against the configuration.
+// It only reports problems; user-authored text is never truncated or rewritten.
+func CheckMessage(message string, cfg *config.Config) []string {
+	var warnings []string
+
+	subject, rest, _ := strings.Cut(strings.TrimSpace(normalizeNewlines(message)), "\n")
+	body := strings.TrimSpace(rest)
+
+	// A revert quotes the original subject and links it with a footer, so only its length is checked
+	revert := revertFooterPattern.MatchString(body)
+	body = strings.TrimSpace(revertFooterPattern.ReplaceAllString(body, ""))
+
+	if cfg.Commit.MaxLength > 0 && len(subject) > cfg.Commit.MaxLength {
+		warnings = append(warnings, fmt.Sprintf("subject is %d characters, the limit is %d", len(subject), cfg.Commit.MaxLength))
+	}
+	if cfg.Commit.MaxBodyLength > 0 && len(body) > cfg.Commit.MaxBodyLength {
+		warnings = append(warnings, fmt.Sprintf("body is %d characters, the limit is %d", len(body), cfg.Commit.MaxBodyLength))
+	}
+
+	if cfg.Commit.Convention.IsConventional() && !revert {
+		subject = strings.TrimPrefix(subject, expandSubjectTemplate(cfg.Commit.SubjectPrefix))
+		subject = strings.TrimSuffix(subject, expandSubjectTemplate(cfg.Commit.SubjectSuffix))
+		match := conventionalSubject.FindStringSubmatch(subject)
+		if match == nil {
+			warnings = append(warnings, fmt.Sprintf("subject does not follow the %s format \"type(scope): subject\"", cfg.Commit.Convention))
+		} else if err := validateConventionalCommit(CommitMessage{Type: match[1], Scope: match[2], Subject: match[3]}, cfg); err != nil {
+			warnings = append(warnings, err.Error())
+		} else {
+			subject = match[3]
+		}
+	}
+	if limit := cfg.Commit.MaxSubjectWords; limit > 0 && !revert && subjectWordCount(subject) > limit {
+		warnings = append(warnings, fmt.Sprintf("subject has %d words, the limit is %d", subjectWordCount(subject), limit))
+	}
+
+	if structuredNone(cfg) && !revert {
+		warnings = append(warnings, structuredWarnings(subject, rest)...)
+	}
+
+	return warnings
+}
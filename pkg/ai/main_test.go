@@ -0,0 +1,16 @@
+package ai
+
+import (
+	"os"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// TestMain counts tokens from characters so the tests never download tiktoken's encoding files
+func TestMain(m *testing.M) {
+	if err := tokenizer.SetEstimator(tokenizer.EstimatorChars); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// maxOutputTokens returns the max_tokens to request from the configured model: the
+// ai.max_tokens_by_model entry for the model (exact name first, then glob patterns such as
+// "llama3*"), else the entry for the provider, else ai.max_tokens. Values above a known model
+// limit are lowered to it so the provider doesn't reject the request.
+func maxOutputTokens(cfg *config.Config) int {
+	maxTokens := cfg.AI.MaxTokens
+	if value, ok := cfg.AI.MaxTokensByModel[cfg.AI.Model]; ok {
+		maxTokens = value
+	} else if value, ok := matchModelPattern(cfg.AI.MaxTokensByModel, cfg.AI.Model); ok {
+		maxTokens = value
+	} else if value, ok := cfg.AI.MaxTokensByModel[string(cfg.AI.Provider)]; ok {
+		maxTokens = value
+	}
+
+	if limit := tokenizer.GetModelOutputLimit(string(cfg.AI.Provider), cfg.AI.Model); limit > 0 && maxTokens > limit {
+		debugPrint(cfg, "MAX TOKENS LOWERED", fmt.Sprintf("%s allows at most %d output tokens, not %d", cfg.AI.Model, limit, maxTokens))
+		maxTokens = limit
+	}
+	return maxTokens
+}
+
+// matchModelPattern returns the value of the first glob key, in sorted order, matching model
+func matchModelPattern(values map[string]int, model string) (int, bool) {
+	var matched string
+	for pattern := range values {
+		if ok, _ := path.Match(pattern, model); ok && (matched == "" || pattern < matched) {
+			matched = pattern
+		}
+	}
+	if matched == "" {
+		return 0, false
+	}
+	return values[matched], true
+}
@@ -0,0 +1,33 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// mergeSummaryCommits is the number of merged commits listed in a merge body, like
+// git's merge.log default
+const mergeSummaryCommits = 20
+
+// GenerateMergeMessage builds the message for a merge commit locally. The subject is the
+// one git prepared ("Merge branch 'feature' into main") whatever the convention, since
+// merges have a canonical form. With commit.merge_summary and bodies enabled, the body
+// lists the merged commits like `git merge --log`; otherwise there is no body.
+func GenerateMergeMessage(cfg *config.Config, subject string, commits []string) string {
+	if !cfg.Commit.IncludeBody || !cfg.Commit.MergeSummary || len(commits) == 0 {
+		return subject
+	}
+
+	var message strings.Builder
+	message.WriteString(subject + "\n")
+	for i, commit := range commits {
+		if i == mergeSummaryCommits {
+			message.WriteString(fmt.Sprintf("\n- ... and %d more", len(commits)-mergeSummaryCommits))
+			break
+		}
+		message.WriteString("\n- " + commit)
+	}
+	return message.String()
+}
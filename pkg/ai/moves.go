@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// minMoveLines is the smallest block of non-blank lines considered a move
+const minMoveLines = 5
+
+// moveMarker prefixes the annotation lines added to a file's diff header
+const moveMarker = "# moved: "
+
+// changeBlock is a contiguous run of added or removed lines in one file
+type changeBlock struct {
+	path  string
+	lines int
+}
+
+// AnnotateMoves finds blocks of lines removed from one file and added to another
+// with identical content, and records them as moves in both file headers so the
+// model describes them as "moved" rather than "removed" and "added".
+// Returns the annotated diff and the number of moves found.
+func AnnotateMoves(diff string) (string, int) {
+	files := ParseDiffByFile(diff)
+	if len(files) < 2 {
+		return diff, 0
+	}
+
+	removed := make(map[string]changeBlock)
+	added := make(map[string][]changeBlock)
+	for _, fd := range files {
+		for hash, lines := range changedBlocks(fd.Content, "-") {
+			removed[hash] = changeBlock{path: fd.Path, lines: lines}
+		}
+		for hash, lines := range changedBlocks(fd.Content, "+") {
+			added[hash] = append(added[hash], changeBlock{path: fd.Path, lines: lines})
+		}
+	}
+
+	notes := make(map[string][]string)
+	moves := 0
+	for hash, from := range removed {
+		for _, to := range added[hash] {
+			if to.path == from.path {
+				continue
+			}
+			notes[from.path] = append(notes[from.path], fmt.Sprintf("%s%d lines to %s", moveMarker, from.lines, to.path))
+			notes[to.path] = append(notes[to.path], fmt.Sprintf("%s%d lines from %s", moveMarker, to.lines, from.path))
+			moves++
+		}
+	}
+
+	if moves == 0 {
+		return diff, 0
+	}
+
+	var result strings.Builder
+
+	// Preserve anything that appears before the first file diff
+	if idx := strings.Index(diff, "diff --git"); idx > 0 {
+		result.WriteString(diff[:idx])
+	}
+
+	for _, fd := range files {
+		if len(notes[fd.Path]) == 0 {
+			result.WriteString(fd.Content)
+			continue
+		}
+
+		// Insert the notes right after the "diff --git" line, sorted as they were found in map order
+		sort.Strings(notes[fd.Path])
+		header, rest, _ := strings.Cut(fd.Content, "\n")
+		result.WriteString(header + "\n" + strings.Join(notes[fd.Path], "\n") + "\n" + rest)
+	}
+
+	return result.String(), moves
+}
+
+// changedBlocks hashes each contiguous run of lines with the given prefix ("+" or "-"),
+// ignoring indentation and blank lines. Only runs of at least minMoveLines are returned.
+func changedBlocks(content, prefix string) map[string]int {
+	blocks := make(map[string]int)
+	var current []string
+
+	flush := func() {
+		if len(current) >= minMoveLines {
+			sum := sha256.Sum256([]byte(strings.Join(current, "\n")))
+			blocks[fmt.Sprintf("%x", sum)] = len(current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, prefix) || strings.HasPrefix(line, prefix+prefix+prefix) {
+			flush()
+			continue
+		}
+		if text := strings.TrimSpace(line[len(prefix):]); text != "" {
+			current = append(current, text)
+		}
+	}
+	flush()
+
+	return blocks
+}
@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// ollamaDefaultNumCtx is the context Ollama gives many models when no num_ctx is sent; a
+// longer prompt is cut by the server without an error
+const ollamaDefaultNumCtx = 2048
+
+// ollamaNumCtxStep rounds num_ctx up, since Ollama reloads the model whenever it changes
+const ollamaNumCtxStep = 1024
+
+// ollamaPromptReserve is kept free in a small context window for the instructions and file
+// list around the diff
+const ollamaPromptReserve = 2000
+
+// ollamaMinDiffTokens is the smallest diff budget a small context window is cut to
+const ollamaMinDiffTokens = 500
+
+// ollamaShowTimeout bounds the /api/show lookup of the model's context length
+const ollamaShowTimeout = 5 * time.Second
+
+// ollamaWindows caches the context length of each host and model for the run; 0 means unknown
+var ollamaWindows = struct {
+	sync.Mutex
+	sizes map[string]int
+}{sizes: make(map[string]int)}
+
+// ollamaHostFor returns ai.ollama_host or Ollama's default address
+func ollamaHostFor(cfg *config.Config) string {
+	if cfg.AI.OllamaHost == "" {
+		return "http://localhost:11434"
+	}
+	return cfg.AI.OllamaHost
+}
+
+// ollamaContextWindow returns the most tokens the configured Ollama model supports, read from
+// /api/show, or 0 when the provider isn't Ollama or the length can't be read
+func ollamaContextWindow(cfg *config.Config) int {
+	if cfg.AI.Provider != config.Ollama {
+		return 0
+	}
+	host := ollamaHostFor(cfg)
+	key := host + "\x00" + cfg.AI.Model
+
+	ollamaWindows.Lock()
+	defer ollamaWindows.Unlock()
+	if size, ok := ollamaWindows.sizes[key]; ok {
+		return size
+	}
+	size, err := fetchOllamaContextWindow(cfg, host)
+	if err != nil {
+		debugPrint(cfg, "OLLAMA CONTEXT WINDOW", fmt.Sprintf("unknown: %v", err))
+	} else {
+		debugPrint(cfg, "OLLAMA CONTEXT WINDOW", fmt.Sprintf("%s supports %d tokens", cfg.AI.Model, size))
+	}
+	ollamaWindows.sizes[key] = size
+	return size
+}
+
+// fetchOllamaContextWindow asks host for the model's "<architecture>.context_length"
+func fetchOllamaContextWindow(cfg *config.Config, host string) (int, error) {
+	body, err := json.Marshal(map[string]string{"model": cfg.AI.Model})
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(requestContext(cfg), ollamaShowTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(host, "/")+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("/api/show returned %s", resp.Status)
+	}
+
+	var show struct {
+		ModelInfo map[string]interface{} `json:"model_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return 0, err
+	}
+	for key, value := range show.ModelInfo {
+		if length, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") && length > 0 {
+			return int(length), nil
+		}
+	}
+	return 0, fmt.Errorf("/api/show has no context_length for %s", cfg.AI.Model)
+}
+
+// ollamaResponseTokens is the room kept for the response in a window of the given size: the
+// configured max_tokens, but at most a quarter of the window, which is plenty for a message
+func ollamaResponseTokens(cfg *config.Config, window int) int {
+	response := maxOutputTokens(cfg)
+	if window > 0 {
+		response = min(response, window/4)
+	}
+	return response
+}
+
+// ollamaNumCtx sizes num_ctx for a prompt and its response, rounded up to ollamaNumCtxStep and
+// never below Ollama's own default, capped at the model's window when it is known
+func ollamaNumCtx(promptTokens, responseTokens, window int) int {
+	needed := promptTokens + responseTokens
+	numCtx := max((needed+ollamaNumCtxStep-1)/ollamaNumCtxStep*ollamaNumCtxStep, ollamaDefaultNumCtx)
+	if window > 0 {
+		numCtx = min(numCtx, window)
+	}
+	return numCtx
+}
+
+// fitOllamaWindow lowers the diff budget to what the model's context window can hold. Many
+// Ollama models have windows far smaller than the prompt overhead assumed for hosted models.
+func fitOllamaWindow(cfg *config.Config, budget *tokenBudget, window, fixedTokens int) {
+	fits := max(window-ollamaResponseTokens(cfg, window)-ollamaPromptReserve-fixedTokens, ollamaMinDiffTokens)
+	if fits >= budget.ForChanges {
+		return
+	}
+	debugPrint(cfg, "OLLAMA BUDGET", fmt.Sprintf("diff budget lowered from %d to %d tokens for a %d-token context window", budget.ForChanges, fits, window))
+	budget.ForChanges = fits
+}
+
+// fitOllamaPrompt returns the prompt and the num_ctx to send with it. The diff budget is
+// already fitted to the window; a prompt that is still too long is shortened here, with a
+// warning, rather than sent for the server to cut.
+func fitOllamaPrompt(cfg *config.Config, prompt string) (string, int) {
+	window := ollamaContextWindow(cfg)
+	model := tokenizerModelFor(cfg)
+	promptTokens := tokenizer.CountTokens(prompt, model)
+	responseTokens := ollamaResponseTokens(cfg, window)
+	if window > 0 && promptTokens+responseTokens > window {
+		fmt.Fprintf(os.Stderr, "\033[1;33m⚠️  The prompt is %d tokens but %s supports %d including the response; shortening it to fit\033[0m\n",
+			promptTokens, cfg.AI.Model, window)
+		prompt = tokenizer.TruncateToTokenLimit(prompt, window-responseTokens, model)
+		promptTokens = tokenizer.CountTokens(prompt, model)
+	}
+	numCtx := ollamaNumCtx(promptTokens, responseTokens, window)
+	debugPrint(cfg, "OLLAMA NUM_CTX", fmt.Sprintf("%d (prompt %d tokens, response %d, window %d)", numCtx, promptTokens, responseTokens, window))
+	return prompt, numCtx
+}
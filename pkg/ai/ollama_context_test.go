@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// ollamaServer is an Ollama host whose /api/show reports window (0 answers 404) and whose
+// /api/generate records each raw request body
+func ollamaServer(t *testing.T, window int) (*config.Config, *[][]byte) {
+	t.Helper()
+	var requests [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			if window == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"model_info": map[string]interface{}{"general.architecture": "llama", "llama.context_length": window},
+			})
+		case "/api/generate":
+			body, _ := io.ReadAll(r.Body)
+			requests = append(requests, body)
+			io.WriteString(w, `{"model":"llama3","response":"feat: add login timeout","done":true}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.AI.Provider = config.Ollama
+	cfg.AI.Model = "llama3"
+	cfg.AI.OllamaHost = server.URL
+	cfg.AI.MaxTokens = 500
+	return cfg, &requests
+}
+
+func TestOllamaNumCtxInRequest(t *testing.T) {
+	longPrompt := strings.Repeat("+\tword word word word\n", 1250)
+	tests := []struct {
+		name          string
+		window        int
+		prompt        string
+		deterministic bool
+		numCtx        int
+		truncated     bool
+	}{
+		{name: "short prompt uses the default", window: 32768, prompt: "Summarize this change.", numCtx: 2048},
+		{name: "long prompt rounds up", window: 32768, prompt: longPrompt, numCtx: 9216},
+		{name: "unknown window", window: 0, prompt: longPrompt, numCtx: 9216},
+		{name: "capped at the window", window: 4096, prompt: longPrompt, numCtx: 4096, truncated: true},
+		{name: "window below the default", window: 1024, prompt: "Summarize this change.", numCtx: 1024},
+		{name: "deterministic keeps num_ctx", window: 32768, prompt: "Summarize this change.", deterministic: true, numCtx: 2048},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, requests := ollamaServer(t, tt.window)
+			cfg.Deterministic = tt.deterministic
+
+			if _, err := generateWithOllama(cfg, tt.prompt); err != nil {
+				t.Fatal(err)
+			}
+			if len(*requests) != 1 {
+				t.Fatalf("made %d generate requests, want 1", len(*requests))
+			}
+			var sent struct {
+				Prompt  string                 `json:"prompt"`
+				Options map[string]interface{} `json:"options"`
+			}
+			if err := json.Unmarshal((*requests)[0], &sent); err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := sent.Options["num_ctx"].(float64); !ok || int(got) != tt.numCtx {
+				t.Errorf("options.num_ctx = %v, want %d in %s", sent.Options["num_ctx"], tt.numCtx, (*requests)[0][:min(len((*requests)[0]), 200)])
+			}
+			if truncated := !strings.Contains(sent.Prompt, strings.TrimSpace(tt.prompt)); truncated != tt.truncated {
+				t.Errorf("prompt truncated = %v, want %v", truncated, tt.truncated)
+			}
+			if tt.deterministic {
+				if sent.Options["temperature"] != float64(0) || sent.Options["seed"] != float64(42) {
+					t.Errorf("options = %v, want temperature 0 and seed 42 next to num_ctx", sent.Options)
+				}
+			} else if _, ok := sent.Options["seed"]; ok {
+				t.Errorf("options = %v, want no seed outside deterministic mode", sent.Options)
+			}
+		})
+	}
+}
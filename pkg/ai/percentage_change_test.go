@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// numberedLines returns n numbered lines, each ending in a newline
+func numberedLines(n int) string {
+	var lines strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&lines, "line %d of the fixture file\n", i)
+	}
+	return lines.String()
+}
+
+func TestPercentageChange(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, "modify.txt", numberedLines(10))
+	writeFile(t, "delete.txt", "retired = true\nowner = nobody\n")
+	writeFile(t, "old.txt", numberedLines(20))
+	writeFile(t, "grow.txt", numberedLines(2))
+	writeFile(t, "image.bin", "\x00\x01\x02")
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "add fixtures")
+
+	writeFile(t, "modify.txt", strings.Replace(strings.Replace(numberedLines(10), "line 3 ", "LINE 3 ", 1), "line 7 ", "LINE 7 ", 1))
+	runGit(t, "rm", "-q", "delete.txt")
+	writeFile(t, "add.txt", "# Changelog\n\n- First release\n")
+	runGit(t, "mv", "old.txt", "new.txt")
+	writeFile(t, "new.txt", strings.Replace(numberedLines(20), "line 5 ", "LINE 5 ", 1))
+	writeFile(t, "grow.txt", numberedLines(10))
+	writeFile(t, "image.bin", "\x00\x03\x04")
+	runGit(t, "add", ".")
+	// Unstaged edits don't count
+	writeFile(t, "modify.txt", numberedLines(1))
+
+	changes, err := git.StagedNumstat("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		status  string
+		oldPath string
+		want    string
+	}{
+		{"modify.txt", "M", "", "20.0%"},
+		{"delete.txt", "D", "", "100% (deleted)"},
+		{"add.txt", "A", "", "100% (new file)"},
+		{"new.txt", "R", "old.txt", "5.0%"},
+		{"grow.txt", "M", "", ">100% (2 lines before, 10 after)"},
+		{"image.bin", "M", "", ""},
+	}
+	if len(changes) != len(tests) {
+		t.Errorf("StagedNumstat found %d files, want %d: %+v", len(changes), len(tests), changes)
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			change, ok := changes[tt.path]
+			if !ok {
+				t.Fatalf("StagedNumstat has no %s: %+v", tt.path, changes)
+			}
+			if change.Status != tt.status || change.OldPath != tt.oldPath {
+				t.Errorf("change = %+v, want status %s from %q", change, tt.status, tt.oldPath)
+			}
+			if got := percentageChange(change, tt.path, ""); got != tt.want {
+				t.Errorf("percentageChange = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Against an older base, as with --amend, the rename's source must exist there too
+	runGit(t, "commit", "-q", "-m", "change fixtures")
+	writeFile(t, "modify.txt", numberedLines(10))
+	runGit(t, "add", "modify.txt")
+	changes, err = git.StagedNumstat("HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := percentageChange(changes["new.txt"], "new.txt", "HEAD~1"); got != "5.0%" {
+		t.Errorf("percentageChange against HEAD~1 for the rename = %q, want 5.0%%", got)
+	}
+	if change, ok := changes["modify.txt"]; ok {
+		t.Errorf("modify.txt matches HEAD~1 but is listed: %+v", change)
+	}
+}
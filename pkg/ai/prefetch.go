@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// prefetchedContext is the staged diff processed ahead of GenerateCommitMessage
+type prefetchedContext struct {
+	done    chan struct{}
+	files   []string // Staged files after exclusions, as GenerateCommitMessage sees them
+	changes string   // The staged diff the context was built from
+	config  string   // contextFingerprint of the config the context was built with
+	result  diffContext
+	ok      bool // False when the staged diff couldn't be read
+}
+
+var (
+	prefetchMu sync.Mutex
+	prefetched *prefetchedContext
+)
+
+// PrefetchContext reads and processes the staged diff in the background, so the work overlaps
+// with a prompt the user is answering. GenerateCommitMessage uses the result only when it is
+// called with exactly the files and diff that were prefetched; if staging changed meanwhile the
+// prefetched context is discarded and built again.
+func PrefetchContext(cfg *config.Config) {
+	// Condensing batches with summary_model calls the provider; only spend that when it's needed
+	if cfg.AI.SummaryModel != "" {
+		return
+	}
+
+	prefetchCfg := *cfg // The caller may keep adjusting its config while this runs
+	p := &prefetchedContext{done: make(chan struct{})}
+	prefetchMu.Lock()
+	prefetched = p
+	prefetchMu.Unlock()
+
+	go func() {
+		defer close(p.done)
+		stopGit := cfg.Timings.Track("git")
+		files, err := git.GetStagedFiles()
+		if err != nil {
+			stopGit()
+			return
+		}
+		changes, err := git.GetStagedChanges()
+		stopGit()
+		if err != nil {
+			return
+		}
+
+		p.files = excludeFiles(contextExclusions(&prefetchCfg), files)
+		p.changes = changes
+		if len(p.files) == 0 {
+			return
+		}
+		// Derived the same way GenerateCommitMessage derives its config, so the fingerprints match
+		generationCfg := generationConfig(withSubjectPackage(&prefetchCfg, p.files))
+		p.config = contextFingerprint(generationCfg)
+		p.result = processDiff(generationCfg, p.files, changes, false)
+		p.ok = true
+	}()
+}
+
+// contextFingerprint describes the settings processDiff depends on: the context and budget
+// settings and what generationConfig derives from the commit settings. Text that only goes
+// into the prompt, like --context, is left out.
+func contextFingerprint(cfg *config.Config) string {
+	settings := cfg.Context
+	settings.UserContext = ""
+	settings.Intent = ""
+	settings.SquashedSubjects = nil
+	settings.DeletedFiles = nil
+	return fmt.Sprintf("%+v|%s|%s|%d|%v|%s|%s|%s|%v|%d", settings,
+		cfg.AI.Provider, cfg.AI.Model, cfg.AI.MaxTokens, cfg.AI.MaxTokensByModel, cfg.AI.SummaryModel,
+		cfg.Commit.SubjectPrefix, cfg.Commit.SubjectSuffix, cfg.Commit.QuickMode, cfg.Commit.MaxLength)
+}
+
+// takePrefetched waits for a prefetch started by PrefetchContext and returns its context if it
+// was built from files and changes with the same settings as cfg. Either way the prefetch is
+// used up.
+func takePrefetched(cfg *config.Config, files []string, changes string) (diffContext, bool) {
+	prefetchMu.Lock()
+	p := prefetched
+	prefetched = nil
+	prefetchMu.Unlock()
+	if p == nil {
+		return diffContext{}, false
+	}
+
+	<-p.done
+	if !p.ok || p.changes != changes || !slices.Equal(p.files, files) {
+		debugPrint(cfg, "PREFETCH DISCARDED", "The staged changes differ from the prefetched ones, building the context again")
+		return diffContext{}, false
+	}
+	if p.config != contextFingerprint(cfg) {
+		debugPrint(cfg, "PREFETCH DISCARDED", "The settings changed since the context was prefetched, building it again")
+		return diffContext{}, false
+	}
+	debugPrint(cfg, "PREFETCH USED", "Context was built while waiting for input")
+	return p.result, true
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// stagedForGeneration reads the staged files and diff the way the generate command does
+func stagedForGeneration(t *testing.T) ([]string, string) {
+	t.Helper()
+	files, err := git.GetStagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := git.GetStagedChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files, changes
+}
+
+// waitForPrefetch blocks until the background prefetch has read the index and finished
+func waitForPrefetch() {
+	prefetchMu.Lock()
+	p := prefetched
+	prefetchMu.Unlock()
+	if p != nil {
+		<-p.done
+	}
+}
+
+func TestPrefetchUsedWhenNothingChanged(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, "a.go", "package a\n")
+	runGit(t, "add", "a.go")
+
+	cfg := config.DefaultConfig()
+	PrefetchContext(cfg)
+	files, changes := stagedForGeneration(t)
+	if _, ok := takePrefetched(generationConfig(cfg), files, changes); !ok {
+		t.Error("prefetch discarded although staging and settings are unchanged")
+	}
+}
+
+func TestPrefetchDiscardedWhenStagingChanges(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, "a.go", "package a\n")
+	runGit(t, "add", "a.go")
+
+	cfg := config.DefaultConfig()
+	PrefetchContext(cfg)
+	waitForPrefetch()
+	writeFile(t, "b.go", "package b\n")
+	runGit(t, "add", "b.go")
+
+	files, changes := stagedForGeneration(t)
+	if _, ok := takePrefetched(generationConfig(cfg), files, changes); ok {
+		t.Error("prefetch used although another file was staged")
+	}
+}
+
+func TestPrefetchDiscardedWhenSettingsChange(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, "a.go", "package a\n")
+	runGit(t, "add", "a.go")
+
+	cfg := config.DefaultConfig()
+	PrefetchContext(cfg)
+	changed := *cfg
+	changed.Context.MaxInputTokens = 2000
+
+	files, changes := stagedForGeneration(t)
+	if _, ok := takePrefetched(generationConfig(&changed), files, changes); ok {
+		t.Error("prefetch used although context.max_input_tokens changed")
+	}
+}
+
+func TestPrefetchKeptWhenOnlyPromptTextChanges(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, "a.go", "package a\n")
+	runGit(t, "add", "a.go")
+
+	cfg := config.DefaultConfig()
+	PrefetchContext(cfg)
+	changed := *cfg
+	changed.Context.Intent = "make the parser faster"
+
+	files, changes := stagedForGeneration(t)
+	if _, ok := takePrefetched(generationConfig(&changed), files, changes); !ok {
+		t.Error("prefetch discarded for a change that only affects the prompt")
+	}
+}
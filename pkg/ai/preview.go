@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxPreviewLineWidth keeps long or minified lines from flooding the preview
+const maxPreviewLineWidth = 160
+
+// DisplayDiffPreview prints the first maxLines lines of a diff with +/- coloring so the
+// message can be checked against what was actually staged
+func DisplayDiffPreview(changes string, maxLines int) {
+	lines := strings.Split(strings.TrimRight(changes, "\n"), "\n")
+	if len(lines) == 0 || (len(lines) == 1 && lines[0] == "") {
+		return
+	}
+
+	fmt.Println("\n\033[1;36m🔎 Staged Diff Preview\033[0m")
+	fmt.Println("\033[38;5;244m────────────────────────\033[0m")
+
+	shown := lines
+	if maxLines > 0 && len(lines) > maxLines {
+		shown = lines[:maxLines]
+	}
+	for _, line := range shown {
+		if runes := []rune(line); len(runes) > maxPreviewLineWidth {
+			line = string(runes[:maxPreviewLineWidth]) + "…"
+		}
+		fmt.Printf("   %s\n", colorizeDiffLine(line))
+	}
+	if len(shown) < len(lines) {
+		fmt.Printf("\033[38;5;244m   … %d more lines\033[0m\n", len(lines)-len(shown))
+	}
+}
+
+// colorizeDiffLine colors a single diff line the way git diff --color does
+func colorizeDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "diff --git"), strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return "\033[1m" + line + "\033[0m"
+	case strings.HasPrefix(line, "@@"):
+		return "\033[36m" + line + "\033[0m"
+	case strings.HasPrefix(line, "+"):
+		return "\033[32m" + line + "\033[0m"
+	case strings.HasPrefix(line, "-"):
+		return "\033[31m" + line + "\033[0m"
+	case strings.HasPrefix(line, "index "), strings.HasPrefix(line, "new file"), strings.HasPrefix(line, "deleted file"):
+		return "\033[38;5;244m" + line + "\033[0m"
+	}
+	return line
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// PromptData is what ai.prompt_template_file and ai.system_prompt_file are rendered with
+type PromptData struct {
+	Files         []string // Staged file paths
+	Diff          string   // The processed diff, as the built-in prompt would include it
+	Convention    string   // commit.convention
+	Types         []string // Allowed commit types for the convention
+	MaxLength     int      // commit.max_length
+	MaxBodyLength int      // commit.max_body_length
+	IncludeBody   bool     // commit.include_body
+	Context       string   // Text passed with --context
+	ContextFile   string   // Content of context.context_file
+	Intent        string   // The author's answer to the --interactive-body question
+	Branch        string   // Current branch ("" on a detached HEAD)
+	Ticket        string   // Issue key from the branch name, e.g. ABC-123
+}
+
+// newPromptData collects the template variables for the staged files and processed diff
+func newPromptData(cfg *config.Config, files []string, changes string) PromptData {
+	branch, _ := git.CurrentBranch()
+	return PromptData{
+		Files:         files,
+		Diff:          changes,
+		Convention:    string(cfg.Commit.Convention),
+		Types:         cfg.Commit.Convention.CommitTypes(),
+		MaxLength:     cfg.Commit.MaxLength,
+		MaxBodyLength: cfg.Commit.MaxBodyLength,
+		IncludeBody:   cfg.Commit.IncludeBody,
+		Context:       cfg.Context.UserContext,
+		ContextFile:   cfg.Context.FileContext,
+		Intent:        cfg.Context.Intent,
+		Branch:        branch,
+		Ticket:        branchTicket(branch),
+	}
+}
+
+// promptTemplateError is a prompt template that failed to execute, located by file and line
+type promptTemplateError struct {
+	Name   string // The template file
+	Line   int
+	Reason string // The failing action and why, e.g. "<.Nope>: can't evaluate field Nope in type ai.PromptData"
+	Err    error  // The error from text/template
+}
+
+func (e *promptTemplateError) Error() string {
+	return fmt.Sprintf("%s, line %d: %s", e.Name, e.Line, e.Reason)
+}
+
+func (e *promptTemplateError) Unwrap() error {
+	return e.Err
+}
+
+// locateTemplateError adds the file and line to an execution error, which text/template only
+// puts in its message as "template: name:line:col: executing "name" at <action>: reason"
+func locateTemplateError(err error) error {
+	var execErr template.ExecError
+	if !errors.As(err, &execErr) {
+		return err
+	}
+	name := regexp.QuoteMeta(execErr.Name)
+	location := regexp.MustCompile(`(?s)^template: ` + name + `:(\d+):\d+: executing "` + name + `" at (.*)$`)
+	match := location.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(match[1])
+	return &promptTemplateError{Name: execErr.Name, Line: line, Reason: match[2], Err: err}
+}
+
+// renderPromptTemplate executes a user prompt template with data
+func renderPromptTemplate(tmpl *template.Template, data PromptData) (string, error) {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", locateTemplateError(err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// customPrompt renders ai.prompt_template_file, or returns "" to use the built-in prompt
+func customPrompt(cfg *config.Config, files []string, changes string) string {
+	if cfg.AI.PromptTemplate == nil {
+		return ""
+	}
+	prompt, err := renderPromptTemplate(cfg.AI.PromptTemplate, newPromptData(cfg, files, changes))
+	if err != nil {
+		debugPrint(cfg, "PROMPT TEMPLATE FAILED", err.Error())
+		return ""
+	}
+	return prompt
+}
+
+// CheckPromptTemplates renders the configured prompt templates with sample data, so references
+// to variables that don't exist are reported with the configuration rather than mid-generation.
+// Errors name the configuration key, the template file and the failing line.
+func CheckPromptTemplates(cfg *config.Config) error {
+	sample := PromptData{
+		Files:       selfTestFiles,
+		Diff:        selfTestDiff,
+		Convention:  string(cfg.Commit.Convention),
+		Types:       cfg.Commit.Convention.CommitTypes(),
+		MaxLength:   cfg.Commit.MaxLength,
+		IncludeBody: cfg.Commit.IncludeBody,
+		Branch:      "feature/ABC-123-sample",
+		Ticket:      "ABC-123",
+	}
+	templates := []struct {
+		key  string
+		tmpl *template.Template
+	}{
+		{"ai.prompt_template_file", cfg.AI.PromptTemplate},
+		{"ai.system_prompt_file", cfg.AI.SystemPromptTemplate},
+	}
+	for _, t := range templates {
+		if t.tmpl == nil {
+			continue
+		}
+		if _, err := renderPromptTemplate(t.tmpl, sample); err != nil {
+			return fmt.Errorf("%s: %w", t.key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package ai
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// promptTemplateConfig writes the prompt and system prompt templates, if not empty, and
+// loads them as loadConfig does
+func promptTemplateConfig(t *testing.T, prompt, system string) (*config.Config, error) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	dir := t.TempDir()
+	if prompt != "" {
+		cfg.AI.PromptTemplateFile = filepath.Join(dir, "prompt.tmpl")
+		writeFile(t, cfg.AI.PromptTemplateFile, prompt)
+	}
+	if system != "" {
+		cfg.AI.SystemPromptFile = filepath.Join(dir, "system.tmpl")
+		writeFile(t, cfg.AI.SystemPromptFile, system)
+	}
+	return cfg, cfg.LoadPromptTemplates()
+}
+
+func TestPromptTemplateRendering(t *testing.T) {
+	newTestRepo(t)
+	runGit(t, "checkout", "-q", "-b", "feature/ABC-123-login-timeout")
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"files and diff",
+			"Files: {{join .Files \", \"}}\n{{.Diff}}\n",
+			"Files: auth/login.go, auth/login_test.go\n+timeout := 30"},
+		{"convention",
+			"{{.Convention}} commits, types {{join .Types \"|\"}}, subject under {{.MaxLength}}{{if .IncludeBody}}, body under {{.MaxBodyLength}}{{end}}",
+			"conventional commits, types feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert, subject under 72, body under 500"},
+		{"context",
+			"Context: {{.Context}}\nNotes: {{.ContextFile}}\nIntent: {{.Intent}}",
+			"Context: login hangs\nNotes: We use Go.\nIntent: stop the hang"},
+		{"branch",
+			"Branch {{.Branch}}{{with .Ticket}}, ticket {{.}}{{end}}",
+			"Branch feature/ABC-123-login-timeout, ticket ABC-123"},
+		{"range",
+			"{{range $i, $f := .Files}}{{if $i}}\n{{end}}- {{$f}}{{end}}",
+			"- auth/login.go\n- auth/login_test.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := promptTemplateConfig(t, tt.template, tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.Commit.Convention = config.ConventionalCommits
+			cfg.Commit.MaxLength = 72
+			cfg.Commit.MaxBodyLength = 500
+			cfg.Commit.IncludeBody = true
+			cfg.Context.UserContext = "login hangs"
+			cfg.Context.FileContext = "We use Go."
+			cfg.Context.Intent = "stop the hang"
+			if err := CheckPromptTemplates(cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			files := []string{"auth/login.go", "auth/login_test.go"}
+			if got := customPrompt(cfg, files, "+timeout := 30"); got != tt.want {
+				t.Errorf("prompt =\n%s\nwant\n%s", got, tt.want)
+			}
+			// The system prompt has no files or diff
+			want := strings.NewReplacer("auth/login.go, auth/login_test.go", "", "+timeout := 30", "", "- auth/login.go\n- auth/login_test.go", "").Replace(tt.want)
+			if got := getSystemPrompt(cfg); got != strings.TrimSpace(want) {
+				t.Errorf("system prompt =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPromptTemplateParseError(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		system string
+		want   string
+	}{
+		{"unclosed action", "Files:\n{{.Files\n", "", "ai.prompt_template_file: template: %s/prompt.tmpl:3: unclosed action started at %s/prompt.tmpl:2"},
+		{"unknown function", "Files:\n\n{{upper .Files}}", "", `ai.prompt_template_file: template: %s/prompt.tmpl:3: function "upper" not defined`},
+		{"system prompt", "", "{{end}}", `ai.system_prompt_file: template: %s/system.tmpl:1: unexpected {{end}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := promptTemplateConfig(t, tt.prompt, tt.system)
+			if err == nil {
+				t.Fatal("LoadPromptTemplates accepted a broken template")
+			}
+			dir := filepath.Dir(cfg.AI.PromptTemplateFile + cfg.AI.SystemPromptFile)
+			if want := strings.ReplaceAll(tt.want, "%s", dir); err.Error() != want {
+				t.Errorf("error = %q, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestPromptTemplateExecutionError(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		system string
+		want   string
+	}{
+		{"unknown field", "Files:\n{{.Nope}}", "", "ai.prompt_template_file: %s/prompt.tmpl, line 2: <.Nope>: can't evaluate field Nope in type ai.PromptData"},
+		{"index out of range", "Files:\n\n  {{index .Files 5}}", "", "ai.prompt_template_file: %s/prompt.tmpl, line 3: <index .Files 5>: error calling index: index out of range: 5"},
+		{"system prompt", "", "You write commits.\n{{.Diff.Nope}}", "ai.system_prompt_file: %s/system.tmpl, line 2: <.Diff.Nope>: can't evaluate field Nope in type string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := promptTemplateConfig(t, tt.prompt, tt.system)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = CheckPromptTemplates(cfg)
+			if err == nil {
+				t.Fatal("CheckPromptTemplates accepted a template that fails to execute")
+			}
+			dir := filepath.Dir(cfg.AI.PromptTemplateFile + cfg.AI.SystemPromptFile)
+			if want := strings.ReplaceAll(tt.want, "%s", dir); err.Error() != want {
+				t.Errorf("error = %q, want %q", err, want)
+			}
+			var execErr template.ExecError
+			if !errors.As(err, &execErr) {
+				t.Error("the text/template error was not wrapped")
+			}
+		})
+	}
+}
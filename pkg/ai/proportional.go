@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// minFileShare is the share every file gets before the rest of the budget is split by
+// priority, enough for the diff header and the first lines of a hunk
+const minFileShare = 40
+
+// truncationMarkerTokens is kept free in each cut file for TruncateToTokenLimit's marker
+const truncationMarkerTokens = 10
+
+// truncateDiff shortens a diff to maxTokens as context.truncate_mode says
+func truncateDiff(cfg *config.Config, diff string, maxTokens int, model string) string {
+	if cfg.Context.TruncateMode == config.TruncateProportional {
+		if truncated, ok := truncateProportionally(cfg, diff, maxTokens, model); ok {
+			return truncated
+		}
+	}
+	return tokenizer.TruncateToTokenLimit(diff, maxTokens, model)
+}
+
+// truncateProportionally cuts each file's diff to a share of maxTokens, so the files at the
+// end of the diff are shortened along with the rest instead of dropped. Every file gets
+// minFileShare first; the remaining budget is split by priority, and whatever a small file
+// doesn't use goes to the larger ones. Files stay in diff order. It returns false when the
+// diff can't be split into files.
+func truncateProportionally(cfg *config.Config, diff string, maxTokens int, model string) (string, bool) {
+	if tokenizer.CountTokens(diff, model) <= maxTokens {
+		return diff, true
+	}
+	files := ParseDiffByFile(diff)
+	if len(files) == 0 {
+		return "", false
+	}
+
+	prioritized := PrioritizeFiles(files)
+	priorities := make(map[string]int, len(prioritized))
+	for _, file := range prioritized {
+		priorities[file.Path] = file.Priority
+	}
+
+	sizes := make([]int, len(files))
+	weights := make([]int, len(files))
+	for i, file := range files {
+		sizes[i] = tokenizer.CountTokens(file.Content, model)
+		weights[i] = priorities[file.Path] + 1 // Priority 0 files still get a share
+	}
+	shares := allocateShares(sizes, weights, maxTokens-len(files)) // Each file ends with a newline
+
+	var result strings.Builder
+	var cut []string
+	for i, file := range files {
+		content := file.Content
+		if sizes[i] > shares[i] {
+			content = tokenizer.TruncateToTokenLimit(content, max(shares[i]-truncationMarkerTokens, 0), model)
+			cut = append(cut, fmt.Sprintf("%s %d→%d", file.Path, sizes[i], shares[i]))
+		}
+		result.WriteString(strings.TrimRight(content, "\n"))
+		result.WriteString("\n")
+	}
+	debugPrint(cfg, "PROPORTIONAL TRUNCATION", fmt.Sprintf("%d of %d files cut to their share (tokens):\n%s", len(cut), len(files), strings.Join(cut, "\n")))
+	return result.String(), true
+}
+
+// allocateShares splits budget across files: each gets up to minFileShare (less when the
+// budget is too small for that), then the rest is handed out by weight, capped at each
+// file's size and redistributed until it is used up or every file fits
+func allocateShares(sizes, weights []int, budget int) []int {
+	shares := make([]int, len(sizes))
+	floor := minFileShare
+	if len(sizes) > 0 {
+		floor = min(floor, budget/len(sizes))
+	}
+	remaining := budget
+	for i, size := range sizes {
+		shares[i] = min(size, floor)
+		remaining -= shares[i]
+	}
+
+	for remaining > 0 {
+		totalWeight := 0
+		for i, size := range sizes {
+			if shares[i] < size {
+				totalWeight += weights[i]
+			}
+		}
+		if totalWeight == 0 {
+			break // Every file fits whole
+		}
+
+		handedOut := 0
+		for i, size := range sizes {
+			if shares[i] >= size {
+				continue
+			}
+			extra := min(remaining*weights[i]/totalWeight, size-shares[i])
+			shares[i] += extra
+			handedOut += extra
+		}
+		if handedOut == 0 {
+			break // Only rounding remainders are left
+		}
+		remaining -= handedOut
+	}
+	return shares
+}
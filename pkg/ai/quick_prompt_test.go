@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// quickPromptTokenLimit is the most a quick mode prompt may cost, whatever the diff size
+const quickPromptTokenLimit = 3000
+
+func TestQuickPromptTokenBudget(t *testing.T) {
+	tests := []struct {
+		name  string
+		files int
+		lines int
+	}{
+		{"small diff", 1, 10},
+		{"one large file", 1, 5000},
+		{"many files", 60, 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			var files []string
+			var diff strings.Builder
+			for i := 0; i < tt.files; i++ {
+				path := fmt.Sprintf("pkg/service%02d/handler.go", i)
+				var lines []string
+				for j := 0; j < tt.lines; j++ {
+					lines = append(lines, fmt.Sprintf("\tresult%04d := client.Fetch(ctx, \"resource-%d\", %d)", j, j, i))
+				}
+				files = append(files, path)
+				diff.WriteString(fileDiff(path, lines))
+			}
+
+			var prompt string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var request struct {
+					Messages []struct {
+						Role    string `json:"role"`
+						Content string `json:"content"`
+					} `json:"messages"`
+				}
+				body, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(body, &request); err != nil {
+					t.Errorf("invalid request body: %v", err)
+				}
+				for _, message := range request.Messages {
+					if message.Role == "user" {
+						prompt = message.Content
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"feat: fetch resources in the handlers"}}]}`)
+			}))
+			defer server.Close()
+
+			cfg := config.DefaultConfig()
+			cfg.AI.APIKey = "test-key"
+			cfg.AI.OpenAIEndpoint = server.URL + "/v1/chat/completions"
+			cfg.AI.Model = "gpt-4o-mini" // A large context, so the diff budget and not the emergency prompt limits it
+			cfg.UI.EnableTUI = false
+			cfg.Commit.QuickMode = true
+
+			if _, err := GenerateCommitMessage(cfg, files, diff.String()); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(prompt, "Output ONLY the subject line") {
+				t.Fatalf("the quick mode prompt wasn't sent:\n%s", prompt)
+			}
+			if tokens := tokenizer.CountTokens(prompt, cfg.AI.Model); tokens > quickPromptTokenLimit {
+				t.Errorf("quick mode prompt is %d tokens, want at most %d", tokens, quickPromptTokenLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// redactionMarker replaces every match of context.redact_patterns
+const redactionMarker = "[REDACTED]"
+
+// redact replaces the matches of context.redact_patterns in text with redactionMarker and
+// returns how many were replaced. An invalid pattern is an error, so nothing is sent that the
+// author meant to keep back.
+func redact(cfg *config.Config, text string) (string, int, error) {
+	count := 0
+	for _, expr := range cfg.Context.RedactPatterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid pattern %q in context.redact_patterns: %w", expr, err)
+		}
+		text = pattern.ReplaceAllStringFunc(text, func(string) string {
+			count++
+			return redactionMarker
+		})
+	}
+	return text, count, nil
+}
+
+// systemMessage returns the system prompt sent with the prompt: the length preamble and the
+// configured or built-in system prompt, redacted like the prompt itself, since a system
+// prompt template can show the context file, --context, the intent and the branch
+func systemMessage(cfg *config.Config) (string, error) {
+	message, redacted, err := redact(cfg, withLengthPreamble(cfg, getSystemPrompt(cfg)))
+	if err != nil {
+		return "", err
+	}
+	if redacted > 0 {
+		debugPrint(cfg, "REDACTED", fmt.Sprintf("%d matches of context.redact_patterns replaced with %s in the system prompt", redacted, redactionMarker))
+	}
+	return message, nil
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func TestRedactCoversSystemPrompt(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, config.DefaultContextFile, "Deploys use the key sk-live-ABC123 from the vault.\n")
+	template := filepath.Join(t.TempDir(), "system.tmpl")
+	writeFile(t, template, "You write commit messages. Background: {{.ContextFile}}\n")
+
+	var request struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if strings.Contains(string(body), "sk-live-") {
+			t.Errorf("a secret was sent to the provider:\n%s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"fix: rotate the key"}}]}`)
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.AI.APIKey = "test-key"
+	cfg.AI.OpenAIEndpoint = server.URL + "/v1/chat/completions"
+	cfg.AI.SystemPromptFile = template
+	if err := cfg.LoadPromptTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Context.RedactPatterns = []string{`sk-live-[A-Za-z0-9]+`}
+	cfg.Context.FileContext = ReadContextFile(cfg)
+
+	if _, err := callProvider(cfg, "Describe the change to sk-live-XYZ789."); err != nil {
+		t.Fatalf("callProvider: %v", err)
+	}
+	if len(request.Messages) != 2 || request.Messages[0].Role != "system" {
+		t.Fatalf("want a system and a user message, got %+v", request.Messages)
+	}
+	if system := request.Messages[0].Content; !strings.Contains(system, "Deploys use the key "+redactionMarker+" from the vault.") {
+		t.Errorf("the context file's secret wasn't replaced in the system message:\n%s", system)
+	}
+	if user := request.Messages[1].Content; !strings.Contains(user, "Describe the change to "+redactionMarker+".") {
+		t.Errorf("the prompt's secret wasn't replaced:\n%s", user)
+	}
+}
+
+func TestRedactInvalidPattern(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Context.RedactPatterns = []string{`sk-(`}
+	if _, _, err := redact(cfg, "text"); err == nil || !strings.Contains(err.Error(), "context.redact_patterns") {
+		t.Errorf("redact error = %v, want one naming context.redact_patterns", err)
+	}
+	if _, err := systemMessage(cfg); err == nil {
+		t.Error("systemMessage sent a system prompt despite an invalid pattern")
+	}
+}
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func TestIsRefusal(t *testing.T) {
+	tests := []struct {
+		response string
+		want     bool
+	}{
+		{"I'm sorry, but I can't help with that.", true},
+		{"I’m sorry, I can’t assist with this request.", true},
+		{"Sorry, I cannot generate a commit message for this content.", true},
+		{"Unfortunately, I am unable to provide that.", true},
+		{"Apologies, but I won't write that.", true},
+		{"I can't comply with this request.", true},
+		{"  \n I am not able to complete this task.", true},
+		{`{"type":"chore","subject":"I'm sorry, but I can't help with that"}`, true},
+		{"```json\n{\"type\":\"fix\",\"subject\":\"I cannot assist with this\"}\n```", true},
+		{"fix: handle sorry-state in the dialog", false},
+		{"feat(i18n): translate \"I can't help\" message", false},
+		{"Sorry for the churn: rename the helpers", false},
+		{"refactor: note that we cannot help old clients", false},
+		{`{"type":"fix","subject":"explain why we can't assist legacy users"}`, false},
+		{`{"type":"docs","subject":"update the FAQ","body":"I'm sorry, but I can't help with that is the bot's reply."}`, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isRefusal(tt.response); got != tt.want {
+			t.Errorf("isRefusal(%q) = %v, want %v", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestRefusalError(t *testing.T) {
+	var err error = &RefusalError{Provider: "OpenAI", Text: "I can't help with that."}
+	if !errors.Is(err, ErrRefused) {
+		t.Error("RefusalError doesn't wrap ErrRefused")
+	}
+	wrapped := fmt.Errorf("generating: %w", err)
+	var refusal *RefusalError
+	if !errors.As(wrapped, &refusal) || refusal.Text != "I can't help with that." {
+		t.Errorf("errors.As lost the refusal: %+v", refusal)
+	}
+	if message := err.Error(); !strings.Contains(message, "OpenAI replied") || !strings.HasSuffix(message, "I can't help with that.") {
+		t.Errorf("Error() = %q, want the provider and its reply", message)
+	}
+}
+
+func TestReadOpenAIStreamRefusal(t *testing.T) {
+	stream := `data: {"choices":[{"delta":{"refusal":"I'm sorry, "}}]}
+
+data: {"choices":[{"delta":{"refusal":"I can't help with that."}}]}
+
+data: [DONE]
+`
+	_, err := readOpenAIStream(config.DefaultConfig(), strings.NewReader(stream))
+	var refusal *RefusalError
+	if !errors.As(err, &refusal) || !errors.Is(err, ErrRefused) {
+		t.Fatalf("readOpenAIStream error = %v, want a RefusalError", err)
+	}
+	if refusal.Text != "I'm sorry, I can't help with that." {
+		t.Errorf("refusal text = %q, want the chunks joined", refusal.Text)
+	}
+
+	answer := `data: {"choices":[{"delta":{"content":"fix: close "}}]}
+
+data: {"choices":[{"delta":{"content":"the file"}}]}
+
+data: [DONE]
+`
+	if content, err := readOpenAIStream(config.DefaultConfig(), strings.NewReader(answer)); err != nil || content != "fix: close the file" {
+		t.Errorf("readOpenAIStream = %q, %v, want the content", content, err)
+	}
+}
+
+// refusalServer answers every chat completion with body
+func refusalServer(t *testing.T, body string) *config.Config {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.AI.APIKey = "test-key"
+	cfg.AI.OpenAIEndpoint = server.URL + "/v1/chat/completions"
+	return cfg
+}
+
+func TestCallProviderRefusal(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		text string
+	}{
+		{"refusal field", `{"choices":[{"message":{"role":"assistant","content":null,"refusal":"I can't help with that."}}]}`, "I can't help with that."},
+		{"refusal phrase", `{"choices":[{"message":{"role":"assistant","content":"I'm sorry, but I cannot assist with this diff."}}]}`, "I'm sorry, but I cannot assist with this diff."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := refusalServer(t, tt.body)
+			response, err := callProvider(cfg, "Write a commit message")
+			var refusal *RefusalError
+			if !errors.As(err, &refusal) {
+				t.Fatalf("callProvider = %q, %v, want a RefusalError", response, err)
+			}
+			if refusal.Text != tt.text {
+				t.Errorf("refusal text = %q, want %q", refusal.Text, tt.text)
+			}
+		})
+	}
+
+	cfg := refusalServer(t, `{"choices":[{"message":{"role":"assistant","content":"fix: close the file"}}]}`)
+	if response, err := callProvider(cfg, "Write a commit message"); err != nil || response != "fix: close the file" {
+		t.Errorf("callProvider = %q, %v, want the answer", response, err)
+	}
+}
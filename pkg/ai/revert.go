@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// revertDiffTokens caps the diff sent along when asking for the reason of a revert
+const revertDiffTokens = 2000
+
+// revertFooterPattern matches the footer git and the conventional spec use to link a revert
+var revertFooterPattern = regexp.MustCompile(`(?m)^This reverts commit [0-9a-f]{7,40}\.$`)
+
+// GenerateRevertMessage builds the message for a revert locally: "revert: <original subject>"
+// (or git's `Revert "<subject>"` without a conventional convention) and the
+// "This reverts commit <sha>." footer. Only the reason for the revert is written by the AI,
+// from the diff and the optional user context, and only when bodies are enabled.
+func GenerateRevertMessage(cfg *config.Config, sha, originalSubject, changes string) (string, error) {
+	var message strings.Builder
+	if cfg.Commit.Convention.IsConventional() {
+		message.WriteString("revert: " + originalSubject)
+	} else {
+		message.WriteString(fmt.Sprintf("Revert %q", originalSubject))
+	}
+
+	if cfg.Commit.IncludeBody {
+		reason, err := revertReason(cfg, originalSubject, changes)
+		if err != nil {
+			return "", err
+		}
+		if reason != "" {
+			message.WriteString("\n\n" + reason)
+		}
+	}
+
+	message.WriteString(fmt.Sprintf("\n\nThis reverts commit %s.", sha))
+	return message.String(), nil
+}
+
+// revertReason asks the model for a short explanation of why the commit is reverted
+func revertReason(cfg *config.Config, originalSubject, changes string) (string, error) {
+	reasonCfg := *cfg
+	reasonCfg.AI.DisableLengthPreamble = true
+
+	model := cfg.Context.TokenizerModel
+	if model == "" {
+		model = cfg.AI.Model
+	}
+	changes = tokenizer.TruncateToTokenLimit(StripDiffMetadata(normalizeNewlines(changes)), revertDiffTokens, model)
+
+	prompt := "A commit is being reverted. Write the body of the revert commit message: 1-3 plain sentences " +
+		"explaining why the change is being reverted. Do not repeat the subject, do not describe the diff line by line, " +
+		"do not use bullet points, and return only the text.\n\n" +
+		fmt.Sprintf("Reverted commit: %s\n", originalSubject)
+	if cfg.Context.UserContext != "" {
+		prompt += fmt.Sprintf("Reason given by the author: %s\n", cfg.Context.UserContext)
+	}
+	prompt += fmt.Sprintf("\nDiff of the revert:\n```\n%s\n```", changes)
+	debugPrint(cfg, "REVERT PROMPT", prompt)
+
+	response, err := callProvider(&reasonCfg, prompt)
+	if err != nil {
+		return "", err
+	}
+	debugPrint(cfg, "REVERT RESPONSE", response)
+
+	reason, err := sanitizeResponse(cfg, normalizeNewlines(response))
+	if err != nil {
+		return "", err
+	}
+	if match := codeFencePattern.FindStringSubmatch(reason); match != nil {
+		reason = strings.TrimSpace(match[1])
+	}
+	return strings.Trim(reason, "\"'"), nil
+}
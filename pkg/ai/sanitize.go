@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// reasoningPatterns match the reasoning blocks chain-of-thought models wrap around their answer
+var reasoningPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?is)<think>.*?</think>`),
+	regexp.MustCompile(`(?is)<thinking>.*?</thinking>`),
+	regexp.MustCompile(`(?is)<reasoning>.*?</reasoning>`),
+}
+
+// analysisOpening matches the first words of an analysis or chatty paragraph ("Sure! Here's...")
+// written before the answer
+var analysisOpening = regexp.MustCompile(`(?i)^(let me|let's|i'll|i will|i need to|first,|looking at|analyzing|okay|alright|sure|certainly|of course|here's|here is|the (diff|changes?) (shows?|adds?|removes?))\b`)
+
+// sanitizeResponse removes reasoning blocks, the patterns in ai.strip_patterns, and analysis
+// paragraphs in front of the first line that looks like the commit message
+func sanitizeResponse(cfg *config.Config, response string) (string, error) {
+	patterns := reasoningPatterns
+	for _, expr := range cfg.AI.StripPatterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern %q in ai.strip_patterns: %w", expr, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	cleaned := response
+	for _, pattern := range patterns {
+		cleaned = pattern.ReplaceAllString(cleaned, "")
+	}
+	cleaned = dropAnalysisParagraphs(strings.TrimSpace(cleaned))
+
+	if cleaned != strings.TrimSpace(response) {
+		debugPrint(cfg, "RESPONSE SANITIZED", cleaned)
+	}
+	return cleaned, nil
+}
+
+// dropAnalysisParagraphs drops leading paragraphs when the first one reads like analysis and
+// a later one starts with JSON, a code fence, a [SUBJECT] marker or a conventional subject
+func dropAnalysisParagraphs(text string) string {
+	paragraphs := strings.Split(text, "\n\n")
+	if len(paragraphs) < 2 || !analysisOpening.MatchString(strings.TrimSpace(paragraphs[0])) {
+		return text
+	}
+
+	for i := 1; i < len(paragraphs); i++ {
+		first, _, _ := strings.Cut(strings.TrimSpace(paragraphs[i]), "\n")
+		first = strings.TrimSpace(first)
+		if strings.HasPrefix(first, "{") || strings.HasPrefix(first, "```") ||
+			strings.Contains(first, "[SUBJECT]") || conventionalSubject.MatchString(first) {
+			return strings.Join(paragraphs[i:], "\n\n")
+		}
+	}
+	return text
+}
+
+// escapeSequence matches ANSI escape sequences: CSI (colors, cursor movement), OSC (titles,
+// hyperlinks) terminated by BEL or ST, and the two-byte forms
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// zeroWidth are invisible characters that survive copy-paste and confuse diffs and hosting UIs
+const zeroWidth = "\u200b\u200c\u200d\u2060\ufeff"
+
+// SanitizeMessage is the last step before a message is committed, written or printed: it
+// normalizes CRLF to LF and removes ANSI escape sequences, zero-width characters and control
+// characters other than newline and tab, which models sometimes echo from colored context
+func SanitizeMessage(message string) string {
+	message = escapeSequence.ReplaceAllString(normalizeNewlines(message), "")
+	return strings.Map(func(r rune) rune {
+		if (unicode.IsControl(r) && r != '\n' && r != '\t') || strings.ContainsRune(zeroWidth, r) {
+			return -1
+		}
+		return r
+	}, message)
+}
@@ -0,0 +1,28 @@
+package ai
+
+import "testing"
+
+func TestSanitizeMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"red subject", "\x1b[31mfix: close the file\x1b[0m", "fix: close the file"},
+		{"bold and colored words", "fix: close \x1b[1;32mthe\x1b[0m file\n\n- \x1b[38;5;244mClose it\x1b[m on error", "fix: close the file\n\n- Close it on error"},
+		{"CRLF", "fix: close the file\r\n\r\n- Close it on error\r\n- Add a test\r\n", "fix: close the file\n\n- Close it on error\n- Add a test\n"},
+		{"CRLF and colors", "\x1b[31mfix: close the file\x1b[0m\r\n\r\n\x1b[31m- Close it\x1b[0m\r\n", "fix: close the file\n\n- Close it\n"},
+		{"lone CR", "fix: close the file\r\r- Close it", "fix: close the file\n\n- Close it"},
+		{"hyperlink", "docs: see \x1b]8;;https://example.com\x07the guide\x1b]8;;\x07", "docs: see the guide"},
+		{"cursor movement", "fix: close\x1b[2K the file\x1b[1A", "fix: close the file"},
+		{"zero-width and control characters", "fix: close\u200b the\x00 file\ufeff\x07", "fix: close the file"},
+		{"tabs and unicode kept", "fix: close the fichier\n\n\t- réessayer 🚀", "fix: close the fichier\n\n\t- réessayer 🚀"},
+			}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeMessage(tt.message); got != tt.want {
+				t.Errorf("SanitizeMessage(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+// scopeHistoryDepth is the number of recent commits scanned for scopes
+const scopeHistoryDepth = 200
+
+// SuggestScopeFromHistory picks the scope most often used in past commits that
+// touched the same directories as the changed files. Returns "" when nothing matches.
+func SuggestScopeFromHistory(cfg *config.Config, files []string) string {
+	commits, err := git.GetRecentCommits(scopeHistoryDepth)
+	if err != nil {
+		debugPrint(cfg, "SCOPE HISTORY ERROR", err.Error())
+		return ""
+	}
+	commits = skipHistoryNoise(cfg, commits)
+
+	scope, scores := rankHistoricalScopes(commits, files)
+	if len(scores) > 0 {
+		debugPrint(cfg, "SCOPE HISTORY", map[string]interface{}{
+			"scores":   scores,
+			"selected": scope,
+		})
+	}
+
+	return scope
+}
+
+// skipHistoryNoise drops work-in-progress and fixup commits (commit.history_skip_prefixes),
+// whose subjects say nothing about how the project writes its messages
+func skipHistoryNoise(cfg *config.Config, commits []git.RecentCommit) []git.RecentCommit {
+	var kept []git.RecentCommit
+	for _, commit := range commits {
+		if !hasSkippedPrefix(commit.Subject, cfg.Commit.HistorySkipPrefixes) {
+			kept = append(kept, commit)
+		}
+	}
+	return kept
+}
+
+// hasSkippedPrefix reports whether subject starts with one of prefixes, ignoring case. A
+// prefix ending in a letter or digit must end a word, so "wip" matches "WIP: tests" and
+// "wip" but not "wipe cache"
+func hasSkippedPrefix(subject string, prefixes []string) bool {
+	subject = strings.ToLower(strings.TrimSpace(subject))
+	for _, prefix := range prefixes {
+		prefix = strings.ToLower(strings.TrimSpace(prefix))
+		if prefix == "" || !strings.HasPrefix(subject, prefix) {
+			continue
+		}
+		last, _ := utf8.DecodeLastRuneInString(prefix)
+		next, _ := utf8.DecodeRuneInString(subject[len(prefix):])
+		if !isWordRune(last) || next == utf8.RuneError || !isWordRune(next) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordRune reports whether r can be part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// rankHistoricalScopes scores each scope found in the commit history against the changed files
+func rankHistoricalScopes(commits []git.RecentCommit, files []string) (string, map[string]int) {
+	// Count how often each scope was used per directory
+	scopesByDir := make(map[string]map[string]int)
+	knownScopes := make(map[string]int)
+
+	for _, commit := range commits {
+		parsed := parseTextCommitMessage(commit.Subject)
+		scope := strings.ToLower(strings.TrimSpace(parsed.Scope))
+		if scope == "" {
+			continue
+		}
+		knownScopes[scope]++
+
+		for _, file := range commit.Files {
+			dir := filepath.Dir(file)
+			if scopesByDir[dir] == nil {
+				scopesByDir[dir] = make(map[string]int)
+			}
+			scopesByDir[dir][scope]++
+		}
+	}
+
+	scores := make(map[string]int)
+	for _, file := range files {
+		// Scopes used for the same directory are the strongest signal
+		for scope, count := range scopesByDir[filepath.Dir(file)] {
+			scores[scope] += 2 * count
+		}
+
+		// Scopes that name a path segment of the changed file are a weaker signal
+		for _, segment := range strings.Split(strings.ToLower(file), "/") {
+			segment = strings.TrimSuffix(segment, filepath.Ext(segment))
+			if count, ok := knownScopes[segment]; ok {
+				scores[segment] += count
+			}
+		}
+	}
+
+	// Pick the highest score, breaking ties alphabetically for stable output
+	var ranked []string
+	for scope := range scores {
+		ranked = append(ranked, scope)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] > scores[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+
+	if len(ranked) == 0 {
+		return "", scores
+	}
+	return ranked[0], scores
+}
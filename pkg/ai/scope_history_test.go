@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/git"
+)
+
+func TestHasSkippedPrefix(t *testing.T) {
+	prefixes := config.DefaultConfig().Commit.HistorySkipPrefixes
+	tests := []struct {
+		subject string
+		want    bool
+	}{
+		{"wip", true},
+		{"WIP: tests", true},
+		{"Wip(parser): half done", true},
+		{"wip parser", true},
+		{"  wip", true},
+		{"fixup! feat(parser): add guard", true},
+		{"squash! fix(lexer): close file", true},
+		{"amend! docs: readme", true},
+		{"FIXUP! fix: typo", true},
+		{"wipe the cache", false},
+		{"wiping: old files", false},
+		{"fixup the build", false},
+		{"feat(parser): wip support", false},
+		{"fix: squash! handling", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hasSkippedPrefix(tt.subject, prefixes); got != tt.want {
+			t.Errorf("hasSkippedPrefix(%q) = %v, want %v", tt.subject, got, tt.want)
+		}
+	}
+
+	if hasSkippedPrefix("wip: tests", nil) || hasSkippedPrefix("wip: tests", []string{" ", ""}) {
+		t.Error("empty prefixes skipped a commit")
+	}
+	if !hasSkippedPrefix("[draft] parser", []string{"[DRAFT]"}) {
+		t.Error("a prefix ending in punctuation didn't match")
+	}
+}
+
+func TestSkipHistoryNoise(t *testing.T) {
+	commits := []git.RecentCommit{
+		{Subject: "fixup! feat(lexer): tweak"},
+		{Subject: "feat(parser): add guard"},
+		{Subject: "WIP(lexer): poke"},
+		{Subject: "wipe stale fixtures"},
+		{Subject: "squash! fix(lexer): x"},
+	}
+	cfg := config.DefaultConfig()
+	want := []git.RecentCommit{{Subject: "feat(parser): add guard"}, {Subject: "wipe stale fixtures"}}
+	if got := skipHistoryNoise(cfg, commits); !reflect.DeepEqual(got, want) {
+		t.Errorf("skipHistoryNoise = %+v, want %+v", got, want)
+	}
+
+	cfg.Commit.HistorySkipPrefixes = nil
+	if got := skipHistoryNoise(cfg, commits); !reflect.DeepEqual(got, commits) {
+		t.Errorf("skipHistoryNoise without prefixes = %+v, want every commit", got)
+	}
+}
+
+func TestSuggestScopeFromHistorySkipsNoise(t *testing.T) {
+	newTestRepo(t)
+	commit := func(subject, file, content string) {
+		writeFile(t, file, content)
+		runGit(t, "add", file)
+		runGit(t, "commit", "-q", "-m", subject)
+	}
+	commit("feat(parser): add the parser", "pkg/parser/parser.go", "package parser\n")
+	commit("WIP(lexer): poke at the parser", "pkg/parser/parser.go", "package parser\n\n// wip\n")
+	commit("fixup! feat(lexer): tweak", "pkg/parser/parser.go", "package parser\n\n// wip 2\n")
+	commit("squash! feat(lexer): more", "pkg/parser/parser.go", "package parser\n\n// wip 3\n")
+
+	cfg := config.DefaultConfig()
+	files := []string{"pkg/parser/parser.go"}
+	if got := SuggestScopeFromHistory(cfg, files); got != "parser" {
+		t.Errorf("SuggestScopeFromHistory = %q, want parser from the one real commit", got)
+	}
+
+	cfg.Commit.HistorySkipPrefixes = nil
+	if got := SuggestScopeFromHistory(cfg, files); got != "lexer" {
+		t.Errorf("SuggestScopeFromHistory without skip prefixes = %q, want lexer from the noise", got)
+	}
+}
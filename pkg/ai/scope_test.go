@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+func TestScopeModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		scope       config.ScopeRequirement
+		format      string
+		instruction string
+		jsonExample string
+		example     string
+		regenerate  bool
+	}{
+		{
+			name:        "required",
+			scope:       config.ScopeRequired,
+			format:      "type(scope): subject",
+			instruction: "A SCOPE IS REQUIRED",
+			jsonExample: "Required, must be lowercase",
+			example:     "fix(parser): correct array parsing issue",
+			regenerate:  true,
+		},
+		{
+			name:        "optional",
+			scope:       config.ScopeOptional,
+			format:      "type(optional-scope): subject",
+			jsonExample: "Optional, must be lowercase",
+			example:     "fix(parser): correct array parsing issue",
+		},
+		{
+			name:        "forbidden",
+			scope:       config.ScopeForbidden,
+			format:      "type: subject",
+			instruction: "DO NOT INCLUDE A SCOPE",
+			jsonExample: "Must be empty, scopes are not allowed",
+			example:     "fix: correct array parsing issue",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Commit.Convention = config.ConventionalCommits
+			cfg.Commit.Scope = tt.scope
+			cfg.Commit.IncludeBody = false
+
+			if got := scopeFormat(cfg); got != tt.format {
+				t.Errorf("scopeFormat = %q, want %q", got, tt.format)
+			}
+			if got := scopeInstruction(cfg); tt.instruction == "" && got != "" || !strings.Contains(got, tt.instruction) {
+				t.Errorf("scopeInstruction = %q, want %q", got, tt.instruction)
+			}
+			if got := scopeJSONExample(cfg); !strings.Contains(got, tt.jsonExample) {
+				t.Errorf("scopeJSONExample = %q, want %q", got, tt.jsonExample)
+			}
+
+			prompt := GenerateTextPrompt(cfg, []string{"auth/login.go"}, "+timeout := 30")
+			for _, want := range []string{"Format MUST BE: " + tt.format, tt.example, tt.instruction} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt is missing %q:\n%s", want, prompt)
+				}
+			}
+
+			reason := regenerationReason(CommitMessage{Type: "feat", Subject: "add login timeout"}, cfg)
+			if (reason != "") != tt.regenerate {
+				t.Errorf("regenerationReason for a scopeless commit = %q, want a retry: %v", reason, tt.regenerate)
+			}
+			if reason := regenerationReason(CommitMessage{Type: "feat", Scope: "auth", Subject: "add login timeout"}, cfg); reason != "" {
+				t.Errorf("regenerationReason for a scoped commit = %q, want none", reason)
+			}
+		})
+	}
+}
+
+func TestScopeModesGenerate(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope config.ScopeRequirement
+		want  string
+	}{
+		{"required", config.ScopeRequired, "feat(auth): add login timeout"},
+		{"optional", config.ScopeOptional, "feat(auth): add login timeout"},
+		{"forbidden", config.ScopeForbidden, "feat: add login timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			cfg := refusalServer(t, `{"choices":[{"message":{"role":"assistant","content":"{\"type\":\"feat\",\"scope\":\"auth\",\"subject\":\"add login timeout\"}"}}]}`)
+			cfg.UI.EnableTUI = false
+			cfg.Commit.Convention = config.ConventionalCommits
+			cfg.Commit.Scope = tt.scope
+			cfg.Commit.IncludeBody = false
+
+			message, err := GenerateCommitMessage(cfg, []string{"auth/login.go"}, "+timeout := 30")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := firstLine(message); got != tt.want {
+				t.Errorf("subject = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
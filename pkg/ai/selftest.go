@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"time"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// selfTestFiles is the file list matching selfTestDiff
+var selfTestFiles = []string{"greeting.go"}
+
+// selfTestDiff is a tiny fixed change used to verify the provider round-trip
+const selfTestDiff = `diff --git a/greeting.go b/greeting.go
+index 3b18e51..a1c2d3e 100644
+--- a/greeting.go
++++ b/greeting.go
+@@ -1,5 +1,9 @@
+ package main
+
+-func greet() string {
+-	return "hello"
++import "fmt"
++
++// greet returns a personalized greeting
++func greet(name string) string {
++	return fmt.Sprintf("hello, %s", name)
+ }
+`
+
+// SelfTest sends a small fixed diff to the configured provider without touching git.
+// Returns the formatted commit message and the provider round-trip latency.
+func SelfTest(cfg *config.Config) (string, time.Duration, error) {
+	// Disable everything that reads from the repository
+	testCfg := *cfg
+	testCfg.UI.EnableTUI = false
+	testCfg.Context.IncludeDiff = true
+	testCfg.Context.IncludeFileStats = false
+	testCfg.Context.IncludeFileSummaries = false
+	testCfg.Context.ShowFirstLinesOfFile = 0
+	testCfg.Context.IncludeRepoStructure = false
+	testCfg.Commit.LearnScopesFromHistory = false
+
+	var prompt string
+	if testCfg.Commit.Convention.IsConventional() {
+		prompt = GenerateTextPrompt(&testCfg, selfTestFiles, selfTestDiff)
+	} else {
+		prompt = buildPrompt(&testCfg, selfTestFiles, selfTestDiff)
+	}
+	debugPrint(&testCfg, "SELF-TEST PROMPT", prompt)
+
+	start := time.Now()
+	rawResponse, err := callProvider(&testCfg, prompt)
+	latency := time.Since(start)
+	if err != nil {
+		return "", latency, err
+	}
+	debugPrint(&testCfg, "SELF-TEST RESPONSE", rawResponse)
+
+	commitMsg, parsed := parseProviderResponse(&testCfg, rawResponse)
+	if !parsed {
+		return rawResponse, latency, nil
+	}
+
+	return FormatCommitMessage(commitMsg, &testCfg), latency, nil
+}
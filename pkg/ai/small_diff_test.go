@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/johnstilia/commitron/pkg/config"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// smallDiff is a one-file change of about 1KB, the typical size the fast path serves
+func smallDiff() string {
+	var diff strings.Builder
+	diff.WriteString("diff --git a/pkg/ai/cache.go b/pkg/ai/cache.go\nindex 1111111..2222222 100644\n--- a/pkg/ai/cache.go\n+++ b/pkg/ai/cache.go\n@@ -10,6 +10,20 @@ func lookup(key string) string {\n")
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&diff, "+\tif value, ok := cache[%q]; ok {\n", fmt.Sprintf("key%d", i))
+	}
+	return diff.String()
+}
+
+func TestSmallDiffTakesFastPath(t *testing.T) {
+	cfg := config.DefaultConfig()
+	diff := smallDiff()
+	if len(diff) >= smallDiffBytes {
+		t.Fatalf("the small diff is %d bytes, not under %d", len(diff), smallDiffBytes)
+	}
+	if result := processDiff(cfg, []string{"pkg/ai/cache.go"}, diff, false); !result.Small {
+		t.Error("a small diff didn't take the fast path")
+	}
+}
+
+// BenchmarkSmallDiff compares processing a small diff with and without the fast path that
+// skips tokenization. With tiktoken's encoding files available the difference includes
+// loading and running the encoder; offline both sides estimate.
+func BenchmarkSmallDiff(b *testing.B) {
+	if err := tokenizer.SetEstimator(tokenizer.EstimatorTiktoken); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { tokenizer.SetEstimator(tokenizer.EstimatorChars) })
+
+	cfg := config.DefaultConfig()
+	files := []string{"pkg/ai/cache.go"}
+	diff := smallDiff()
+
+	b.Run("fast path", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			processDiff(cfg, files, diff, false)
+		}
+	})
+	b.Run("tokenized", func(b *testing.B) {
+		limit := smallDiffBytes
+		smallDiffBytes = 0
+		defer func() { smallDiffBytes = limit }()
+		for i := 0; i < b.N; i++ {
+			processDiff(cfg, files, diff, false)
+		}
+	})
+}
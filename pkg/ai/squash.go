@@ -0,0 +1,31 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// maxSquashedSubjects caps the commit subjects listed in the prompt for a squash
+const maxSquashedSubjects = 50
+
+// squashSection lists the subjects of the commits being squashed, which tell the model what
+// the branch was for, or returns "" when not squashing
+func squashSection(cfg *config.Config) string {
+	subjects := cfg.Context.SquashedSubjects
+	if len(subjects) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf("\nThese %d commits are being squashed into one; write a single message for their combined diff (use the subjects for intent, do not list them one by one):", len(subjects)))
+	for i, subject := range subjects {
+		if i == maxSquashedSubjects {
+			section.WriteString(fmt.Sprintf("\n- ... and %d more", len(subjects)-maxSquashedSubjects))
+			break
+		}
+		section.WriteString("\n- " + subject)
+	}
+	return section.String()
+}
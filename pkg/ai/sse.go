@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// readSSEEvents calls handle with the data payload of each server-sent event
+// until the stream ends or sends the [DONE] marker
+func readSSEEvents(r io.Reader, handle func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			// Skip blank separators, comments and event/id fields
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+		if data == "" {
+			continue
+		}
+
+		if err := handle(data); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// readOpenAIStream accumulates the content of an OpenAI-compatible chat completion
+// stream, printing tokens to stderr as they arrive
+func readOpenAIStream(cfg *config.Config, body io.Reader) (string, error) {
+	type Chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content string `json:"content"`
+				Refusal string `json:"refusal"`
+			} `json:"delta"`
+		} `json:"choices"`
+		Error json.RawMessage `json:"error,omitempty"`
+	}
+
+	var content, refusal strings.Builder
+	err := readSSEEvents(body, func(data string) error {
+		var chunk Chunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid stream chunk from OpenAI API: %w", err)
+		}
+		if len(chunk.Error) > 0 {
+			return providerError("OpenAI", http.StatusOK, string(chunk.Error))
+		}
+
+		for _, choice := range chunk.Choices {
+			refusal.WriteString(choice.Delta.Refusal)
+			content.WriteString(choice.Delta.Content)
+			fmt.Fprint(os.Stderr, choice.Delta.Content)
+		}
+		return nil
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	// Debug: Show the accumulated streamed response
+	debugPrint(cfg, "OPENAI STREAMED RESPONSE", content.String())
+
+	if text := strings.TrimSpace(refusal.String()); text != "" {
+		return "", &RefusalError{Provider: "OpenAI", Text: text}
+	}
+	if content.Len() == 0 {
+		return "", fmt.Errorf("no response from OpenAI API")
+	}
+
+	return strings.TrimSpace(content.String()), nil
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// structuredNone reports whether commit.structured_none applies: no type prefix, but a
+// normalized subject, a body and trailers as with conventional commits
+func structuredNone(cfg *config.Config) bool {
+	return cfg.Commit.StructuredNone && (cfg.Commit.Convention == config.NoConvention || cfg.Commit.Convention == "")
+}
+
+// structuredRules are the prompt instructions for commit.structured_none
+func structuredRules(includeBody bool) string {
+	rules := []string{
+		"Subject MUST NOT start with a type such as 'feat:' or 'fix:'",
+		"Subject MUST be in the imperative mood, start with a capital letter and not end with a period, e.g. 'Add login timeout'",
+	}
+	if includeBody {
+		rules = append(rules, "Body MUST explain what changed and why in short bullet points")
+	}
+	rules = append(rules, "Trailers such as 'Refs: #123', 'Co-authored-by: ...' or 'BREAKING CHANGE: ...' go in the footers list, NEVER in the body")
+
+	text := "STRUCTURE REQUIREMENTS (this project does not use type prefixes):\n"
+	for i, rule := range rules {
+		text += fmt.Sprintf("%d. %s\n", i+1, rule)
+	}
+	return text
+}
+
+// structureMessage normalizes a message for commit.structured_none: a type prefix the model
+// added anyway is dropped, the subject is capitalized without a trailing period, and trailers
+// written at the end of the body become footers
+func structureMessage(msg CommitMessage) CommitMessage {
+	subject := strings.TrimSpace(msg.Subject)
+	if match := conventionalSubject.FindStringSubmatch(subject); match != nil && isConventionalType(match[1]) {
+		subject = strings.TrimSpace(match[3])
+	}
+	subject = strings.TrimRight(subject, ".")
+	if first, size := utf8.DecodeRuneInString(subject); first != utf8.RuneError {
+		subject = string(unicode.ToUpper(first)) + subject[size:]
+	}
+	msg.Subject = subject
+
+	body := strings.TrimSpace(normalizeNewlines(msg.Body))
+	if trailers := trailerParagraph(body); trailers != "" {
+		msg.Body = strings.TrimSpace(strings.TrimSuffix(body, trailers))
+		var footers []Footer
+		for _, line := range strings.Split(trailers, "\n") {
+			token, value, _ := strings.Cut(line, ": ")
+			footers = append(footers, Footer{Token: token, Value: value})
+		}
+		msg.Footers = append(footers, msg.Footers...)
+	}
+
+	// A footer git wouldn't read as a trailer would end up as a stray body line
+	var footers []Footer
+	for _, footer := range msg.Footers {
+		token := strings.TrimSpace(footer.Token)
+		if trailerLinePattern.MatchString(token + ": " + strings.TrimSpace(footer.Value)) {
+			footers = append(footers, footer)
+		}
+	}
+	msg.Footers = footers
+	return msg
+}
+
+// isConventionalType reports whether word is one of the conventional commit types
+func isConventionalType(word string) bool {
+	return containsString(config.ConventionalCommits.CommitTypes(), strings.ToLower(word))
+}
+
+// structuredWarnings checks a finished message against the commit.structured_none layout
+func structuredWarnings(subject, body string) []string {
+	var warnings []string
+	if match := conventionalSubject.FindStringSubmatch(subject); match != nil && isConventionalType(match[1]) {
+		warnings = append(warnings, "subject starts with a type prefix, which commit.structured_none leaves out")
+	}
+	if strings.HasSuffix(subject, ".") {
+		warnings = append(warnings, "subject ends with a period")
+	}
+	if body != "" && !strings.HasPrefix(body, "\n") {
+		warnings = append(warnings, "the body is not separated from the subject by a blank line")
+	}
+
+	// Trailers only count when the last paragraph holds nothing else
+	paragraphs := strings.Split(strings.TrimSpace(body), "\n\n")
+	last := strings.Split(strings.TrimSpace(paragraphs[len(paragraphs)-1]), "\n")
+	trailers := 0
+	for _, line := range last {
+		if trailerLinePattern.MatchString(line) {
+			trailers++
+		}
+	}
+	if trailers > 0 && trailers < len(last) {
+		warnings = append(warnings, "the last paragraph mixes trailers with other text, so git won't read them as trailers")
+	}
+	return warnings
+}
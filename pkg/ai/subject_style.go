@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// goPackage returns the Go package most of the changed .go files belong to, named the way Go
+// projects prefix subjects: by the last element of its directory ("pkg/ai" gives "ai"). Ties go
+// to the package changed first. It returns "" when no .go file changed or the root package wins,
+// since that has no short name of its own.
+func goPackage(files []string) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		dir := path.Dir(file)
+		if counts[dir] == 0 {
+			order = append(order, dir)
+		}
+		counts[dir]++
+	}
+
+	dominant := ""
+	for _, dir := range order {
+		if dominant == "" || counts[dir] > counts[dominant] {
+			dominant = dir
+		}
+	}
+	if dominant == "" || dominant == "." {
+		return ""
+	}
+	return path.Base(dominant)
+}
+
+// withSubjectPackage records the package prefix for commit.subject_style "go-package"
+func withSubjectPackage(cfg *config.Config, files []string) *config.Config {
+	if cfg.Commit.SubjectStyle != config.SubjectStyleGoPackage || cfg.Commit.Convention != config.NoConvention {
+		return cfg
+	}
+	pkg := goPackage(files)
+	if pkg == "" {
+		return cfg
+	}
+	debugPrint(cfg, "SUBJECT PACKAGE", pkg)
+	packageCfg := *cfg
+	packageCfg.Commit.SubjectPackage = pkg
+	return &packageCfg
+}
+
+// subjectPackagePrefix returns the "pkg: " written before the subject, or ""
+func subjectPackagePrefix(cfg *config.Config) string {
+	if cfg.Commit.SubjectPackage == "" {
+		return ""
+	}
+	return cfg.Commit.SubjectPackage + ": "
+}
+
+// packageSubject strips a package prefix the model wrote itself despite being told not to, and
+// starts the subject in lower case as Go subjects do ("ai: fix parsing"), leaving acronyms
+// and identifiers like "HTTP" or "JSONEncoder" alone
+func packageSubject(subject, pkg string) string {
+	if rest, ok := strings.CutPrefix(subject, pkg+":"); ok {
+		subject = strings.TrimSpace(rest)
+	}
+	first, size := utf8.DecodeRuneInString(subject)
+	second, _ := utf8.DecodeRuneInString(subject[size:])
+	if unicode.IsUpper(first) && !unicode.IsUpper(second) {
+		subject = string(unicode.ToLower(first)) + subject[size:]
+	}
+	return subject
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// subjectWordCount counts the words of a subject description (type and scope not included)
+func subjectWordCount(subject string) int {
+	return len(strings.Fields(subject))
+}
+
+// firstWords keeps the first n words of a subject, for commit.on_length_violation: truncate
+func firstWords(subject string, n int) string {
+	words := strings.Fields(subject)
+	if len(words) <= n {
+		return subject
+	}
+	return strings.TrimRight(strings.Join(words[:n], " "), " ,;:-")
+}
+
+// subjectWordsInstruction tells the model about commit.max_subject_words, or returns "" without a limit
+func subjectWordsInstruction(cfg *config.Config) string {
+	if cfg.Commit.MaxSubjectWords <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("CRITICAL: The subject (not counting the type and scope) MUST be at most %d words.", cfg.Commit.MaxSubjectWords)
+}
+
+// collapseRepeatedWords fixes the stutter models sometimes produce: a word repeated right
+// after itself ("add add support") is kept once and runs of spaces become one. Only words with
+// a letter are collapsed, so a version like "1 1" is left alone.
+func collapseRepeatedWords(subject string) string {
+	words := strings.Fields(subject)
+	var kept []string
+	for _, word := range words {
+		if len(kept) > 0 && strings.EqualFold(word, kept[len(kept)-1]) && strings.IndexFunc(word, unicode.IsLetter) >= 0 {
+			continue
+		}
+		kept = append(kept, word)
+	}
+	return strings.Join(kept, " ")
+}
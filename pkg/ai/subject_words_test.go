@@ -0,0 +1,111 @@
+package ai
+
+import "testing"
+
+func TestSubjectWordLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		subject   string
+		limit     int
+		collapsed string
+		words     int
+		truncated string
+	}{
+		{
+			name:      "within limit",
+			subject:   "add retry to the uploader",
+			limit:     6,
+			collapsed: "add retry to the uploader",
+			words:     5,
+			truncated: "add retry to the uploader",
+		},
+		{
+			name:      "at limit",
+			subject:   "add retry to the uploader",
+			limit:     5,
+			collapsed: "add retry to the uploader",
+			words:     5,
+			truncated: "add retry to the uploader",
+		},
+		{
+			name:      "over limit",
+			subject:   "add exponential backoff and jitter to the S3 uploader retries",
+			limit:     6,
+			collapsed: "add exponential backoff and jitter to the S3 uploader retries",
+			words:     10,
+			truncated: "add exponential backoff and jitter to",
+		},
+		{
+			name:      "stutter",
+			subject:   "add add support for for YAML config",
+			limit:     5,
+			collapsed: "add support for YAML config",
+			words:     7,
+			truncated: "add add support for for",
+		},
+		{
+			name:      "stutter in other case",
+			subject:   "Fix fix the parser",
+			limit:     8,
+			collapsed: "Fix the parser",
+			words:     4,
+			truncated: "Fix fix the parser",
+		},
+		{
+			name:      "extra spaces",
+			subject:   "  update   the   changelog  ",
+			limit:     2,
+			collapsed: "update the changelog",
+			words:     3,
+			truncated: "update the",
+		},
+		{
+			name:      "repeated numbers kept",
+			subject:   "bump go from 1 1 to 1 2",
+			limit:     10,
+			collapsed: "bump go from 1 1 to 1 2",
+			words:     8,
+			truncated: "bump go from 1 1 to 1 2",
+		},
+		{
+			name:      "trailing punctuation trimmed",
+			subject:   "refactor config loading, add validation and defaults",
+			limit:     3,
+			collapsed: "refactor config loading, add validation and defaults",
+			words:     7,
+			truncated: "refactor config loading",
+		},
+		{
+			name:      "trailing dash trimmed",
+			subject:   "rename handlers - clearer names for routes",
+			limit:     3,
+			collapsed: "rename handlers - clearer names for routes",
+			words:     7,
+			truncated: "rename handlers",
+		},
+		{
+			name:      "empty",
+			subject:   "",
+			limit:     3,
+			collapsed: "",
+			words:     0,
+			truncated: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collapseRepeatedWords(tt.subject); got != tt.collapsed {
+				t.Errorf("collapseRepeatedWords(%q) = %q, want %q", tt.subject, got, tt.collapsed)
+			}
+			if got := subjectWordCount(tt.subject); got != tt.words {
+				t.Errorf("subjectWordCount(%q) = %d, want %d", tt.subject, got, tt.words)
+			}
+			if got := firstWords(tt.subject, tt.limit); got != tt.truncated {
+				t.Errorf("firstWords(%q, %d) = %q, want %q", tt.subject, tt.limit, got, tt.truncated)
+			}
+			if got := subjectWordCount(firstWords(tt.subject, tt.limit)); got > tt.limit {
+				t.Errorf("firstWords(%q, %d) left %d words", tt.subject, tt.limit, got)
+			}
+		})
+	}
+}
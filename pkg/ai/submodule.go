@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/git"
+	"github.com/johnstilia/commitron/pkg/tokenizer"
+)
+
+// subprojectPattern matches the old and new pointer lines of a submodule in a diff
+var subprojectPattern = regexp.MustCompile(`(?m)^([-+])Subproject commit ([0-9a-f]{7,40})`)
+
+// ExpandSubmoduleDiffs appends the diff inside each updated submodule after its pointer change,
+// so the model sees what changed instead of a bare commit hash bump. Added and removed
+// submodules and ones that aren't checked out are left as they are. The expansions share
+// maxTokens. Returns the expanded diff and the number of submodules expanded.
+func ExpandSubmoduleDiffs(diff string, maxTokens int, model string) (string, int) {
+	if !strings.Contains(diff, "Subproject commit ") {
+		return diff, 0
+	}
+
+	var result strings.Builder
+
+	// Preserve anything that appears before the first file diff
+	if idx := strings.Index(diff, "diff --git"); idx > 0 {
+		result.WriteString(diff[:idx])
+	}
+
+	expanded, used := 0, 0
+	for _, fd := range ParseDiffByFile(diff) {
+		result.WriteString(fd.Content)
+
+		from, to := submodulePointers(fd.Content)
+		if from == "" || to == "" || used >= maxTokens {
+			continue
+		}
+		inner, err := git.SubmoduleDiff(fd.Path, from, to)
+		if err != nil || strings.TrimSpace(inner) == "" {
+			continue
+		}
+
+		inner = tokenizer.TruncateToTokenLimit(normalizeNewlines(inner), maxTokens-used, model)
+		used += tokenizer.CountTokens(inner, model)
+		if !strings.HasSuffix(fd.Content, "\n") {
+			result.WriteString("\n")
+		}
+		result.WriteString(strings.TrimSuffix(inner, "\n") + "\n")
+		expanded++
+	}
+
+	return result.String(), expanded
+}
+
+// submodulePointers returns the old and new commits of a submodule's diff section, or
+// empty strings when the section isn't a submodule update
+func submodulePointers(section string) (string, string) {
+	var from, to string
+	for _, match := range subprojectPattern.FindAllStringSubmatch(section, -1) {
+		if match[1] == "-" {
+			from = match[2]
+		} else {
+			to = match[2]
+		}
+	}
+	return from, to
+}
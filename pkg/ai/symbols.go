@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxListedSymbols caps the functions named per file in "Changes in:"
+const maxListedSymbols = 8
+
+// callableName matches an identifier followed by an opening parenthesis, as in a function
+// or method declaration, allowing Go type parameters in between ("Map[T any](")
+var callableName = regexp.MustCompile(`([A-Za-z_$][\w$]*)\s*(?:\[[^\]]*\])?\s*\(`)
+
+// typeName matches type-like declarations that have no parameter list
+var typeName = regexp.MustCompile(`\b(?:class|struct|interface|type|module|impl|enum|trait|object|namespace)\s+([A-Za-z_$][\w$]*)`)
+
+// declarationKeywords are words followed by "(" that aren't the declared name
+var declarationKeywords = map[string]bool{
+	"func": true, "function": true, "def": true, "fn": true, "sub": true,
+	"if": true, "elif": true, "for": true, "foreach": true, "while": true, "switch": true, "catch": true,
+	"return": true, "import": true, "var": true, "const": true, "let": true, "new": true,
+	"async": true, "await": true,
+}
+
+// hunkSymbol returns the function or type named in the context git prints after a hunk
+// header ("@@ -10,6 +10,8 @@ func (s *Server) Start() error {" gives "Start"), or ""
+// when the context isn't a recognizable declaration. Git picks that line with the
+// language's funcname rules, so this works across languages.
+func hunkSymbol(header string) string {
+	_, context, found := strings.Cut(strings.TrimPrefix(header, "@@"), "@@")
+	if !found {
+		return ""
+	}
+	context = strings.TrimSpace(context)
+	if context == "" {
+		return ""
+	}
+
+	for _, match := range callableName.FindAllStringSubmatch(context, -1) {
+		if !declarationKeywords[match[1]] {
+			return match[1]
+		}
+	}
+	if match := typeName.FindStringSubmatch(context); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// capSymbols joins at most maxListedSymbols names and notes how many were left out
+func capSymbols(names []string) string {
+	if len(names) <= maxListedSymbols {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedSymbols], ", "), len(names)-maxListedSymbols)
+}
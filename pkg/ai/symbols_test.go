@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHunkSymbol(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"go function", "@@ -10,6 +10,8 @@ func Parse(input string) error {", "Parse"},
+		{"go method", "@@ -10,6 +10,8 @@ func (s *Server) Start() error {", "Start"},
+		{"go generic function", "@@ -1,3 +1,4 @@ func Map[T any](items []T) []T {", "Map"},
+		{"go generic method", "@@ -1,3 +1,4 @@ func (s *Set[T]) Add(item T) {", "Add"},
+		{"go type", "@@ -20 +20,2 @@ type Server struct {", "Server"},
+		{"python method", "@@ -5,7 +5,9 @@ class Parser:\n", "Parser"},
+		{"python def", "@@ -5,7 +5,9 @@     def parse(self, text):", "parse"},
+		{"async python def", "@@ -5 +5 @@ async def fetch(url):", "fetch"},
+		{"javascript function", "@@ -1,4 +1,5 @@ export async function loadUser(id) {", "loadUser"},
+		{"javascript arrow function", "@@ -1,4 +1,5 @@ const loadUser = async (id) => {", ""},
+		{"javascript class", "@@ -3,2 +3,3 @@ export default class UserStore extends Store {", "UserStore"},
+		{"java method", "@@ -40,6 +40,7 @@ public static List<User> findAll(Connection conn) throws SQLException {", "findAll"},
+		{"rust function", "@@ -8,3 +8,4 @@ pub fn parse_line(line: &str) -> Option<Token> {", "parse_line"},
+		{"rust impl", "@@ -8,3 +8,4 @@ impl Display for Token {", "Display"},
+		{"c function", "@@ -100,7 +100,9 @@ static int read_config(const char *path)", "read_config"},
+		{"dollar identifier", "@@ -1 +1 @@ function $init() {", "$init"},
+		{"keyword only", "@@ -1,2 +1,3 @@ if (ready) {", ""},
+		{"no context", "@@ -1,2 +1,3 @@", ""},
+		{"blank context", "@@ -1,2 +1,3 @@   ", ""},
+		{"plain text context", "@@ -1,2 +1,3 @@ ## Installation", ""},
+		{"not a hunk header", "func Parse() {", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hunkSymbol(tt.header); got != tt.want {
+				t.Errorf("hunkSymbol(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffByFileSymbols(t *testing.T) {
+	diff := "diff --git a/server.go b/server.go\n" +
+		"--- a/server.go\n" +
+		"+++ b/server.go\n" +
+		"@@ -10,6 +10,8 @@ func (s *Server) Start() error {\n" +
+		"+\ts.ready = true\n" +
+		"@@ -30,6 +32,8 @@ func (s *Server) Stop() error {\n" +
+		"+\ts.ready = false\n" +
+		"@@ -50,2 +54,3 @@ func (s *Server) Start() error {\n" +
+		"+\treturn nil\n" +
+		"@@ -70,2 +75,3 @@\n" +
+		"+// trailing comment\n"
+
+	files := ParseDiffByFile(diff)
+	if len(files) != 1 {
+		t.Fatalf("ParseDiffByFile found %d files, want 1", len(files))
+	}
+	if want := []string{"Start", "Stop"}; !reflect.DeepEqual(files[0].Symbols, want) {
+		t.Errorf("symbols = %q, want %q", files[0].Symbols, want)
+	}
+
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	if got, want := capSymbols(names), "a, b, c, d, e, f, g, h and 2 more"; got != want {
+		t.Errorf("capSymbols = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxListedTests caps how many test names are listed per file and in the prompt
+const maxListedTests = 10
+
+// testNamePatterns recognize test declarations; the first capture group is the test name
+var testNamePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^func ((?:Test|Benchmark|Fuzz)\w*)\(`),                        // Go
+	regexp.MustCompile(`\bt\.Run\(\s*"([^"]+)"`),                                      // Go subtests
+	regexp.MustCompile("^\\s*(?:it|test)(?:\\.only|\\.skip)?\\(\\s*['\"`]([^'\"`]+)"), // Jest/Mocha
+	regexp.MustCompile(`^\s*(?:async\s+)?def (test_\w+)\(`),                           // pytest
+}
+
+// extractTestNames returns the tests declared on added and removed lines of a file diff.
+// Tests that appear on both sides were changed and are only reported as added.
+func extractTestNames(diff string) (added []string, removed []string) {
+	seenAdded := make(map[string]bool)
+	seenRemoved := make(map[string]bool)
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			continue
+		}
+
+		var isAdded bool
+		switch {
+		case strings.HasPrefix(line, "+"):
+			isAdded = true
+		case strings.HasPrefix(line, "-"):
+			isAdded = false
+		default:
+			continue
+		}
+
+		name := matchTestName(line[1:])
+		if name == "" {
+			continue
+		}
+		if isAdded && !seenAdded[name] {
+			seenAdded[name] = true
+			added = append(added, name)
+		} else if !isAdded && !seenRemoved[name] {
+			seenRemoved[name] = true
+			removed = append(removed, name)
+		}
+	}
+
+	var removedOnly []string
+	for _, name := range removed {
+		if !seenAdded[name] {
+			removedOnly = append(removedOnly, name)
+		}
+	}
+
+	return added, removedOnly
+}
+
+// matchTestName returns the test declared on a line of code, or ""
+func matchTestName(code string) string {
+	for _, pattern := range testNamePatterns {
+		if match := pattern.FindStringSubmatch(code); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// capTestNames joins at most maxListedTests names and notes how many were left out
+func capTestNames(names []string) string {
+	if len(names) <= maxListedTests {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedTests], ", "), len(names)-maxListedTests)
+}
+
+// testsSection lists the tests added, changed or removed per file for the prompt.
+// Returns "" when the diff touches no recognizable tests.
+func testsSection(changes string) string {
+	var section strings.Builder
+	listed := 0
+	for _, fd := range ParseDiffByFile(changes) {
+		added, removed := extractTestNames(fd.Content)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		if listed == maxListedTests {
+			section.WriteString("* ... more test files\n")
+			break
+		}
+		listed++
+
+		section.WriteString(fmt.Sprintf("* %s\n", fd.Path))
+		if len(added) > 0 {
+			section.WriteString(fmt.Sprintf("  Added/changed: %s\n", capTestNames(added)))
+		}
+		if len(removed) > 0 {
+			section.WriteString(fmt.Sprintf("  Removed: %s\n", capTestNames(removed)))
+		}
+	}
+
+	if section.Len() == 0 {
+		return ""
+	}
+	return "\nTests added/changed (worth naming in the body):\n" + section.String()
+}
@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// goTestDiff adds a test with subtests, changes one and removes another
+const goTestDiff = `diff --git a/parser_test.go b/parser_test.go
+--- a/parser_test.go
++++ b/parser_test.go
+@@ -10,12 +10,20 @@ import "testing"
+-func TestParseEmpty(t *testing.T) {
++func TestParseEmpty(t *testing.T) { // Now table driven
++	t.Run("nil input", func(t *testing.T) {})
++	t.Run( "blank input", func(t *testing.T) {})
+ }
+-func TestParseLegacy(t *testing.T) {
+-}
++func TestParseNested(t *testing.T) {
++}
++func BenchmarkParse(b *testing.B) {
++}
++func FuzzParse(f *testing.F) {
++}
++func helperNotATest(t *testing.T) {
++}
++// func TestInComment(t *testing.T) {
+`
+
+// jestTestDiff adds, skips and removes Jest specs with each quote style
+const jestTestDiff = "diff --git a/src/parser.test.js b/src/parser.test.js\n" +
+	"--- a/src/parser.test.js\n" +
+	"+++ b/src/parser.test.js\n" +
+	"@@ -1,10 +1,14 @@\n" +
+	" describe('parser', () => {\n" +
+	"-  it('parses numbers', () => {\n" +
+	"+  it('parses numbers', () => {\n" +
+	"+  test(\"parses strings\", async () => {\n" +
+	"+  it.only(`parses templates`, () => {\n" +
+	"+  test.skip('parses regexes', () => {\n" +
+	"-  it(\"handles null\", () => {\n" +
+	"+  const it = 'not a test'\n" +
+	"+  describe('nested', () => {})\n" +
+	" })\n"
+
+func TestExtractTestNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		diff    string
+		added   []string
+		removed []string
+	}{
+		{"go", goTestDiff, []string{"TestParseEmpty", "nil input", "blank input", "TestParseNested", "BenchmarkParse", "FuzzParse"}, []string{"TestParseLegacy"}},
+		{"jest", jestTestDiff, []string{"parses numbers", "parses strings", "parses templates", "parses regexes"}, []string{"handles null"}},
+		{"no tests", "diff --git a/a.go b/a.go\n+func Parse() {}\n", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := extractTestNames(tt.diff)
+			if !reflect.DeepEqual(added, tt.added) {
+				t.Errorf("added = %q, want %q", added, tt.added)
+			}
+			if !reflect.DeepEqual(removed, tt.removed) {
+				t.Errorf("removed = %q, want %q", removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestTestsSection(t *testing.T) {
+	want := `
+Tests added/changed (worth naming in the body):
+* parser_test.go
+  Added/changed: TestParseEmpty, nil input, blank input, TestParseNested, BenchmarkParse, FuzzParse
+  Removed: TestParseLegacy
+* src/parser.test.js
+  Added/changed: parses numbers, parses strings, parses templates, parses regexes
+  Removed: handles null
+`
+	if got := testsSection(goTestDiff + jestTestDiff); got != want {
+		t.Errorf("testsSection =\n%s\nwant\n%s", got, want)
+	}
+	if got := testsSection("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n+func Parse() {}\n"); got != "" {
+		t.Errorf("testsSection without tests = %q, want \"\"", got)
+	}
+
+	var many []string
+	for i := 0; i < maxListedTests+3; i++ {
+		many = append(many, string(rune('A'+i)))
+	}
+	if got := capTestNames(many); !strings.HasSuffix(got, "J and 3 more") {
+		t.Errorf("capTestNames = %q, want the first %d and a count", got, maxListedTests)
+	}
+}
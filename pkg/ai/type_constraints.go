@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/johnstilia/commitron/pkg/config"
+)
+
+// fileCategory groups changed files by the commit type they usually imply
+type fileCategory string
+
+const (
+	categoryDocs  fileCategory = "docs"
+	categoryTest  fileCategory = "test"
+	categoryCI    fileCategory = "ci"
+	categoryBuild fileCategory = "build"
+	categoryCode  fileCategory = "code"
+)
+
+// categoryDescriptions phrase each category for the prompt
+var categoryDescriptions = map[fileCategory]string{
+	categoryDocs:  "documentation",
+	categoryTest:  "tests",
+	categoryCI:    "CI configuration",
+	categoryBuild: "build files and dependencies",
+}
+
+// escapeHatchTypes stay allowed in a constrained set for housekeeping changes
+var escapeHatchTypes = []string{"chore", "build"}
+
+var testFilePattern = regexp.MustCompile(`(_test\.go|_test\.py|(^|/)test_[^/]*\.py|\.(test|spec)\.[jt]sx?)$`)
+
+var buildFileNames = map[string]bool{
+	"makefile":       true,
+	"dockerfile":     true,
+	"go.mod":         true,
+	"package.json":   true,
+	"pyproject.toml": true,
+	"setup.py":       true,
+	"cargo.toml":     true,
+	"pom.xml":        true,
+	"build.gradle":   true,
+	"cmakelists.txt": true,
+}
+
+// categorizeFile returns the category of a changed file
+func categorizeFile(path string) fileCategory {
+	lower := strings.ToLower(filepath.ToSlash(path))
+	base := filepath.Base(lower)
+	ext := filepath.Ext(lower)
+
+	switch {
+	case strings.HasPrefix(lower, ".github/workflows/"), strings.HasPrefix(lower, ".circleci/"),
+		base == ".gitlab-ci.yml", base == "jenkinsfile", base == ".travis.yml":
+		return categoryCI
+	case testFilePattern.MatchString(lower), strings.Contains("/"+lower, "/tests/"), strings.Contains("/"+lower, "/__tests__/"):
+		return categoryTest
+	case ext == ".md", ext == ".rst", ext == ".adoc", ext == ".txt" && base != "cmakelists.txt",
+		strings.HasPrefix(lower, "docs/"), base == "license", base == "changelog":
+		return categoryDocs
+	case buildFileNames[base], lockfileNames[filepath.Base(path)]:
+		return categoryBuild
+	}
+	return categoryCode
+}
+
+// constrainedCommitTypes returns the commit types that fit the changed files, or nil when
+// source code changed and every type remains possible. The escape hatches chore and build
+// are always included, and only types the convention allows are returned.
+func constrainedCommitTypes(files []string, cfg *config.Config) ([]string, []fileCategory) {
+	seen := make(map[fileCategory]bool)
+	var categories []fileCategory
+	for _, file := range files {
+		category := categorizeFile(file)
+		if category == categoryCode {
+			return nil, nil
+		}
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		return nil, nil
+	}
+
+	var types []string
+	for _, candidate := range append(categoryTypes(categories), escapeHatchTypes...) {
+		if isValidCommitType(candidate, cfg) && !containsString(types, candidate) {
+			types = append(types, candidate)
+		}
+	}
+	return types, categories
+}
+
+// categoryTypes maps file categories to the commit types of the same name
+func categoryTypes(categories []fileCategory) []string {
+	types := make([]string, 0, len(categories))
+	for _, category := range categories {
+		types = append(types, string(category))
+	}
+	return types
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// typeConstraintInstruction tells the model which types fit the changed files, or "" when unconstrained
+func typeConstraintInstruction(files []string, cfg *config.Config) string {
+	if !cfg.Commit.ConstrainTypeByFiles || !cfg.Commit.Convention.IsConventional() {
+		return ""
+	}
+
+	types, categories := constrainedCommitTypes(files, cfg)
+	if len(types) == 0 {
+		return ""
+	}
+
+	described := make([]string, 0, len(categories))
+	for _, category := range categories {
+		described = append(described, categoryDescriptions[category])
+	}
+	return fmt.Sprintf("Given only %s changed, the type MUST be one of: %s. Pick the one that fits best.",
+		strings.Join(described, " and "), strings.Join(types, ", "))
+}
+
+// typeMismatch returns why a commit type doesn't fit the changed files, such as feat when
+// only tests changed, or "" when it fits or nothing can be said because source code changed
+func typeMismatch(commitType string, files []string, cfg *config.Config) string {
+	if !cfg.Commit.Convention.IsConventional() || commitType == "" {
+		return ""
+	}
+
+	types, categories := constrainedCommitTypes(files, cfg)
+	if len(types) == 0 || containsString(types, commitType) {
+		return ""
+	}
+
+	described := make([]string, 0, len(categories))
+	for _, category := range categories {
+		described = append(described, categoryDescriptions[category])
+	}
+	return fmt.Sprintf("the type '%s' does not fit the changes: only %s changed, so the type should be one of: %s.",
+		commitType, strings.Join(described, " and "), strings.Join(types, ", "))
+}
+
+// CheckType returns a warning when the type of a formatted conventional message doesn't fit
+// the changed files (commit.verify_type_against_changes), or "" when it does
+func CheckType(message string, files []string, cfg *config.Config) string {
+	if cfg.Commit.VerifyTypeAgainstChanges == config.TypeVerifyOff {
+		return ""
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(normalizeNewlines(message)), "\n")
+	subject = strings.TrimPrefix(subject, expandSubjectTemplate(cfg.Commit.SubjectPrefix))
+	match := conventionalSubject.FindStringSubmatch(subject)
+	if match == nil {
+		return ""
+	}
+	return typeMismatch(match[1], files, cfg)
+}
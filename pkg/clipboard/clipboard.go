@@ -0,0 +1,49 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when no clipboard tool is installed
+var ErrUnavailable = errors.New("no clipboard tool found (pbcopy, wl-copy, xclip, xsel or clip)")
+
+// candidates returns the clipboard commands to try on this system, in order of preference.
+// wl-copy only comes first in a Wayland session, where xclip may reach the wrong clipboard.
+func candidates() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+	x11 := [][]string{{"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+	wayland := [][]string{{"wl-copy"}}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append(wayland, x11...)
+	}
+	return append(x11, wayland...)
+}
+
+// Copy puts text on the system clipboard using the first clipboard tool found
+func Copy(text string) error {
+	for _, candidate := range candidates() {
+		path, err := exec.LookPath(candidate[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, candidate[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		// No output pipes: xclip and xsel stay in the background to serve the selection,
+		// and waiting for their output would never end
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s failed: %w", candidate[0], err)
+		}
+		return nil
+	}
+	return ErrUnavailable
+}
@@ -1,12 +1,26 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/template"
 
+	"github.com/johnstilia/commitron/pkg/timing"
 	"gopkg.in/yaml.v3"
 )
 
+// Debug output formats for --log-format
+const (
+	// LogFormatText prints each debug entry as a framed block on stdout
+	LogFormatText = "text"
+	// LogFormatJSON prints each debug entry as one JSON object per line on stderr
+	LogFormatJSON = "json"
+)
+
 // CommitConvention represents the convention to use for commit messages
 type CommitConvention string
 
@@ -17,8 +31,153 @@ const (
 	ConventionalCommits CommitConvention = "conventional"
 	// CustomConvention follows a custom convention defined in config
 	CustomConvention CommitConvention = "custom"
+	// AngularConvention follows the Angular commit guidelines (conventional commits with a fixed type set and required scope)
+	AngularConvention CommitConvention = "angular"
+)
+
+// IsConventional reports whether the convention uses the type(scope): subject format
+func (c CommitConvention) IsConventional() bool {
+	return c == ConventionalCommits || c == AngularConvention
+}
+
+// CommitTypes returns the commit types allowed by the convention
+func (c CommitConvention) CommitTypes() []string {
+	if c == AngularConvention {
+		return []string{"build", "ci", "docs", "feat", "fix", "perf", "refactor", "test"}
+	}
+	return []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+}
+
+// ScopeRequirement controls whether conventional commits carry a scope
+type ScopeRequirement string
+
+const (
+	// ScopeOptional lets the model decide whether to include a scope
+	ScopeOptional ScopeRequirement = "optional"
+	// ScopeRequired demands a scope on every commit
+	ScopeRequired ScopeRequirement = "required"
+	// ScopeForbidden strips any scope from generated commits
+	ScopeForbidden ScopeRequirement = "forbidden"
+)
+
+// TruncateStrategy controls how an over-long commit body is shortened
+type TruncateStrategy string
+
+const (
+	// TruncateCut cuts the body at the maximum length
+	TruncateCut TruncateStrategy = "truncate"
+	// TruncateReprompt asks the model for a shorter body before falling back to cutting
+	TruncateReprompt TruncateStrategy = "reprompt"
+)
+
+// SubjectStyle is a preset for how the subject line is written
+type SubjectStyle string
+
+const (
+	// SubjectStylePlain leaves the subject to commit.convention
+	SubjectStylePlain SubjectStyle = "plain"
+	// SubjectStyleConventional writes conventional commit subjects ("type(scope): subject")
+	SubjectStyleConventional SubjectStyle = "conventional"
+	// SubjectStyleGoPackage prefixes the subject with the changed Go package, as Go projects do ("ai: fix parsing")
+	SubjectStyleGoPackage SubjectStyle = "go-package"
 )
 
+// TruncateMode controls how the "truncate" diff strategy shortens a diff over the token budget
+type TruncateMode string
+
+const (
+	// TruncateTail cuts the diff at the budget, dropping the files at the end
+	TruncateTail TruncateMode = "tail"
+	// TruncateProportional gives every file a share of the budget by priority and size
+	TruncateProportional TruncateMode = "proportional"
+)
+
+// LengthViolationPolicy controls what happens when the subject line exceeds max_length
+type LengthViolationPolicy string
+
+const (
+	// LengthTruncate shortens the subject to fit
+	LengthTruncate LengthViolationPolicy = "truncate"
+	// LengthRetry asks the model for a shorter subject, failing after max_retries attempts
+	LengthRetry LengthViolationPolicy = "retry"
+	// LengthError aborts and shows the overlong message
+	LengthError LengthViolationPolicy = "error"
+)
+
+// Truncates reports whether an overlong subject is shortened; unknown values keep the default
+func (p LengthViolationPolicy) Truncates() bool {
+	return p != LengthRetry && p != LengthError
+}
+
+// TypeVerification controls what happens when the commit type doesn't fit the changed files
+type TypeVerification string
+
+const (
+	// TypeVerifyOff skips the check
+	TypeVerifyOff TypeVerification = "off"
+	// TypeVerifyWarn prints a warning and keeps the message
+	TypeVerifyWarn TypeVerification = "warn"
+	// TypeVerifyEnforce asks the model again, failing after max_retries attempts
+	TypeVerifyEnforce TypeVerification = "enforce"
+)
+
+// NonInteractiveAction is what the confirmation prompt does when nobody answers in time
+type NonInteractiveAction string
+
+const (
+	// ActionReject cancels the commit
+	ActionReject NonInteractiveAction = "reject"
+	// ActionAccept commits the message as shown
+	ActionAccept NonInteractiveAction = "accept"
+)
+
+// AttributionTrailer is commit.attribution_trailer: false (""), true for
+// DefaultAttributionTrailer, or a trailer template
+type AttributionTrailer string
+
+// DefaultAttributionTrailer is the trailer appended when commit.attribution_trailer is true
+const DefaultAttributionTrailer AttributionTrailer = "Generated-by: commitron/{{version}} ({{model}})"
+
+// DefaultContextFile is the repository file whose content is added to every prompt
+const DefaultContextFile = ".commitron-context.md"
+
+// DefaultIssuePattern matches GitHub-style "#123" references and issue, pull request or
+// merge request URLs
+const DefaultIssuePattern = `(https?://[^\s"'<>()\[\]]+/(?:issues|pull|merge_requests)/\d+|\B#\d+\b)`
+
+// UnmarshalYAML accepts a boolean or a template string
+func (a *AttributionTrailer) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!bool" {
+		var enabled bool
+		if err := node.Decode(&enabled); err != nil {
+			return err
+		}
+		*a = ""
+		if enabled {
+			*a = DefaultAttributionTrailer
+		}
+		return nil
+	}
+
+	var template string
+	if err := node.Decode(&template); err != nil {
+		return err
+	}
+	*a = AttributionTrailer(template)
+	return nil
+}
+
+// MarshalYAML writes the default trailer and an empty one back as booleans
+func (a AttributionTrailer) MarshalYAML() (interface{}, error) {
+	switch a {
+	case "":
+		return false, nil
+	case DefaultAttributionTrailer:
+		return true, nil
+	}
+	return string(a), nil
+}
+
 // AIProvider represents the AI service to use
 type AIProvider string
 
@@ -37,47 +196,136 @@ const (
 type Config struct {
 	// AI provider configuration
 	AI struct {
-		Provider       AIProvider `yaml:"provider"`
-		APIKey         string     `yaml:"api_key"`
-		Model          string     `yaml:"model"`
-		OllamaHost     string     `yaml:"ollama_host,omitempty"`
-		OpenAIEndpoint string     `yaml:"openai_endpoint,omitempty"` // Custom OpenAI API endpoint
-		Temperature    float64    `yaml:"temperature"`
-		SystemPrompt   string     `yaml:"system_prompt"`
-		Debug          bool       `yaml:"debug,omitempty"`      // When true, prints debug info about AI requests
-		MaxTokens      int        `yaml:"max_tokens,omitempty"` // Maximum tokens to generate in response
+		Provider              AIProvider         `yaml:"provider"`
+		APIKey                string             `yaml:"api_key"`
+		Model                 string             `yaml:"model"`
+		OllamaHost            string             `yaml:"ollama_host,omitempty"`
+		OpenAIEndpoint        string             `yaml:"openai_endpoint,omitempty"` // Custom OpenAI API endpoint
+		Endpoints             []string           `yaml:"endpoints,omitempty"`       // Mirror endpoints used round-robin with failover (OpenAI-compatible URLs or Ollama hosts)
+		Temperature           float64            `yaml:"temperature"`
+		SystemPrompt          string             `yaml:"system_prompt"`
+		SystemPromptFile      string             `yaml:"system_prompt_file,omitempty"`      // text/template file rendered as the system prompt (instead of system_prompt)
+		PromptTemplateFile    string             `yaml:"prompt_template_file,omitempty"`    // text/template file rendered instead of the built-in prompt
+		Debug                 bool               `yaml:"debug,omitempty"`                   // When true, prints debug info about AI requests
+		MaxTokens             int                `yaml:"max_tokens,omitempty"`              // Maximum tokens to generate in response
+		MaxTokensByModel      map[string]int     `yaml:"max_tokens_by_model,omitempty"`     // max_tokens per model name, glob pattern or provider (falls back to max_tokens)
+		SummaryModel          string             `yaml:"summary_model,omitempty"`           // Model used to condense batches of large diffs (empty = local summaries only)
+		SummaryProvider       AIProvider         `yaml:"summary_provider,omitempty"`        // Provider for summary_model (empty = same as provider)
+		Stream                bool               `yaml:"stream,omitempty"`                  // Stream tokens to stderr while the response is generated (OpenAI-compatible)
+		DisableLengthPreamble bool               `yaml:"disable_length_preamble,omitempty"` // Skip the subject length instructions injected before every prompt
+		StripPatterns         []string           `yaml:"strip_patterns,omitempty"`          // Extra regular expressions removed from responses before parsing (reasoning blocks are always removed)
+		PromptTemplate        *template.Template `yaml:"-"`                                 // Parsed prompt_template_file (set by LoadPromptTemplates)
+		SystemPromptTemplate  *template.Template `yaml:"-"`                                 // Parsed system_prompt_file (set by LoadPromptTemplates)
 	} `yaml:"ai"`
 
 	// Commit message configuration
 	Commit struct {
-		Convention     CommitConvention `yaml:"convention"`
-		IncludeBody    bool             `yaml:"include_body"`
-		MaxLength      int              `yaml:"max_length"`
-		MaxBodyLength  int              `yaml:"max_body_length"` // Maximum length for the commit body
-		CustomTemplate string           `yaml:"custom_template,omitempty"`
+		Convention               CommitConvention      `yaml:"convention"`
+		SubjectStyle             SubjectStyle          `yaml:"subject_style"` // Subject preset: "plain", "conventional" or "go-package" (sets commit.convention unless "plain")
+		IncludeBody              bool                  `yaml:"include_body"`
+		MaxLength                int                   `yaml:"max_length"`
+		MaxBodyLength            int                   `yaml:"max_body_length"` // Maximum length for the commit body
+		CustomTemplate           string                `yaml:"custom_template,omitempty"`
+		StructuredNone           bool                  `yaml:"structured_none"`                     // With convention "none", still write a capitalized subject, a bulleted body and trailers, just without a type prefix
+		Scope                    ScopeRequirement      `yaml:"scope"`                               // Scope requirement for conventional commits: "required", "optional", "forbidden"
+		MaxRetries               int                   `yaml:"max_retries"`                         // Maximum regeneration attempts when a response violates a hard requirement
+		LearnScopesFromHistory   bool                  `yaml:"learn_scopes_from_history,omitempty"` // Suggest a scope from past commits when the model omits one
+		HistorySkipPrefixes      []string              `yaml:"history_skip_prefixes"`               // Subject prefixes of past commits ignored when learning from history, e.g. "wip" or "fixup!" (case-insensitive)
+		SubjectPrefix            string                `yaml:"subject_prefix,omitempty"`            // Template prepended to the subject ({{ticket}}, {{branch}}, {{date}})
+		SubjectSuffix            string                `yaml:"subject_suffix,omitempty"`            // Template appended to the subject ({{ticket}}, {{branch}}, {{date}})
+		TruncateStrategy         TruncateStrategy      `yaml:"truncate_strategy"`                   // How to shorten an over-long body: "truncate" or "reprompt"
+		QuickMode                bool                  `yaml:"quick_mode"`                          // Subject-only message from a minimal prompt and a small diff budget
+		FormatCommand            string                `yaml:"format_command,omitempty"`            // Shell command that receives the message on stdin and prints the final message
+		RecordModel              bool                  `yaml:"record_model"`                        // Append a "Commitron-Model: provider/model" trailer
+		Glossary                 map[string]string     `yaml:"glossary,omitempty"`                  // Project terms and their meanings, added to the prompt as "Project terminology"
+		Examples                 []string              `yaml:"examples,omitempty"`                  // Ideal commit messages from the project, added to the prompt as few-shot examples
+		AttributionTrailer       AttributionTrailer    `yaml:"attribution_trailer"`                 // false, true or a trailer template ({{provider}}, {{model}}, {{version}}) marking AI-assisted commits
+		OnLengthViolation        LengthViolationPolicy `yaml:"on_length_violation"`                 // What to do with a subject over max_length: "truncate", "retry" or "error"
+		MaxSubjectWords          int                   `yaml:"max_subject_words"`                   // Cap on words in the subject, not counting type and scope; handled like max_length (0 = no limit)
+		CollapseRepeatedWords    bool                  `yaml:"collapse_repeated_words"`             // Collapse stuttered words ("add add support") and extra spaces in generated subjects
+		ConstrainTypeByFiles     bool                  `yaml:"constrain_type_by_files"`             // Limit the commit types offered when only docs, tests, CI or build files changed
+		VerifyTypeAgainstChanges TypeVerification      `yaml:"verify_type_against_changes"`         // Check the generated type against the changed files: "off", "warn" or "enforce"
+		MaxTotalLength           int                   `yaml:"max_total_length"`                    // Ceiling on the whole formatted message (subject, body and footers); only the body is shortened (0 = no limit)
+		AllowRawFallback         bool                  `yaml:"allow_raw_fallback"`                  // Offer a response that couldn't be parsed for confirmation instead of failing
+		MergeSummary             bool                  `yaml:"merge_summary"`                       // List the merged commits in the body of merge commits
+		DetectReverts            bool                  `yaml:"detect_reverts"`                      // Recognize staged changes that undo a recent commit and write a revert message (costs a diff per commit searched)
+		AutoRefs                 bool                  `yaml:"auto_refs"`                           // Add a "Refs:" footer for the issues referenced on added lines (context.issue_pattern)
+		SubjectPackage           string                `yaml:"-"`                                   // Package the subject is prefixed with for subject_style "go-package" (set from the changed .go files)
 	} `yaml:"commit"`
 
 	// Additional context to provide to the AI
 	Context struct {
-		IncludeFileNames     bool   `yaml:"include_file_names"`                 // Include file names in the context
-		IncludeDiff          bool   `yaml:"include_diff"`                       // Include the diff in the context
-		MaxContextLength     int    `yaml:"max_context_length"`                 // Maximum length for the context (deprecated, use MaxInputTokens)
-		IncludeFileStats     bool   `yaml:"include_file_stats"`                 // Include stats about file changes (+/- lines)
-		IncludeFileSummaries bool   `yaml:"include_file_summaries"`             // Include brief description of what each file does
-		ShowFirstLinesOfFile int    `yaml:"show_first_lines_of_file,omitempty"` // Show first N lines of each file for better context
-		IncludeRepoStructure bool   `yaml:"include_repo_structure,omitempty"`   // Include high-level repo structure
-		MaxInputTokens       int    `yaml:"max_input_tokens,omitempty"`         // Maximum tokens for input context (replaces MaxContextLength)
-		DiffStrategy         string `yaml:"diff_strategy,omitempty"`            // Strategy for handling large diffs: "auto", "summarize", "batch", "truncate"
-		TokenizerModel       string `yaml:"tokenizer_model,omitempty"`          // Model to use for token counting (empty = use AI model)
-		SummarizationEnabled bool   `yaml:"summarization_enabled,omitempty"`    // Enable smart diff summarization
+		IncludeFileNames     bool         `yaml:"include_file_names"`                 // Include file names in the context
+		IncludeDiff          bool         `yaml:"include_diff"`                       // Include the diff in the context
+		MaxContextLength     int          `yaml:"max_context_length"`                 // Maximum length for the context (deprecated, use MaxInputTokens)
+		IncludeFileStats     bool         `yaml:"include_file_stats"`                 // Include stats about file changes (+/- lines)
+		IncludeFileSummaries bool         `yaml:"include_file_summaries"`             // Include brief description of what each file does
+		ShowFirstLinesOfFile int          `yaml:"show_first_lines_of_file,omitempty"` // Show first N lines of each file for better context
+		IncludeRepoStructure bool         `yaml:"include_repo_structure,omitempty"`   // Include high-level repo structure
+		DirectoryGroupDepth  int          `yaml:"directory_group_depth"`              // Path components used to group changed files by directory in the prompt (0 = no grouping)
+		MaxInputTokens       int          `yaml:"max_input_tokens,omitempty"`         // Maximum tokens for input context (replaces MaxContextLength)
+		DiffStrategy         string       `yaml:"diff_strategy,omitempty"`            // Strategy for handling large diffs: "auto", "summarize", "batch", "truncate"
+		TruncateMode         TruncateMode `yaml:"truncate_mode"`                      // How "truncate" shortens the diff: "tail" or "proportional" (every file keeps a share)
+		TokenEstimator       string       `yaml:"token_estimator"`                    // How tokens are counted: "tiktoken", or the "chars" or "words" estimates that need no encoding files
+		TokenizerModel       string       `yaml:"tokenizer_model,omitempty"`          // Model to use for token counting (empty = use AI model)
+		SummarizationEnabled bool         `yaml:"summarization_enabled,omitempty"`    // Enable smart diff summarization
+		SummarizeLockfiles   bool         `yaml:"summarize_lockfiles"`                // Replace lockfile diffs (go.sum, yarn.lock, ...) with one-line summaries
+		StripDiffMetadata    bool         `yaml:"strip_diff_metadata"`                // Remove index/mode header lines from the diff
+		MaxDiffLineLength    int          `yaml:"max_diff_line_length"`               // Cap on bytes per diff line, e.g. minified files (0 = no limit)
+		DetectMoves          bool         `yaml:"detect_moves"`                       // Annotate identical blocks removed from one file and added to another as moves
+		ExpandSubmoduleDiffs bool         `yaml:"expand_submodule_diffs"`             // Add the diff inside updated submodules between the old and new commits
+		MaxTokensPerFile     int          `yaml:"max_tokens_per_file"`                // Cap on one file's share of the diff budget; larger files are summarized (0 = 25% of the budget)
+		IncludeBranchName    bool         `yaml:"include_branch_name"`                // Add the current branch name to the prompt (skipped on detached HEAD and default branches)
+		ExcludePaths         []string     `yaml:"exclude_paths,omitempty"`            // gitignore-style patterns for files whose changes are never sent to the AI (added to .commitronignore)
+		IssuePattern         string       `yaml:"issue_pattern"`                      // Regular expression for issue references on added lines, listed in the prompt (first group is the reference; "" = off)
+		HighlightDeletions   bool         `yaml:"highlight_deletions"`                // List deleted files in the prompt ("Deleted: ...") so the message mentions them
+		ContextFile          string       `yaml:"context_file"`                       // File whose content is author context in every prompt, relative to the repository root ("" = off)
+		RedactPatterns       []string     `yaml:"redact_patterns,omitempty"`          // Regular expressions whose matches are replaced with [REDACTED] in everything sent to the AI (the commit keeps the real content)
+		DiffBase             string       `yaml:"-"`                                  // Revision the staged diff is taken against (set by --amend)
+		UserContext          string       `yaml:"-"`                                  // Extra context from the author, e.g. why the change was made (set by --context)
+		FileContext          string       `yaml:"-"`                                  // Content of context.context_file (read unless --ignore-context-file)
+		Intent               string       `yaml:"-"`                                  // The author's reason for the change, which the body is written around (set by --interactive-body)
+		UseProvidedDiff      bool         `yaml:"-"`                                  // Use the changes passed in instead of the staged diff (set by --from-patch)
+		SquashedSubjects     []string     `yaml:"-"`                                  // Subjects of the commits being squashed into one (set by --squash-range)
+		DeletedFiles         []string     `yaml:"-"`                                  // Files the staged diff deletes, for context.highlight_deletions (set during generation)
 	} `yaml:"context"`
 
+	// Git repository configuration
+	Git struct {
+		ProtectedBranches []string `yaml:"protected_branches"` // Branches (globs like "release/*") that need confirmation before committing
+	} `yaml:"git"`
+
+	// Git hook configuration
+	Hook struct {
+		AugmentExisting bool `yaml:"augment_existing"` // Append the generated body to a message that was already provided
+	} `yaml:"hook"`
+
+	// Generation history configuration
+	History struct {
+		Enabled bool   `yaml:"enabled"`        // Record each generation locally (used by "commitron stats")
+		Path    string `yaml:"path,omitempty"` // History file (empty = $XDG_STATE_HOME/commitron/history.jsonl)
+	} `yaml:"history"`
+
 	// User interface configuration
 	UI struct {
-		EnableTUI         bool `yaml:"enable_tui"`          // Enable TUI for better visualization
-		ConfirmCommit     bool `yaml:"confirm_commit"`      // Ask for confirmation before committing
-		DisplayFilesLimit int  `yaml:"display_files_limit"` // Maximum files to display in the UI (0 = no limit)
+		EnableTUI             bool                 `yaml:"enable_tui"`              // Enable TUI for better visualization
+		ConfirmCommit         bool                 `yaml:"confirm_commit"`          // Ask for confirmation before committing
+		AutoStage             bool                 `yaml:"auto_stage"`              // Stage modified tracked files before generating (same as --stage-all)
+		AlwaysEdit            bool                 `yaml:"always_edit"`             // Open the generated message in the editor before committing
+		DisplayFilesLimit     int                  `yaml:"display_files_limit"`     // Maximum files to display in the UI (0 = no limit)
+		ShowDiffPreview       bool                 `yaml:"show_diff_preview"`       // Show a colorized preview of the staged diff above the message
+		DiffPreviewLines      int                  `yaml:"diff_preview_lines"`      // Maximum diff lines in the preview (0 = no limit)
+		ShowCoverage          bool                 `yaml:"show_coverage"`           // At the confirmation prompt, list which staged files the message mentions
+		ConfirmTimeoutSeconds int                  `yaml:"confirm_timeout_seconds"` // Answer the confirmation prompt with non_interactive_action after this long (0 = wait forever)
+		NonInteractiveAction  NonInteractiveAction `yaml:"non_interactive_action"`  // What a confirmation timeout does: "reject" or "accept"
 	} `yaml:"ui"`
+
+	// Runtime state set from the command line, never read from the file
+	Ctx           context.Context   `yaml:"-"` // Deadline for the whole run (set by --timeout)
+	Timings       *timing.Recorder  `yaml:"-"` // Time spent per pipeline stage (reported by --verbose)
+	Sources       map[string]string `yaml:"-"` // File or git config key each setting was read from, by dotted key
+	LogFormat     string            `yaml:"-"` // Debug output format, LogFormatText or LogFormatJSON (set by --log-format)
+	Deterministic bool              `yaml:"-"` // Temperature 0, a fixed seed and no timestamps or timings, for reproducible output (set by --deterministic)
 }
 
 // DefaultConfig returns the default configuration
@@ -95,9 +343,18 @@ func DefaultConfig() *Config {
 
 	// Default commit settings
 	cfg.Commit.Convention = NoConvention
+	cfg.Commit.SubjectStyle = SubjectStylePlain
 	cfg.Commit.IncludeBody = true
 	cfg.Commit.MaxLength = 120
 	cfg.Commit.MaxBodyLength = 1000 // Default maximum body length
+	cfg.Commit.Scope = ScopeOptional
+	cfg.Commit.MaxRetries = 2
+	cfg.Commit.TruncateStrategy = TruncateCut
+	cfg.Commit.OnLengthViolation = LengthTruncate
+	cfg.Commit.CollapseRepeatedWords = true
+	cfg.Commit.VerifyTypeAgainstChanges = TypeVerifyWarn
+	cfg.Commit.AllowRawFallback = true
+	cfg.Commit.HistorySkipPrefixes = []string{"wip", "fixup!", "squash!", "amend!"}
 
 	// Default context settings
 	cfg.Context.IncludeFileNames = true
@@ -107,105 +364,191 @@ func DefaultConfig() *Config {
 	cfg.Context.IncludeFileSummaries = false
 	cfg.Context.ShowFirstLinesOfFile = 0
 	cfg.Context.IncludeRepoStructure = false
-	cfg.Context.MaxInputTokens = 100000          // 100K tokens (safe under most model limits)
-	cfg.Context.DiffStrategy = "auto"            // Auto-select strategy based on size
-	cfg.Context.TokenizerModel = ""              // Empty = use cfg.AI.Model
+	cfg.Context.DirectoryGroupDepth = 1
+	cfg.Context.MaxInputTokens = 100000 // 100K tokens (safe under most model limits)
+	cfg.Context.DiffStrategy = "auto"   // Auto-select strategy based on size
+	cfg.Context.TokenizerModel = ""     // Empty = use cfg.AI.Model
+	cfg.Context.TruncateMode = TruncateTail
+	cfg.Context.TokenEstimator = "tiktoken"
 	cfg.Context.SummarizationEnabled = true
+	cfg.Context.SummarizeLockfiles = true
+	cfg.Context.StripDiffMetadata = true
+	cfg.Context.MaxDiffLineLength = 1000
+	cfg.Context.IssuePattern = DefaultIssuePattern
+	cfg.Context.HighlightDeletions = true
+	cfg.Context.ContextFile = DefaultContextFile
+
+	// Default git settings
+	cfg.Git.ProtectedBranches = []string{"main", "master"}
 
 	// Default UI settings
 	cfg.UI.EnableTUI = true
-	cfg.UI.ConfirmCommit = true
+	cfg.UI.ConfirmCommit = false
+	cfg.UI.AutoStage = false
 	cfg.UI.DisplayFilesLimit = 20
+	cfg.UI.DiffPreviewLines = 40
+	cfg.UI.NonInteractiveAction = ActionReject
 
 	return cfg
 }
 
 // ParseConfig parses a configuration from YAML data
+// Unknown keys are reported like in LoadConfigFromPaths
 func ParseConfig(data []byte) (*Config, error) {
 	cfg := DefaultConfig()
+	cfg.Sources = map[string]string{}
 
 	// Parse YAML
-	err := yaml.Unmarshal(data, cfg)
+	unknown, err := cfg.layer(data, "")
 	if err != nil {
 		return nil, err
 	}
 
+	cfg.applyConventionDefaults()
+
+	if len(unknown) > 0 {
+		return cfg, &UnknownKeysError{Keys: unknown}
+	}
 	return cfg, nil
 }
 
-// LoadConfig loads the configuration from ~/.commitronrc
-func LoadConfig() (*Config, error) {
-	// Get user's home directory
+// applyConventionDefaults adjusts settings that depend on the commit convention
+func (c *Config) applyConventionDefaults() {
+	// A subject style other than "plain" picks the convention it is built on
+	switch c.Commit.SubjectStyle {
+	case SubjectStyleConventional:
+		if !c.Commit.Convention.IsConventional() {
+			c.Commit.Convention = ConventionalCommits
+		}
+	case SubjectStyleGoPackage:
+		c.Commit.Convention = NoConvention
+	}
+
+	// Angular always requires a scope unless scopes are explicitly forbidden
+	if c.Commit.Convention == AngularConvention && c.Commit.Scope == ScopeOptional {
+		c.Commit.Scope = ScopeRequired
+	}
+}
+
+// ConfigSearchPaths returns the default configuration file locations in the order they are checked:
+// $XDG_CONFIG_HOME/commitron/config.yaml, ~/.config/commitron/config.yaml, then ~/.commitronrc
+func ConfigSearchPaths() ([]string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return DefaultConfig(), err
+		return nil, err
+	}
+
+	var paths []string
+	if xdgHome := os.Getenv("XDG_CONFIG_HOME"); xdgHome != "" {
+		paths = append(paths, filepath.Join(xdgHome, "commitron", "config.yaml"))
 	}
+	paths = append(paths,
+		filepath.Join(homeDir, ".config", "commitron", "config.yaml"),
+		filepath.Join(homeDir, ".commitronrc"), // Legacy location kept for backward compatibility
+	)
 
-	configPath := filepath.Join(homeDir, ".commitronrc")
-	return LoadConfigFromPath(configPath)
+	return paths, nil
 }
 
-// LoadConfigFromPath loads configuration from a specified path
-// If the file doesn't exist, returns default configuration
-func LoadConfigFromPath(configPath string) (*Config, error) {
-	cfg := DefaultConfig()
+// DefaultConfigPath returns the XDG location where new configuration files are created
+func DefaultConfigPath() (string, error) {
+	paths, err := ConfigSearchPaths()
+	if err != nil {
+		return "", err
+	}
+	return paths[0], nil
+}
 
-	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// No config file, just return defaults
-		return cfg, nil
+// LegacyConfigPath returns the legacy ~/.commitronrc location
+func LegacyConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
 	}
+	return filepath.Join(homeDir, ".commitronrc"), nil
+}
 
-	// Read config file
-	data, err := os.ReadFile(configPath)
+// LoadConfig loads the configuration from the first existing file in ConfigSearchPaths
+func LoadConfig() (*Config, error) {
+	paths, err := ConfigSearchPaths()
 	if err != nil {
-		return cfg, err
+		return DefaultConfig(), err
 	}
 
-	return ParseConfig(data)
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return LoadConfigFromPath(path)
+		}
+	}
+
+	// No config file, just return defaults
+	return DefaultConfig(), nil
 }
 
-// SaveExampleConfig saves an example configuration to the given path
-func SaveExampleConfig(path string) error {
-	cfg := DefaultConfig()
+// LoadConfigFromPath loads configuration from a specified path
+// If the file doesn't exist, returns default configuration
+func LoadConfigFromPath(configPath string) (*Config, error) {
+	return LoadConfigFromPaths([]string{configPath})
+}
 
-	// Add some example values
-	cfg.AI.Provider = OpenAI
-	cfg.AI.APIKey = "your-api-key-here"
-	cfg.AI.Model = "gpt-3.5-turbo"
-	cfg.AI.Temperature = 0.7 // Example temperature value
-	cfg.AI.Debug = false     // Set to true to see AI prompts and responses
-	cfg.AI.MaxTokens = 4000  // Maximum response tokens
+// PlaceholderAPIKey is the api_key written by SaveExampleConfig
+const PlaceholderAPIKey = "your-api-key-here"
 
-	// Example of a custom system prompt (commented out by default)
-	cfg.AI.SystemPrompt = "# Custom system prompt (uncomment to use)\n# You are an expert developer who writes clear, concise commit messages.\n# Always follow the conventional commits format and be specific."
+// ErrAPIKeyNotSet is returned when the provider needs an API key and none, or only a placeholder, is configured
+var ErrAPIKeyNotSet = errors.New("no API key is configured")
 
-	cfg.Commit.Convention = ConventionalCommits
-	cfg.Commit.CustomTemplate = "{{type}}({{scope}}): {{subject}}"
+// placeholderKeys are api_key values copied from examples rather than real keys, compared
+// without regard to case
+var placeholderKeys = []string{PlaceholderAPIKey, "your-api-key", "your_api_key", "your-openai-api-key", "api-key-here", "changeme", "<api-key>", "<your-api-key>"}
 
-	// Set example context values
-	cfg.Context.IncludeFileStats = false
-	cfg.Context.IncludeFileSummaries = false
-	cfg.Context.ShowFirstLinesOfFile = 0
-	cfg.Context.IncludeRepoStructure = false
+// isPlaceholderKey reports whether key is a known placeholder, or an obvious one: all x's or
+// a single <...> placeholder
+func isPlaceholderKey(key string) bool {
+	for _, placeholder := range placeholderKeys {
+		if strings.EqualFold(key, placeholder) {
+			return true
+		}
+	}
+	if strings.Trim(strings.ToLower(key), "x") == "" {
+		return true
+	}
+	return strings.HasPrefix(key, "<") && strings.HasSuffix(key, ">") && !strings.ContainsAny(key[1:len(key)-1], "<>")
+}
 
-	// Set example UI values
-	cfg.UI.EnableTUI = true
-	cfg.UI.ConfirmCommit = true
-	cfg.UI.DisplayFilesLimit = 20
+// apiKeyPrefixes are the usual key prefixes of providers with a fixed endpoint
+var apiKeyPrefixes = map[AIProvider]string{
+	OpenAI: "sk-",
+	Claude: "sk-ant-",
+	Gemini: "AIza",
+}
 
-	// Marshal to YAML
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return err
+// CheckAPIKey returns ErrAPIKeyNotSet when the provider needs a key and it is missing or a
+// placeholder, and a warning when the key doesn't look like one of the provider's keys.
+// The format check is only a heuristic, so it never fails on its own.
+func (c *Config) CheckAPIKey() (string, error) {
+	if c.AI.Provider == Ollama {
+		return "", nil
 	}
 
-	// Add comments to the YAML
-	yamlWithComments := `# Commitron configuration file
-# This file configures the behavior of the commitron tool
+	// OpenAI-compatible servers (LocalAI, vLLM, proxies) may use any key or none at all
+	customEndpoint := c.AI.Provider == OpenAI &&
+		(len(c.AI.Endpoints) > 0 || (c.AI.OpenAIEndpoint != "" && !strings.Contains(c.AI.OpenAIEndpoint, "api.openai.com")))
+
+	if customEndpoint {
+		return "", nil
+	}
 
-` + string(data)
+	key := strings.TrimSpace(c.AI.APIKey)
+	if key == "" {
+		return "", fmt.Errorf("%w for the %s provider", ErrAPIKeyNotSet, c.AI.Provider)
+	}
+	// The key itself is never echoed: a real key must not end up in terminals or logs
+	if isPlaceholderKey(key) {
+		return "", fmt.Errorf("%w: api_key is still the placeholder from the example configuration", ErrAPIKeyNotSet)
+	}
 
-	// Write to file
-	return os.WriteFile(path, []byte(yamlWithComments), 0644)
+	if prefix, ok := apiKeyPrefixes[c.AI.Provider]; ok && !strings.HasPrefix(key, prefix) {
+		return fmt.Sprintf("the API key doesn't look like a %s key (they usually start with %q)", c.AI.Provider, prefix), nil
+	}
+	return "", nil
 }
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckAPIKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider AIProvider
+		key      string
+		endpoint string
+		wantErr  bool
+		warns    bool
+	}{
+		{"real OpenAI key", OpenAI, "sk-proj-abc123", "", false, false},
+		{"empty", OpenAI, "", "", true, false},
+		{"blank", Claude, "   ", "", true, false},
+		{"init placeholder", OpenAI, PlaceholderAPIKey, "", true, false},
+		{"placeholder in capitals", Gemini, "YOUR-API-KEY-HERE", "", true, false},
+		{"all x's", Claude, "xxxxxxxxxxxx", "", true, false},
+		{"angle brackets", OpenAI, "<your openai key>", "", true, false},
+		{"changeme", OpenAI, "changeme", "", true, false},
+		{"real key containing xxxx", OpenAI, "sk-abcxxxxdef", "", false, false},
+		{"real key containing <", Claude, "sk-ant-a<b", "", false, false},
+		{"unusual prefix only warns", Claude, "not-an-anthropic-key", "", false, true},
+		{"custom endpoint without a key", OpenAI, "", "http://localhost:8080/v1/chat/completions", false, false},
+		{"custom endpoint with a placeholder", OpenAI, PlaceholderAPIKey, "http://localhost:8080/v1/chat/completions", false, false},
+		{"custom endpoint with any key", OpenAI, "local", "http://localhost:8080/v1/chat/completions", false, false},
+		{"ollama needs none", Ollama, "", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.AI.Provider = tt.provider
+			cfg.AI.APIKey = tt.key
+			if tt.endpoint != "" {
+				cfg.AI.OpenAIEndpoint = tt.endpoint
+			}
+			warning, err := cfg.CheckAPIKey()
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrAPIKeyNotSet)) {
+				t.Fatalf("CheckAPIKey() error = %v, want ErrAPIKeyNotSet: %v", err, tt.wantErr)
+			}
+			if (warning != "") != tt.warns {
+				t.Errorf("CheckAPIKey() warning = %q, want one: %v", warning, tt.warns)
+			}
+			if strings.TrimSpace(tt.key) != "" && err != nil && strings.Contains(err.Error(), tt.key) {
+				t.Errorf("the error echoes the key: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// exampleTemplate is the annotated configuration written by "commitron init". Each
+// provider's settings sit in a commented-out block between "# >>> name" and "# <<< name".
+//
+//go:embed example.commitronrc
+var exampleTemplate string
+
+// ExampleConfig returns the annotated example configuration with the block for the
+// given provider uncommented
+func ExampleConfig(provider AIProvider) (string, error) {
+	begin := "# >>> " + string(provider)
+	end := "# <<< " + string(provider)
+	if !strings.Contains(exampleTemplate, begin) {
+		return "", fmt.Errorf("unknown provider %q (available: openai, claude, gemini, ollama)", provider)
+	}
+
+	lines := strings.Split(exampleTemplate, "\n")
+	inBlock := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == begin:
+			inBlock = true
+		case trimmed == end:
+			inBlock = false
+		case inBlock && strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "# "):
+			// "#key: value" is a commented-out setting, "# text" an explanation
+			indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+			lines[i] = indent + strings.TrimPrefix(trimmed, "#")
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// SaveExampleConfig saves the annotated example configuration for a provider to the given path
+func SaveExampleConfig(path string, provider AIProvider) error {
+	content, err := ExampleConfig(provider)
+	if err != nil {
+		return err
+	}
+
+	// Create the parent directory (e.g. ~/.config/commitron) if needed
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(content), 0644)
+}
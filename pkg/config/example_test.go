@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestExampleConfigRoundTrips(t *testing.T) {
+	providers := []struct {
+		provider AIProvider
+		model    string
+	}{
+		{OpenAI, "gpt-4o"},
+		{Claude, "claude-3-5-sonnet-20241022"},
+		{Gemini, "gemini-2.0-flash-exp"},
+		{Ollama, "qwen2.5:latest"},
+	}
+	for _, tt := range providers {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			example, err := ExampleConfig(tt.provider)
+			if err != nil {
+				t.Fatalf("ExampleConfig: %v", err)
+			}
+			cfg, err := ParseConfig([]byte(example))
+			if err != nil {
+				t.Fatalf("ParseConfig of the %s example: %v", tt.provider, err)
+			}
+			if cfg.AI.Provider != tt.provider || cfg.AI.Model != tt.model {
+				t.Errorf("provider and model = %s/%s, want %s/%s", cfg.AI.Provider, cfg.AI.Model, tt.provider, tt.model)
+			}
+			for _, other := range providers {
+				if other.provider != tt.provider && strings.Contains(example, "\n  provider: "+string(other.provider)) {
+					t.Errorf("the %s example also enables the %s block", tt.provider, other.provider)
+				}
+			}
+
+			// Writing the parsed config back out and reading it again changes nothing
+			data, err := yaml.Marshal(cfg)
+			if err != nil {
+				t.Fatalf("yaml.Marshal: %v", err)
+			}
+			reparsed, err := ParseConfig(data)
+			if err != nil {
+				t.Fatalf("ParseConfig of the marshaled config: %v\n%s", err, data)
+			}
+			cfg.Sources, reparsed.Sources = nil, nil
+			if !reflect.DeepEqual(cfg, reparsed) {
+				t.Errorf("config changed in the round trip:\nfirst:  %+v\nsecond: %+v", cfg, reparsed)
+			}
+		})
+	}
+}
+
+func TestExampleConfigUnknownProvider(t *testing.T) {
+	if _, err := ExampleConfig("mistral"); err == nil || !strings.Contains(err.Error(), "mistral") {
+		t.Errorf("ExampleConfig(\"mistral\") error = %v, want one naming the provider", err)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// gitConfigKeys maps the git config keys commitron reads (e.g. "git config commitron.model
+// gpt-4o") to the setting each one overrides
+var gitConfigKeys = []struct {
+	key     string
+	setting string
+	apply   func(c *Config, value string) error
+}{
+	{"commitron.provider", "ai.provider", func(c *Config, v string) error { c.AI.Provider = AIProvider(v); return nil }},
+	{"commitron.model", "ai.model", func(c *Config, v string) error { c.AI.Model = v; return nil }},
+	{"commitron.apiKey", "ai.api_key", func(c *Config, v string) error { c.AI.APIKey = v; return nil }},
+	{"commitron.openaiEndpoint", "ai.openai_endpoint", func(c *Config, v string) error { c.AI.OpenAIEndpoint = v; return nil }},
+	{"commitron.ollamaHost", "ai.ollama_host", func(c *Config, v string) error { c.AI.OllamaHost = v; return nil }},
+	{"commitron.convention", "commit.convention", func(c *Config, v string) error { c.Commit.Convention = CommitConvention(v); return nil }},
+	{"commitron.includeBody", "commit.include_body", func(c *Config, v string) error { return parseGitBool(v, &c.Commit.IncludeBody) }},
+	{"commitron.maxLength", "commit.max_length", func(c *Config, v string) error { return parseGitInt(v, &c.Commit.MaxLength) }},
+}
+
+// ApplyGitConfig overrides settings with the commitron.* keys found in git config, so a
+// repository can carry its own provider or model. get returns "" for unset keys.
+// Precedence, lowest first: defaults, the YAML file, git config, command-line flags.
+func (c *Config) ApplyGitConfig(get func(key string) string) error {
+	for _, entry := range gitConfigKeys {
+		value := get(entry.key)
+		if value == "" {
+			continue
+		}
+		if err := entry.apply(c, value); err != nil {
+			return fmt.Errorf("git config %s: %w", entry.key, err)
+		}
+		if c.Sources == nil {
+			c.Sources = map[string]string{}
+		}
+		c.Sources[entry.setting] = "git config " + entry.key
+	}
+	c.applyConventionDefaults()
+	return nil
+}
+
+// parseGitBool parses a boolean the way git does (true/yes/on/1, false/no/off/0)
+func parseGitBool(value string, target *bool) error {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		*target = true
+	case "false", "no", "off", "0":
+		*target = false
+	default:
+		return fmt.Errorf("%q is not a boolean", value)
+	}
+	return nil
+}
+
+// parseGitInt parses a whole number
+func parseGitInt(value string, target *int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+	*target = n
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets when the configuration is printed
+const redactedValue = "<redacted>"
+
+// LoadConfigFromPaths layers configuration files over the defaults. Each file only overrides
+// the settings it contains, so later files win and an explicit false or 0 is told apart from
+// a key that isn't there. Missing files are skipped, like in LoadConfigFromPath.
+//
+// Keys that match no setting are reported together in an *UnknownKeysError, which is
+// returned along with the otherwise complete configuration.
+func LoadConfigFromPaths(paths []string) (*Config, error) {
+	cfg := DefaultConfig()
+	cfg.Sources = map[string]string{}
+
+	var unknown []UnknownKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys, err := cfg.layer(data, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		unknown = append(unknown, keys...)
+	}
+
+	cfg.applyConventionDefaults()
+	if len(unknown) > 0 {
+		return cfg, &UnknownKeysError{Keys: unknown}
+	}
+	return cfg, nil
+}
+
+// layer decodes one YAML document over the current settings, records it as the source of
+// every key it sets and returns the keys that match no setting
+func (c *Config) layer(data []byte, source string) ([]UnknownKey, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil // Empty file
+	}
+	if err := doc.Decode(c); err != nil {
+		return nil, err
+	}
+
+	walkLeaves(doc.Content[0], "", func(key string, _ *yaml.Node) {
+		c.Sources[key] = source
+	})
+	return unknownKeys(doc.Content[0], reflect.TypeOf(*c), "", source), nil
+}
+
+// walkLeaves calls fn with the dotted key (e.g. "ai.model") of every value in a YAML mapping
+// that isn't itself a mapping
+func walkLeaves(node *yaml.Node, prefix string, fn func(key string, value *yaml.Node)) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		value := node.Content[i+1]
+		if value.Kind == yaml.MappingNode {
+			walkLeaves(value, key, fn)
+		} else {
+			fn(key, value)
+		}
+	}
+}
+
+// ResolvedYAML renders the configuration with secrets redacted and a comment naming the file
+// (or git config key) each setting came from. Unless all is set, only settings that came
+// from somewhere other than the defaults are included.
+func (c *Config) ResolvedYAML(all bool) ([]byte, error) {
+	shown := *c
+	if shown.AI.APIKey != "" {
+		shown.AI.APIKey = redactedValue
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(&shown); err != nil {
+		return nil, err
+	}
+	walkLeaves(&doc, "", func(key string, value *yaml.Node) {
+		if source, ok := c.Sources[key]; ok {
+			value.LineComment = "from " + source
+		}
+	})
+	if !all {
+		pruneDefaults(&doc)
+	}
+	return yaml.Marshal(&doc)
+}
+
+// pruneDefaults removes values without a source comment and the mappings left empty
+func pruneDefaults(node *yaml.Node) {
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		if value.Kind == yaml.MappingNode {
+			pruneDefaults(value)
+			if len(value.Content) == 0 {
+				continue
+			}
+		} else if value.LineComment == "" {
+			continue
+		}
+		kept = append(kept, node.Content[i], value)
+	}
+	node.Content = kept
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfigs writes each YAML document to its own file and returns the paths in order
+func writeConfigs(t *testing.T, docs ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, doc := range docs {
+		path := filepath.Join(dir, string(rune('a'+i))+".yaml")
+		if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestLoadConfigFromPathsMergesNestedSettings(t *testing.T) {
+	paths := writeConfigs(t,
+		"ai:\n  provider: claude\n  model: claude-3-5-sonnet-20241022\ncommit:\n  max_length: 50\n  convention: conventional\n",
+		"ai:\n  model: claude-3-5-haiku-20241022\ncontext:\n  max_input_tokens: 20000\n",
+	)
+	cfg, err := LoadConfigFromPaths(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AI.Provider != Claude {
+		t.Errorf("ai.provider = %q, want claude from the first file", cfg.AI.Provider)
+	}
+	if cfg.AI.Model != "claude-3-5-haiku-20241022" {
+		t.Errorf("ai.model = %q, want the second file's", cfg.AI.Model)
+	}
+	if cfg.Commit.MaxLength != 50 || cfg.Commit.Convention != ConventionalCommits {
+		t.Errorf("commit.max_length and convention = %d, %q, want 50, conventional from the first file", cfg.Commit.MaxLength, cfg.Commit.Convention)
+	}
+	if cfg.Context.MaxInputTokens != 20000 {
+		t.Errorf("context.max_input_tokens = %d, want 20000", cfg.Context.MaxInputTokens)
+	}
+
+	// Settings no file mentions keep their defaults, siblings of set keys included
+	defaults := DefaultConfig()
+	if cfg.AI.Temperature != defaults.AI.Temperature || cfg.Commit.MaxRetries != defaults.Commit.MaxRetries || cfg.Context.DiffStrategy != defaults.Context.DiffStrategy {
+		t.Errorf("unset settings changed: temperature %v, max_retries %d, diff_strategy %q", cfg.AI.Temperature, cfg.Commit.MaxRetries, cfg.Context.DiffStrategy)
+	}
+
+	wantSources := map[string]string{
+		"ai.provider":              paths[0],
+		"ai.model":                 paths[1],
+		"commit.max_length":        paths[0],
+		"commit.convention":        paths[0],
+		"context.max_input_tokens": paths[1],
+	}
+	if !reflect.DeepEqual(cfg.Sources, wantSources) {
+		t.Errorf("sources = %v, want %v", cfg.Sources, wantSources)
+	}
+}
+
+func TestLoadConfigFromPathsKeepsExplicitZeroValues(t *testing.T) {
+	paths := writeConfigs(t,
+		"ai:\n  temperature: 0\ncommit:\n  include_body: false\n  max_retries: 0\n  collapse_repeated_words: false\ncontext:\n  summarization_enabled: false\n  directory_group_depth: 0\nui:\n  enable_tui: false\n",
+		"ai:\n  model: gpt-4o\n",
+		"",
+	)
+	cfg, err := LoadConfigFromPaths(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AI.Temperature != 0 || cfg.Commit.MaxRetries != 0 || cfg.Context.DirectoryGroupDepth != 0 {
+		t.Errorf("explicit zeros lost: temperature %v, max_retries %d, directory_group_depth %d",
+			cfg.AI.Temperature, cfg.Commit.MaxRetries, cfg.Context.DirectoryGroupDepth)
+	}
+	if cfg.Commit.IncludeBody || cfg.Commit.CollapseRepeatedWords || cfg.Context.SummarizationEnabled || cfg.UI.EnableTUI {
+		t.Errorf("explicit false lost: include_body %v, collapse_repeated_words %v, summarization_enabled %v, enable_tui %v",
+			cfg.Commit.IncludeBody, cfg.Commit.CollapseRepeatedWords, cfg.Context.SummarizationEnabled, cfg.UI.EnableTUI)
+	}
+	if cfg.AI.Model != "gpt-4o" {
+		t.Errorf("ai.model = %q, want gpt-4o", cfg.AI.Model)
+	}
+}
+
+func TestLoadConfigFromPathsLaterFileWins(t *testing.T) {
+	paths := writeConfigs(t,
+		"commit:\n  include_body: false\n  max_length: 0\ngit:\n  protected_branches: [main, release]\n",
+		"commit:\n  include_body: true\n  max_length: 60\ngit:\n  protected_branches: [trunk]\n",
+	)
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+	cfg, err := LoadConfigFromPaths(append(paths, missing))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.Commit.IncludeBody || cfg.Commit.MaxLength != 60 {
+		t.Errorf("include_body and max_length = %v, %d, want true, 60 from the second file", cfg.Commit.IncludeBody, cfg.Commit.MaxLength)
+	}
+	if want := []string{"trunk"}; !reflect.DeepEqual(cfg.Git.ProtectedBranches, want) {
+		t.Errorf("git.protected_branches = %q, want %q: a later list replaces an earlier one", cfg.Git.ProtectedBranches, want)
+	}
+	if cfg.Sources["commit.include_body"] != paths[1] {
+		t.Errorf("commit.include_body source = %q, want %q", cfg.Sources["commit.include_body"], paths[1])
+	}
+}
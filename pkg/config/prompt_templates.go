@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// promptTemplateFuncs are the functions available in ai.prompt_template_file and
+// ai.system_prompt_file besides the text/template builtins
+var promptTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// LoadPromptTemplates parses ai.prompt_template_file and ai.system_prompt_file. Relative paths
+// are taken from the directory of the config file that set them. Parse errors name the file
+// and line, so a broken template is reported when the configuration is loaded.
+func (c *Config) LoadPromptTemplates() error {
+	if c.AI.SystemPromptFile != "" && c.AI.SystemPrompt != "" {
+		return errors.New("set only one of ai.system_prompt and ai.system_prompt_file")
+	}
+
+	var err error
+	if c.AI.PromptTemplate, err = c.parsePromptTemplate("ai.prompt_template_file", c.AI.PromptTemplateFile); err != nil {
+		return err
+	}
+	c.AI.SystemPromptTemplate, err = c.parsePromptTemplate("ai.system_prompt_file", c.AI.SystemPromptFile)
+	return err
+}
+
+// parsePromptTemplate reads and parses the template file set by key, or returns nil if unset
+func (c *Config) parsePromptTemplate(key, path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, path[2:])
+		}
+	} else if source := c.Sources[key]; !filepath.IsAbs(path) && source != "" && !strings.HasPrefix(source, "git config ") {
+		path = filepath.Join(filepath.Dir(source), path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	tmpl, err := template.New(path).Funcs(promptTemplateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownKey is wrapped by UnknownKeysError
+var ErrUnknownKey = errors.New("unknown configuration key")
+
+// UnknownKey is a key in a configuration file that doesn't match any setting
+type UnknownKey struct {
+	File       string // Empty when parsed from memory
+	Line       int
+	Key        string // Dotted path, e.g. "commit.include_boby"
+	Suggestion string // Closest valid key at the same level, or ""
+}
+
+func (k UnknownKey) String() string {
+	location := fmt.Sprintf("line %d", k.Line)
+	if k.File != "" {
+		location = fmt.Sprintf("%s:%d", k.File, k.Line)
+	}
+	s := fmt.Sprintf("%s: unknown key %q", location, k.Key)
+	if k.Suggestion != "" {
+		s += fmt.Sprintf(" (did you mean %q?)", k.Suggestion)
+	}
+	return s
+}
+
+// UnknownKeysError lists the unknown keys found while loading configuration. The loaders
+// still return the configuration with the known keys applied alongside it, so callers can
+// choose to only warn.
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownKeysError) Error() string {
+	if len(e.Keys) == 1 {
+		return e.Keys[0].String()
+	}
+	lines := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		lines[i] = key.String()
+	}
+	return fmt.Sprintf("%d unknown configuration keys:\n%s", len(e.Keys), strings.Join(lines, "\n"))
+}
+
+func (e *UnknownKeysError) Unwrap() error {
+	return ErrUnknownKey
+}
+
+// unknownKeys returns the keys of a YAML mapping, and of the mappings nested in it, that
+// don't match a field of the struct type t. yaml.v3 silently ignores them when decoding.
+func unknownKeys(node *yaml.Node, t reflect.Type, prefix, file string) []UnknownKey {
+	if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := yamlFields(t)
+	var unknown []UnknownKey
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		field, ok := fields[name]
+		if !ok {
+			key := UnknownKey{File: file, Line: node.Content[i].Line, Key: prefix + name}
+			if suggestion := closestKey(name, fields); suggestion != "" {
+				key.Suggestion = prefix + suggestion
+			}
+			unknown = append(unknown, key)
+			continue
+		}
+		unknown = append(unknown, unknownKeys(node.Content[i+1], field, prefix+name+".", file)...)
+	}
+	return unknown
+}
+
+// yamlFields maps the YAML keys of a struct type to their field types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name) // yaml.v3's default key
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// closestKey returns the valid key nearest to name by edit distance, or "" if none is
+// close enough to be a likely typo
+func closestKey(name string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", max(2, len(name)/3)+1
+	for candidate := range fields {
+		distance := editDistance(name, candidate)
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	doc := `ai:
+  provider: openai
+  modle: gpt-4o
+commit:
+  include_boby: false
+  max_length: 60
+  xyzzy_frobnicate: true
+contxt:
+  include_diff: false
+ui:
+  enable_tui: false
+`
+	cfg, err := ParseConfig([]byte(doc))
+	var unknownErr *UnknownKeysError
+	if !errors.As(err, &unknownErr) || !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("ParseConfig error = %v, want an *UnknownKeysError wrapping ErrUnknownKey", err)
+	}
+
+	want := []UnknownKey{
+		{Line: 3, Key: "ai.modle", Suggestion: "ai.model"},
+		{Line: 5, Key: "commit.include_boby", Suggestion: "commit.include_body"},
+		{Line: 7, Key: "commit.xyzzy_frobnicate"},
+		// The keys under an unknown section aren't reported on their own
+		{Line: 8, Key: "contxt", Suggestion: "context"},
+	}
+	if !reflect.DeepEqual(unknownErr.Keys, want) {
+		t.Errorf("unknown keys = %+v, want %+v", unknownErr.Keys, want)
+	}
+
+	// The known keys around them still apply
+	if cfg == nil || cfg.Commit.MaxLength != 60 || cfg.UI.EnableTUI {
+		t.Errorf("known keys weren't applied alongside the error: %+v", cfg)
+	}
+
+	message := err.Error()
+	for _, line := range []string{
+		"4 unknown configuration keys:",
+		`line 3: unknown key "ai.modle" (did you mean "ai.model"?)`,
+		`line 7: unknown key "commit.xyzzy_frobnicate"` + "\n",
+	} {
+		if !strings.Contains(message, line) {
+			t.Errorf("error message lacks %q:\n%s", line, message)
+		}
+	}
+}
+
+func TestUnknownKeysLayered(t *testing.T) {
+	paths := writeConfigs(t,
+		"ai:\n  model: gpt-4o\n  temprature: 0.2\n",
+		"commit:\n  max_lenght: 50\n",
+	)
+	cfg, err := LoadConfigFromPaths(paths)
+	var unknownErr *UnknownKeysError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("LoadConfigFromPaths error = %v, want an *UnknownKeysError", err)
+	}
+
+	want := []UnknownKey{
+		{File: paths[0], Line: 3, Key: "ai.temprature", Suggestion: "ai.temperature"},
+		{File: paths[1], Line: 2, Key: "commit.max_lenght", Suggestion: "commit.max_length"},
+	}
+	if !reflect.DeepEqual(unknownErr.Keys, want) {
+		t.Errorf("unknown keys = %+v, want %+v", unknownErr.Keys, want)
+	}
+	if got := want[1].String(); got != paths[1]+`:2: unknown key "commit.max_lenght" (did you mean "commit.max_length"?)` {
+		t.Errorf("String() = %q, want the file and line first", got)
+	}
+	if cfg == nil || cfg.AI.Model != "gpt-4o" {
+		t.Errorf("known keys weren't applied alongside the error: %+v", cfg)
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	fields := yamlFields(reflect.TypeOf(DefaultConfig().Commit))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"include_boby", "include_body"},
+		{"convetion", "convention"},
+		{"maxlength", "max_length"},
+		{"MAX_LENGTH", ""}, // Keys are case-sensitive and every letter differs
+		{"scope", "scope"},
+		{"frobnicate", ""},
+		{"x", ""},
+	}
+	for _, tt := range tests {
+		if got := closestKey(tt.name, fields); got != tt.want {
+			t.Errorf("closestKey(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"model", "modle", 2},
+		{"kitten", "sitting", 3},
+		{"same", "same", 0},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// emptyTree is git's well-known hash of the empty tree, used as the base of a root commit
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// errNoCommits is returned when amending in a repository without commits
+var errNoCommits = errors.New("there is no commit to amend")
+
+// AmendBase returns the revision an amended HEAD commit will be compared against:
+// its parent, or the empty tree when HEAD is the root commit
+func AmendBase() (string, error) {
+	if err := exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD").Run(); err != nil {
+		return "", errNoCommits
+	}
+
+	if err := exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD~1").Run(); err != nil {
+		return emptyTree, nil
+	}
+	return "HEAD~1", nil
+}
+
+// GetStagedFilesSince returns the files that differ between base and the index,
+// e.g. the files an amended commit will contain changes for
+func GetStagedFilesSince(base string) ([]string, error) {
+	cmd := DiffCommand("--name-only", "--cached", base)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, file := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if file != "" {
+			result = append(result, file)
+		}
+	}
+
+	return result, nil
+}
+
+// GetStagedChangesSince returns the diff between base and the index
+func GetStagedChangesSince(base string) (string, error) {
+	cmd := DiffCommand("--cached", base)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
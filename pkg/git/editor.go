@@ -0,0 +1,82 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrEditAborted is returned when the editor fails or the message is emptied
+var ErrEditAborted = errors.New("commit message edit aborted")
+
+// Editor returns the editor git would use, resolved from $GIT_EDITOR, core.editor,
+// $VISUAL and $EDITOR in that order by "git var GIT_EDITOR"
+func Editor() (string, error) {
+	cmd := exec.Command("git", "var", "GIT_EDITOR")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("could not determine the editor: %w", err)
+	}
+
+	editor := strings.TrimSpace(out.String())
+	if editor == "" {
+		return "", errors.New("no editor is configured; set core.editor or $EDITOR")
+	}
+	return editor, nil
+}
+
+// EditMessage opens message in the user's editor and returns the edited text with
+// comment lines removed. Like git commit, a failing editor or an empty message aborts.
+func EditMessage(message string) (string, error) {
+	editor, err := Editor()
+	if err != nil {
+		return "", err
+	}
+
+	tmpFile, err := os.CreateTemp("", "commitron-edit-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	commentChar := CommentChar()
+	content := fmt.Sprintf("%s\n\n%s Edit the commit message above. Lines starting with '%s' are ignored,\n%s and an empty message aborts the commit.\n",
+		message, commentChar, commentChar, commentChar)
+	if _, err := tmpFile.WriteString(content); err != nil {
+		tmpFile.Close()
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+
+	// The editor setting may carry arguments ("code --wait"), so run it through the shell as git does
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", editor+" \""+tmpFile.Name()+"\"")
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$@"`, editor, tmpFile.Name())
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: editor %q failed: %v", ErrEditAborted, editor, err)
+	}
+
+	data, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		return "", err
+	}
+
+	edited := StripComments(string(data), commentChar)
+	if edited == "" {
+		return "", fmt.Errorf("%w: the message is empty", ErrEditAborted)
+	}
+	return edited, nil
+}
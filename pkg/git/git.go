@@ -3,8 +3,10 @@ package git
 import (
 	"bytes"
 	"errors"
+	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -15,9 +17,23 @@ func IsGitRepo() bool {
 	return err == nil
 }
 
+// stableDiffArgs make git diff output canonical whatever the user's diff.* configuration
+// (external diff drivers, diff.noprefix, diff.mnemonicPrefix, diff.relative, diff.submodule,
+// color) and detect renames and copies the same way everywhere, so the a/ b/ headers and
+// "Subproject commit" lines can be parsed
+var stableDiffArgs = []string{
+	"-c", "diff.noprefix=false", "-c", "diff.mnemonicPrefix=false",
+	"diff", "--no-ext-diff", "--no-color", "--no-relative", "--submodule=short", "--src-prefix=a/", "--dst-prefix=b/", "-M", "-C",
+}
+
+// DiffCommand returns a git diff command with stable output options followed by args
+func DiffCommand(args ...string) *exec.Cmd {
+	return exec.Command("git", append(append([]string{}, stableDiffArgs...), args...)...)
+}
+
 // GetStagedFiles returns a list of staged files
 func GetStagedFiles() ([]string, error) {
-	cmd := exec.Command("git", "diff", "--name-only", "--cached")
+	cmd := DiffCommand("--name-only", "--cached")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	err := cmd.Run()
@@ -39,7 +55,7 @@ func GetStagedFiles() ([]string, error) {
 
 // GetStagedChanges returns the diff of staged changes
 func GetStagedChanges() (string, error) {
-	cmd := exec.Command("git", "diff", "--cached")
+	cmd := DiffCommand("--cached")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	err := cmd.Run()
@@ -105,33 +121,195 @@ func StageAllModified() error {
 	return cmd.Run()
 }
 
+// StageAll stages all changes including untracked files (respects .gitignore)
+func StageAll() error {
+	cmd := exec.Command("git", "add", "-A")
+	return cmd.Run()
+}
+
+// UntrackedFiles returns the untracked files that aren't ignored
+func UntrackedFiles() ([]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "--untracked-files=all")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "?? ") {
+			result = append(result, strings.Trim(strings.TrimPrefix(line, "?? "), "\""))
+		}
+	}
+
+	return result, nil
+}
+
 // Commit creates a new commit with the given message
 func Commit(message string) error {
+	return CommitWithOptions(message, CommitOptions{})
+}
+
+// CommitOptions controls how the commit is created
+type CommitOptions struct {
+	Amend       bool // Replace the HEAD commit instead of creating a new one
+	ResetAuthor bool // With Amend, take over authorship and reset the author date
+	NoVerify    bool // Skip the pre-commit and commit-msg hooks
+	Sign        bool // Sign the commit even if commit.gpgSign is not set
+}
+
+// AmendCommit replaces the HEAD commit with the staged index and the given message.
+// Without ResetAuthor git keeps the original author and author date.
+func AmendCommit(message string, opts CommitOptions) error {
+	opts.Amend = true
+	return CommitWithOptions(message, opts)
+}
+
+// commitArgs builds the git commit arguments; the message is read from stdin.
+// Signing follows the user's git configuration (commit.gpgSign, gpg.format), so a new
+// and an amended commit are signed the same way; -S is only added when Sign is requested
+// and git would not sign anyway.
+func commitArgs(opts CommitOptions) ([]string, error) {
+	if opts.ResetAuthor && !opts.Amend {
+		return nil, errors.New("--reset-author can only be used when amending")
+	}
+
+	args := []string{"commit", "-F", "-"}
+	if opts.Amend {
+		args = append(args, "--amend")
+	}
+	if opts.ResetAuthor {
+		args = append(args, "--reset-author")
+	}
+	if opts.NoVerify {
+		args = append(args, "--no-verify")
+	}
+	if opts.Sign && !ConfigBool("commit.gpgsign") {
+		args = append(args, "-S")
+	}
+
+	return args, nil
+}
+
+// CommitWithOptions runs git commit with the message piped to stdin, so no temporary
+// file is needed and concurrent runs can't clash over one
+func CommitWithOptions(message string, opts CommitOptions) error {
 	if message == "" {
 		return errors.New("commit message cannot be empty")
 	}
 
-	// Write commit message to temporary file
-	tmpFile, err := os.CreateTemp("", "commitron-msg-")
+	args, err := commitArgs(opts)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tmpFile.Name())
 
-	_, err = tmpFile.WriteString(message)
-	if err != nil {
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Stdout = os.Stdout
+	// Keep a copy of stderr so signing failures can be told apart from other errors
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+
+	if err := cmd.Run(); err != nil {
+		if signErr := signingError(stderr.String()); signErr != nil {
+			return signErr
+		}
 		return err
 	}
+	return nil
+}
+
+// RecentCommit holds the subject and changed files of a commit from the history
+type RecentCommit struct {
+	Subject string
+	Files   []string
+}
+
+// GetRecentCommits returns up to limit recent commits with their changed files
+func GetRecentCommits(limit int) ([]RecentCommit, error) {
+	// Each commit starts with a NUL-prefixed subject line followed by its file names
+	cmd := exec.Command("git", "log", "-n", strconv.Itoa(limit), "--format=%x00%s", "--name-only")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []RecentCommit
+	for _, entry := range strings.Split(out.String(), "\x00") {
+		lines := strings.Split(strings.TrimSpace(entry), "\n")
+		if len(lines) == 0 || lines[0] == "" {
+			continue
+		}
+
+		commit := RecentCommit{Subject: lines[0]}
+		for _, file := range lines[1:] {
+			if file = strings.TrimSpace(file); file != "" {
+				commit.Files = append(commit.Files, file)
+			}
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
 
-	err = tmpFile.Close()
+// CurrentBranch returns the name of the checked-out branch, or "" on a detached HEAD
+func CurrentBranch() (string, error) {
+	cmd := exec.Command("git", "branch", "--show-current")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Create commit using the temp file
-	cmd := exec.Command("git", "commit", "-F", tmpFile.Name())
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	return strings.TrimSpace(out.String()), nil
+}
 
-	return cmd.Run()
+// IsDefaultBranch reports whether branch is the repository's default branch: the remote's
+// HEAD when known, otherwise init.defaultBranch, main or master
+func IsDefaultBranch(branch string) bool {
+	cmd := exec.Command("git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil {
+		return branch == strings.TrimPrefix(strings.TrimSpace(out.String()), "origin/")
+	}
+
+	out.Reset()
+	cmd = exec.Command("git", "config", "--get", "init.defaultBranch")
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil && branch == strings.TrimSpace(out.String()) {
+		return true
+	}
+
+	return branch == "main" || branch == "master"
+}
+
+// GetConfig returns the value of a git config key, or "" when it isn't set
+func GetConfig(key string) string {
+	cmd := exec.Command("git", "config", "--get", key)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// GitDir returns the path to the repository's git directory
+func GitDir() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-dir")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(out.String()), nil
 }
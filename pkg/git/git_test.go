@@ -0,0 +1,204 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CommitOptions
+		gpgSign bool // commit.gpgsign in the repository's config
+		want    []string
+		wantErr bool
+	}{
+		{"plain", CommitOptions{}, false, []string{"commit", "-F", "-"}, false},
+		{"amend", CommitOptions{Amend: true}, false, []string{"commit", "-F", "-", "--amend"}, false},
+		{"amend and reset author", CommitOptions{Amend: true, ResetAuthor: true}, false, []string{"commit", "-F", "-", "--amend", "--reset-author"}, false},
+		{"reset author without amend", CommitOptions{ResetAuthor: true}, false, nil, true},
+		{"reset author without amend, signed", CommitOptions{ResetAuthor: true, Sign: true, NoVerify: true}, false, nil, true},
+		{"no verify", CommitOptions{NoVerify: true}, false, []string{"commit", "-F", "-", "--no-verify"}, false},
+		{"sign", CommitOptions{Sign: true}, false, []string{"commit", "-F", "-", "-S"}, false},
+		{"sign with commit.gpgsign set", CommitOptions{Sign: true}, true, []string{"commit", "-F", "-"}, false},
+		{"commit.gpgsign set without sign", CommitOptions{}, true, []string{"commit", "-F", "-"}, false},
+		{"amend, no verify and sign", CommitOptions{Amend: true, NoVerify: true, Sign: true}, false, []string{"commit", "-F", "-", "--amend", "--no-verify", "-S"}, false},
+		{"everything", CommitOptions{Amend: true, ResetAuthor: true, NoVerify: true, Sign: true}, false, []string{"commit", "-F", "-", "--amend", "--reset-author", "--no-verify", "-S"}, false},
+		{"everything with commit.gpgsign set", CommitOptions{Amend: true, ResetAuthor: true, NoVerify: true, Sign: true}, true, []string{"commit", "-F", "-", "--amend", "--reset-author", "--no-verify"}, false},
+	}
+
+	newTestRepo(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.gpgSign {
+				runGit(t, "config", "commit.gpgsign", "true")
+			} else {
+				runGit(t, "config", "commit.gpgsign", "false")
+			}
+			got, err := commitArgs(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("commitArgs(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commitArgs(%+v) = %q, want %q", tt.opts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitWithOptionsRoundTripsMessage(t *testing.T) {
+	messages := []string{
+		"fix(i18n): prise en charge des caractères accentués\n\nLes noms de fichiers comme « résumé.txt » étaient tronqués.",
+		"feat: 日本語のコミットメッセージを追加\n\n- 改行を含む本文\n- 絵文字も保持する 🚀👩‍💻",
+		"docs: quote \"$HOME\", `backticks` and 'single quotes'\n\nA line with a tab\tand a backslash \\n that is not a newline.",
+		"chore: mixed scripts Ελληνικά, русский, العربية, עברית\n\nCo-authored-by: Zoë Ångström <zoe@example.com>",
+	}
+
+	newTestRepo(t)
+	for i, message := range messages {
+		writeFile(t, "file.txt", message)
+		runGit(t, "add", "file.txt")
+		if err := CommitWithOptions(message, CommitOptions{}); err != nil {
+			t.Fatalf("message %d: CommitWithOptions: %v", i, err)
+		}
+		if got := strings.TrimSpace(runGit(t, "log", "-1", "--format=%B")); got != message {
+			t.Errorf("message %d: git stored %q, want %q", i, got, message)
+		}
+	}
+
+	// Amending reads the new message from stdin the same way
+	amended := "fix: amended message with ümlauts and 中文"
+	if err := AmendCommit(amended, CommitOptions{}); err != nil {
+		t.Fatalf("AmendCommit: %v", err)
+	}
+	if got := strings.TrimSpace(runGit(t, "log", "-1", "--format=%B")); got != amended {
+		t.Errorf("amended message = %q, want %q", got, amended)
+	}
+	if count := strings.TrimSpace(runGit(t, "rev-list", "--count", "HEAD")); count != "5" {
+		t.Errorf("history has %s commits after amending, want 5", count)
+	}
+}
+
+func TestUntrackedFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  []string
+	}{
+		{"clean tree", func(t *testing.T) {}, nil},
+		{"untracked only", func(t *testing.T) {
+			writeFile(t, "new.go", "package main\n")
+			writeFile(t, "docs/guide.md", "# guide\n")
+			writeFile(t, ".gitignore", "*.log\n")
+			writeFile(t, "debug.log", "ignored\n")
+		}, []string{".gitignore", "docs/guide.md", "new.go"}},
+		{"modified tracked files", func(t *testing.T) {
+			writeFile(t, "README.md", "# changed\n")
+			writeFile(t, "staged.go", "package main\n")
+			runGit(t, "add", "staged.go")
+		}, nil},
+		{"modified and untracked", func(t *testing.T) {
+			writeFile(t, "README.md", "# changed\n")
+			writeFile(t, "new.go", "package main\n")
+		}, []string{"new.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			tt.setup(t)
+			got, err := UntrackedFiles()
+			if err != nil {
+				t.Fatalf("UntrackedFiles: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UntrackedFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchBranch(t *testing.T) {
+	patterns := []string{"main", "release/*"}
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"main", "main"},
+		{"release/1.2", "release/*"},
+		{"release/v2", "release/*"},
+		{"release/1.2/hotfix", ""},
+		{"release", ""},
+		{"releases/1.2", ""},
+		{"feature/release/1.2", ""},
+		{"Release/1.2", ""},
+		{"main-backup", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := MatchBranch(patterns, tt.branch); got != tt.want {
+			t.Errorf("MatchBranch(%q, %q) = %q, want %q", patterns, tt.branch, got, tt.want)
+		}
+	}
+
+	// A pattern that isn't a valid glob still protects the branch of the same name
+	if got := MatchBranch([]string{"release/[1.x"}, "release/[1.x"); got != "release/[1.x" {
+		t.Errorf("MatchBranch with an invalid glob = %q, want the exact match", got)
+	}
+}
+
+func TestDiffCommandIgnoresUserDiffConfig(t *testing.T) {
+	newTestRepo(t)
+	writeFile(t, "pkg/parser/parser.go", "package parser\n")
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "add parser")
+
+	runGit(t, "config", "diff.noprefix", "true")
+	runGit(t, "config", "diff.mnemonicPrefix", "true")
+	runGit(t, "config", "diff.relative", "true")
+	runGit(t, "config", "color.diff", "always")
+	writeFile(t, "README.md", "# test\n\nMore detail.\n")
+	writeFile(t, "pkg/parser/parser.go", "package parser\n\nfunc Parse() {}\n")
+	runGit(t, "add", ".")
+
+	// A diff from a subdirectory still covers the whole repository with a/ and b/ prefixes
+	if err := os.Chdir("pkg"); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := GetStagedChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"diff --git a/README.md b/README.md\n",
+		"--- a/README.md\n+++ b/README.md\n",
+		"diff --git a/pkg/parser/parser.go b/pkg/parser/parser.go\n",
+		"--- a/pkg/parser/parser.go\n+++ b/pkg/parser/parser.go\n",
+	} {
+		if !strings.Contains(changes, want) {
+			t.Errorf("staged diff is missing %q:\n%s", want, changes)
+		}
+	}
+	if strings.Contains(changes, "\x1b[") || strings.Contains(changes, " c/") || strings.Contains(changes, " i/") {
+		t.Errorf("staged diff has colors or mnemonic prefixes:\n%s", changes)
+	}
+
+	files, err := GetStagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"README.md", "pkg/parser/parser.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("GetStagedFiles = %q, want %q", files, want)
+	}
+
+	// Without the stable options, the same repository gives an unprefixed diff
+	plain, err := exec.Command("git", "diff", "--cached", "--no-color").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "diff --git a/") {
+		t.Errorf("the fixture doesn't set diff.noprefix:\n%s", plain)
+	}
+}
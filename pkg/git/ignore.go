@@ -0,0 +1,67 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IgnoreFile is one ignore file found in the repository
+type IgnoreFile struct {
+	Dir     string // Directory relative to the repository root ("" for the root)
+	Content string
+}
+
+// ReadIgnoreFiles returns every file called name in the working tree, tracked or not (unless
+// git ignores it). Parent directories come before their subdirectories, so patterns from
+// deeper files can be given precedence.
+func ReadIgnoreFiles(name string) ([]IgnoreFile, error) {
+	root, err := revParsePath("--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--", ":(top,glob)**/"+name)
+	cmd.Dir = root
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var files []IgnoreFile
+	seen := make(map[string]bool)
+	for _, file := range strings.Split(out.String(), "\x00") {
+		if file == "" || seen[file] {
+			continue // ls-files lists a file once per stage during conflicts
+		}
+		seen[file] = true
+
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			continue // Deleted in the working tree
+		}
+		dir := path.Dir(file)
+		if dir == "." {
+			dir = ""
+		}
+		files = append(files, IgnoreFile{Dir: dir, Content: string(data)})
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return depth(files[i].Dir) < depth(files[j].Dir)
+	})
+	return files, nil
+}
+
+// depth counts the directories in a repository-relative path
+func depth(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
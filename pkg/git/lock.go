@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned when another commitron process holds the repository lock
+var ErrLocked = errors.New("another commitron process is running for this repository")
+
+// staleLockAge is how old a lock file may get before it is considered abandoned
+const staleLockAge = 10 * time.Minute
+
+// RepoLock is an exclusive per-repository lock held while commitron stages and commits
+type RepoLock struct {
+	path string
+}
+
+// AcquireLock creates the .git/commitron.lock file, replacing it if it was left
+// behind by a process that is no longer running
+func AcquireLock() (*RepoLock, error) {
+	gitDir, err := GitDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(gitDir, "commitron.lock")
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			// Record the owner so other processes can detect a stale lock
+			_, err = fmt.Fprintf(file, "%d %d\n", os.Getpid(), time.Now().Unix())
+			closeErr := file.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return &RepoLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if !isStaleLock(path) {
+			return nil, fmt.Errorf("%w (lock file: %s)", ErrLocked, path)
+		}
+		os.Remove(path)
+	}
+
+	return nil, fmt.Errorf("%w (lock file: %s)", ErrLocked, path)
+}
+
+// Release removes the lock file
+func (l *RepoLock) Release() error {
+	return os.Remove(l.path)
+}
+
+// isStaleLock reports whether the lock file belongs to a dead process or is too old
+func isStaleLock(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// The owner released the lock in the meantime
+		return os.IsNotExist(err)
+	}
+
+	var pid int
+	var timestamp int64
+	if _, err := fmt.Sscanf(string(data), "%d %d", &pid, &timestamp); err != nil {
+		// Unreadable lock contents can't belong to a live commitron process
+		return true
+	}
+
+	if time.Since(time.Unix(timestamp, 0)) > staleLockAge {
+		return true
+	}
+
+	return !processAlive(pid)
+}
@@ -0,0 +1,59 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// MergeHead returns the full hash of the commit being merged when a merge is waiting
+// to be committed (after "git merge --no-commit" or resolved conflicts), or "" otherwise
+func MergeHead() string {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "MERGE_HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// MergeSubject returns the subject git prepared for the merge in MERGE_MSG, such as
+// "Merge branch 'feature' into main"
+func MergeSubject() (string, error) {
+	path, err := GitPath("MERGE_MSG")
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	text, _ := SplitMessageComments(string(data), CommentChar())
+	subject, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if subject == "" {
+		return "", fmt.Errorf("%s has no subject", path)
+	}
+	return subject, nil
+}
+
+// MergedCommits returns the subjects of the non-merge commits the merge brings in, newest first
+func MergedCommits() ([]string, error) {
+	cmd := exec.Command("git", "log", "--no-merges", "--format=%s", "HEAD..MERGE_HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var subjects []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
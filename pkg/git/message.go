@@ -0,0 +1,134 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// MessageKind classifies the content of an existing commit message file
+type MessageKind int
+
+const (
+	// MessageEmpty is a blank message file
+	MessageEmpty MessageKind = iota
+	// MessageCommentsOnly holds nothing but comment lines, e.g. git's status summary
+	MessageCommentsOnly
+	// MessageTemplateOnly holds an unmodified commit.template
+	MessageTemplateOnly
+	// MessageReal holds a message provided by the user or another tool
+	MessageReal
+)
+
+// String returns a readable name for the message kind
+func (k MessageKind) String() string {
+	switch k {
+	case MessageEmpty:
+		return "empty"
+	case MessageCommentsOnly:
+		return "comments only"
+	case MessageTemplateOnly:
+		return "template only"
+	default:
+		return "message"
+	}
+}
+
+// CommentChar returns the repository's core.commentChar, defaulting to "#"
+func CommentChar() string {
+	cmd := exec.Command("git", "config", "--get", "core.commentChar")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "#"
+	}
+
+	char := strings.TrimSpace(out.String())
+	if char == "" || char == "auto" {
+		return "#"
+	}
+	return char
+}
+
+// CommitTemplate returns the content of the configured commit.template, or "" if none is set
+func CommitTemplate() string {
+	cmd := exec.Command("git", "config", "--get", "commit.template")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+
+	path := strings.TrimSpace(out.String())
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, path[2:])
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// scissorsMark is the line, after the comment character, below which git discards everything
+// (e.g. the diff shown by "git commit -v"), whether or not it is commented
+const scissorsMark = " ------------------------ >8 ------------------------"
+
+// CutAtScissors drops the scissors line and everything below it, like git does
+func CutAtScissors(content, commentChar string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if line == commentChar+scissorsMark {
+			return strings.Join(lines[:i], "\n")
+		}
+	}
+	return content
+}
+
+// StripComments removes comment lines, and everything below a scissors line, from a commit message
+func StripComments(content, commentChar string) string {
+	var kept []string
+	for _, line := range strings.Split(CutAtScissors(content, commentChar), "\n") {
+		if strings.HasPrefix(line, commentChar) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// SplitMessageComments separates the message text from the trailing comment block
+func SplitMessageComments(content, commentChar string) (string, string) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, commentChar) {
+			return strings.TrimSpace(strings.Join(lines[:i], "\n")), strings.Join(lines[i:], "\n")
+		}
+	}
+	return strings.TrimSpace(content), ""
+}
+
+// ClassifyCommitMessage determines whether a message file is empty, only has comments,
+// only has the unmodified commit template, or holds a real message
+func ClassifyCommitMessage(content, commentChar, template string) MessageKind {
+	if strings.TrimSpace(content) == "" {
+		return MessageEmpty
+	}
+
+	message := StripComments(content, commentChar)
+	if message == "" {
+		return MessageCommentsOnly
+	}
+
+	if template != "" && message == StripComments(template, commentChar) {
+		return MessageTemplateOnly
+	}
+
+	return MessageReal
+}
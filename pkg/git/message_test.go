@@ -0,0 +1,110 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+// statusComments is the comment block git appends to COMMIT_EDITMSG
+const statusComments = `# Please enter the commit message for your changes. Lines starting
+# with '#' will be ignored, and an empty message aborts the commit.
+#
+# On branch main
+# Changes to be committed:
+#	modified:   main.go
+#
+`
+
+const commitTemplate = `feat(scope): summary
+
+# Why is this change needed?
+`
+
+func TestClassifyCommitMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		commentChar string
+		template    string
+		want        MessageKind
+	}{
+		{"empty", "", "#", "", MessageEmpty},
+		{"blank lines", "\n\n  \n", "#", "", MessageEmpty},
+		{"status comments", "\n" + statusComments, "#", "", MessageCommentsOnly},
+		{"message", "fix: close the file\n\n" + statusComments, "#", "", MessageReal},
+		{"message with CRLF", "fix: close the file\r\n\r\n# comment\r\n", "#", "", MessageReal},
+		{"unmodified template", commitTemplate + "\n" + statusComments, "#", commitTemplate, MessageTemplateOnly},
+		{"edited template", "feat(parser): accept empty input\n\n" + statusComments, "#", commitTemplate, MessageReal},
+		{"template without a template configured", commitTemplate + "\n" + statusComments, "#", "", MessageReal},
+		{"merge", "Merge branch 'feature' into main\n\n" + `# Please enter a commit message to explain why this merge is necessary,
+# especially if it merges an updated upstream into a topic branch.
+#
+# Lines starting with '#' will be ignored, and an empty message aborts
+# the commit.
+`, "#", "", MessageReal},
+		{"merge with conflicts", "Merge branch 'feature' into main\n\n# Conflicts:\n#\tmain.go\n" + statusComments, "#", "", MessageReal},
+		{"squash", `Squashed commit of the following:
+
+commit 3f2a1c0d9e8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f
+Author: Test <test@example.com>
+Date:   Mon Jan 1 00:00:00 2024 +0000
+
+    fix: close the file
+
+` + statusComments, "#", "", MessageReal},
+		{"custom comment char", "; Please enter the commit message\n; On branch main\n", ";", "", MessageCommentsOnly},
+		{"hash lines with a custom comment char", "#123 fix the login\n; On branch main\n", ";", "", MessageReal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyCommitMessage(tt.content, tt.commentChar, tt.template); got != tt.want {
+				t.Errorf("ClassifyCommitMessage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// verboseDiff is what "git commit -v" appends below the scissors line
+const verboseDiff = `# ------------------------ >8 ------------------------
+# Do not modify or remove the line above.
+# Everything below it will be ignored.
+diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-package old
++package main
+`
+
+func TestScissors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		commentChar string
+		cut         string
+		stripped    string
+	}{
+		{"no scissors", "fix: close the file\n# comment\n", "#", "fix: close the file\n# comment\n", "fix: close the file"},
+		{"verbose diff", "fix: close the file\n\n" + statusComments + verboseDiff, "#", "fix: close the file\n\n" + strings.TrimSuffix(statusComments, "\n"), "fix: close the file"},
+		{"CRLF", "fix: close the file\r\n" + "# ------------------------ >8 ------------------------\r\n+added\r\n", "#", "fix: close the file", "fix: close the file"},
+		{"custom comment char", "fix: close the file\n; ------------------------ >8 ------------------------\n+added\n", ";", "fix: close the file", "fix: close the file"},
+		{"other comment char's scissors", "fix: close the file\n# ------------------------ >8 ------------------------\n+added\n", ";", "fix: close the file\n# ------------------------ >8 ------------------------\n+added\n", "fix: close the file\n# ------------------------ >8 ------------------------\n+added"},
+		{"indented scissors", "fix: close the file\n  # ------------------------ >8 ------------------------\n", "#", "fix: close the file\n  # ------------------------ >8 ------------------------\n", "fix: close the file\n  # ------------------------ >8 ------------------------"},
+		{"only a diff", verboseDiff, "#", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CutAtScissors(tt.content, tt.commentChar); got != tt.cut {
+				t.Errorf("CutAtScissors = %q, want %q", got, tt.cut)
+			}
+			if got := StripComments(tt.content, tt.commentChar); got != tt.stripped {
+				t.Errorf("StripComments = %q, want %q", got, tt.stripped)
+			}
+		})
+	}
+
+	if got := ClassifyCommitMessage(verboseDiff, "#", ""); got != MessageCommentsOnly {
+		t.Errorf("ClassifyCommitMessage of a bare verbose diff = %v, want %v", got, MessageCommentsOnly)
+	}
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FileChange describes one staged file: how git classifies the change and how many lines it
+// adds and removes (binary files count as zero)
+type FileChange struct {
+	Status  string // git's status letter: A (added), M, D (deleted), R (renamed), C (copied) or T
+	OldPath string // Path before a rename or copy, "" otherwise
+	Added   int
+	Removed int
+}
+
+// StagedNumstat returns the status and added/removed line counts of every staged file in one
+// git call, keyed by the file's new path. base is the revision to diff against ("" means
+// HEAD), as with --amend
+func StagedNumstat(base string) (map[string]FileChange, error) {
+	args := []string{"--cached", "--raw", "--numstat", "-z"}
+	if base != "" {
+		args = append(args, base)
+	}
+	cmd := DiffCommand(args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	// With -z the raw records come first, ":<modes> <hashes> <status>\0<path>\0" or for renames
+	// and copies ":... R100\0<old path>\0<new path>\0". The numstat records follow:
+	// "<added>\t<removed>\t<path>\0", or "<added>\t<removed>\t\0<old path>\0<new path>\0"
+	changes := make(map[string]FileChange)
+	fields := strings.Split(out.String(), "\x00")
+	for i := 0; i < len(fields); i++ {
+		if strings.HasPrefix(fields[i], ":") {
+			meta := strings.Fields(fields[i])
+			if len(meta) < 5 || i+1 >= len(fields) {
+				continue
+			}
+			status := meta[4][:1]
+			change := FileChange{Status: status}
+			path := fields[i+1]
+			i++
+			if (status == "R" || status == "C") && i+1 < len(fields) {
+				change.OldPath, path = path, fields[i+1]
+				i++
+			}
+			changes[path] = change
+			continue
+		}
+
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+		change := changes[path]
+		change.Added, _ = strconv.Atoi(parts[0]) // "-" for binary files
+		change.Removed, _ = strconv.Atoi(parts[1])
+		changes[path] = change
+	}
+	return changes, nil
+}
+
+// BlobLineCount returns the number of lines of path as of rev (e.g. "HEAD"), counting a last
+// line without a newline
+func BlobLineCount(rev, path string) (int, error) {
+	cmd := exec.Command("git", "cat-file", "blob", rev+":"+path)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return 0, err
+	}
+
+	lines := bytes.Count(out.Bytes(), []byte("\n"))
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		lines++
+	}
+	return lines, nil
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+)
+
+// patchSubjectPrefix matches the "[PATCH 2/5] " tag format-patch puts before the subject
+var patchSubjectPrefix = regexp.MustCompile(`^\[[^\]]*\]\s*`)
+
+// SetPatchMessage replaces the commit message of a patch with message. In git format-patch
+// output the Subject header (keeping its "[PATCH n/m]" tag) and the body up to the "---"
+// separator are replaced; a plain diff gets the message and a "---" line in front of it,
+// which git apply skips
+func SetPatchMessage(patch, message string) string {
+	subject, body, _ := strings.Cut(strings.ReplaceAll(message, "\r\n", "\n"), "\n\n")
+	subject = strings.TrimSpace(subject)
+	body = strings.TrimSpace(body)
+
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	if !strings.HasPrefix(lines[0], "From ") {
+		return strings.TrimSpace(message) + "\n---\n" + patch
+	}
+
+	var result []string
+	i := 0
+	// Mail headers run until the first blank line; folded headers continue with whitespace
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		if !strings.HasPrefix(lines[i], "Subject:") {
+			result = append(result, lines[i])
+			continue
+		}
+		old := strings.TrimSpace(strings.TrimPrefix(lines[i], "Subject:"))
+		for i+1 < len(lines) && (strings.HasPrefix(lines[i+1], " ") || strings.HasPrefix(lines[i+1], "\t")) {
+			i++
+			old += lines[i]
+		}
+		result = append(result, "Subject: "+patchSubjectPrefix.FindString(old)+subject)
+	}
+
+	// Skip the old body; the diffstat separator or the diff itself ends it
+	for i < len(lines) && lines[i] != "---" && !strings.HasPrefix(lines[i], "diff --git ") {
+		i++
+	}
+
+	result = append(result, "")
+	if body != "" {
+		result = append(result, body, "")
+	}
+	if i < len(lines) && lines[i] != "---" {
+		result = append(result, "---")
+	}
+	return strings.Join(append(result, lines[i:]...), "\n")
+}
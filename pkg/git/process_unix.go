@@ -0,0 +1,20 @@
+//go:build !windows
+
+package git
+
+import (
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// Signal 0 performs error checking only; EPERM means the process exists
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
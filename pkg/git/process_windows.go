@@ -0,0 +1,16 @@
+//go:build windows
+
+package git
+
+import "os"
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	// On Windows FindProcess opens a handle and fails if the process doesn't exist
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,27 @@
+package git
+
+import (
+	"errors"
+	"path"
+)
+
+// ErrProtectedBranch is returned when committing to a protected branch wasn't confirmed
+var ErrProtectedBranch = errors.New("refusing to commit to a protected branch")
+
+// MatchBranch returns the first pattern that matches the branch name, or "" if none does.
+// Patterns are globs where * stays within one path segment, so "release/*" matches
+// "release/1.2" but not "release/1.2/hotfix".
+func MatchBranch(patterns []string, branch string) string {
+	if branch == "" {
+		return ""
+	}
+	for _, pattern := range patterns {
+		if pattern == branch {
+			return pattern
+		}
+		if ok, err := path.Match(pattern, branch); err == nil && ok {
+			return pattern
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// HasUpstream reports whether the current branch tracks a remote branch
+func HasUpstream() bool {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	return cmd.Run() == nil
+}
+
+// Push pushes the current branch. With setUpstream it runs "git push -u origin <branch>"
+// for branches that don't track a remote yet; otherwise it pushes to the upstream.
+func Push(setUpstream bool) error {
+	args := []string{"push"}
+	if setUpstream {
+		branch, err := CurrentBranch()
+		if err != nil {
+			return err
+		}
+		if branch == "" {
+			return errors.New("cannot push a detached HEAD")
+		}
+		if !hasRemote("origin") {
+			return errors.New("no remote named \"origin\" is configured")
+		}
+		args = append(args, "-u", "origin", branch)
+	}
+
+	cmd := exec.Command("git", args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		// git explains rejections (non-fast-forward, permissions) on stderr
+		if detail := strings.TrimSpace(out.String()); detail != "" {
+			return fmt.Errorf("git %s failed: %s", strings.Join(args, " "), detail)
+		}
+		return fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
+
+// hasRemote reports whether a remote with the given name exists
+func hasRemote(name string) bool {
+	cmd := exec.Command("git", "remote")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+
+	for _, remote := range strings.Fields(out.String()) {
+		if remote == name {
+			return true
+		}
+	}
+	return false
+}
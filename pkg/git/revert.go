@@ -0,0 +1,78 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// RevertHead returns the full hash of the commit being reverted when a
+// "git revert --no-commit" is in progress, or "" otherwise
+func RevertHead() string {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "REVERT_HEAD")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// CommitSubject returns the subject line of a commit
+func CommitSubject(rev string) (string, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%s", rev)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// patchID returns the stable patch ID of a diff, which ignores line numbers and
+// whitespace, or "" for an empty diff
+func patchID(diff []byte) (string, error) {
+	cmd := exec.Command("git", "patch-id", "--stable")
+	cmd.Stdin = bytes.NewReader(diff)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	fields := strings.Fields(out.String())
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+// FindRevertedCommit returns the full hash of the recent commit whose changes the staged
+// diff exactly undoes, or "" if there is none. Only the last depth non-merge commits are
+// searched, since every candidate costs a diff and a patch-id.
+func FindRevertedCommit(depth int) (string, error) {
+	staged, err := DiffCommand("--cached").Output()
+	if err != nil {
+		return "", err
+	}
+	stagedID, err := patchID(staged)
+	if err != nil || stagedID == "" {
+		return "", err
+	}
+
+	out, err := exec.Command("git", "rev-list", "--no-merges", fmt.Sprintf("--max-count=%d", depth), "HEAD").Output()
+	if err != nil {
+		return "", err
+	}
+	for _, sha := range strings.Fields(string(out)) {
+		// Diffing from the commit to its parent gives the inverse of the commit
+		inverse, err := DiffCommand(sha, sha+"^").Output()
+		if err != nil {
+			continue // Root commit
+		}
+		if id, err := patchID(inverse); err == nil && id == stagedID {
+			return sha, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,45 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrSigningFailed is returned when git could not sign the commit (missing key, agent not running)
+var ErrSigningFailed = errors.New("commit signing failed")
+
+// signingFailureMarkers are stderr fragments git, gpg and ssh-keygen print when signing fails
+var signingFailureMarkers = []string{
+	"gpg failed to sign the data",
+	"failed to sign the data",
+	"couldn't load public key",
+	"couldn't get agent socket",
+	"agent refused operation",
+	"no private key found",
+	"ssh-keygen",
+}
+
+// ConfigBool reads a boolean git config value, treating unset or invalid values as false
+func ConfigBool(key string) bool {
+	cmd := exec.Command("git", "config", "--type=bool", "--get", key)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+	return strings.TrimSpace(out.String()) == "true"
+}
+
+// signingError returns ErrSigningFailed with git's stderr when it describes a signing failure, or nil
+func signingError(stderr string) error {
+	lower := strings.ToLower(stderr)
+	for _, marker := range signingFailureMarkers {
+		if strings.Contains(lower, marker) {
+			return fmt.Errorf("%w:\n%s", ErrSigningFailed, strings.TrimSpace(stderr))
+		}
+	}
+	return nil
+}
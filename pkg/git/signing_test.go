@@ -0,0 +1,71 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSigningError(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   bool
+	}{
+		{"error: gpg failed to sign the data\nfatal: failed to write commit object\n", true},
+		{"error: GPG FAILED TO SIGN THE DATA", true},
+		{"error: Couldn't load public key /home/me/.ssh/id.pub: No such file or directory\nfatal: failed to write commit object", true},
+		{"error: agent refused operation?", true},
+		{"error: No private key found for \"me@example.com\"", true},
+		{"pre-commit hook failed", false},
+		{"fatal: unable to auto-detect email address", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		err := signingError(tt.stderr)
+		if got := errors.Is(err, ErrSigningFailed); got != tt.want {
+			t.Errorf("signingError(%q) = %v, want ErrSigningFailed: %v", tt.stderr, err, tt.want)
+		}
+		if err != nil && !strings.Contains(err.Error(), strings.TrimSpace(tt.stderr)) {
+			t.Errorf("signingError(%q) = %q, want it to include git's output", tt.stderr, err)
+		}
+	}
+}
+
+// fakeGit puts a git on PATH that prints stderr and exits with status 128
+func fakeGit(t *testing.T, stderr string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake git is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nprintf '%s' '" + stderr + "' >&2\nexit 128\n"
+	if err := os.WriteFile(filepath.Join(dir, "git"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCommitReportsSigningFailure(t *testing.T) {
+	fakeGit(t, "error: gpg failed to sign the data\nfatal: failed to write commit object\n")
+	err := CommitWithOptions("fix: sign me", CommitOptions{})
+	if !errors.Is(err, ErrSigningFailed) {
+		t.Fatalf("CommitWithOptions error = %v, want ErrSigningFailed", err)
+	}
+	if !strings.Contains(err.Error(), "gpg failed to sign the data") {
+		t.Errorf("error %q doesn't include git's output", err)
+	}
+}
+
+func TestCommitReportsOtherFailures(t *testing.T) {
+	fakeGit(t, "error: pre-commit hook rejected the commit\n")
+	err := CommitWithOptions("fix: hook me", CommitOptions{})
+	if err == nil {
+		t.Fatal("CommitWithOptions succeeded although git failed")
+	}
+	if errors.Is(err, ErrSigningFailed) {
+		t.Errorf("CommitWithOptions error = %v, a hook failure isn't a signing failure", err)
+	}
+}
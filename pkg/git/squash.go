@@ -0,0 +1,140 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrEmptyRange is returned when a commit range contains no commits
+var ErrEmptyRange = errors.New("the range contains no commits")
+
+// CommitRange is a "base..head" range of commits, resolved to full hashes
+type CommitRange struct {
+	Spec      string // The range as given, e.g. "main..HEAD"
+	Base      string
+	Head      string
+	MergeBase string // Where head forked from base; the squashed commit's parent
+}
+
+// ResolveRange parses "base..head" (or "base...head"; head defaults to HEAD) and resolves
+// both ends and their merge base
+func ResolveRange(spec string) (CommitRange, error) {
+	base, head, found := strings.Cut(spec, "...")
+	if !found {
+		base, head, found = strings.Cut(spec, "..")
+	}
+	if !found || base == "" {
+		return CommitRange{}, fmt.Errorf("invalid range %q: expected <base>..<head>, e.g. main..HEAD", spec)
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+
+	r := CommitRange{Spec: spec}
+	var err error
+	if r.Base, err = resolveCommit(base); err != nil {
+		return CommitRange{}, err
+	}
+	if r.Head, err = resolveCommit(head); err != nil {
+		return CommitRange{}, err
+	}
+
+	cmd := exec.Command("git", "merge-base", r.Base, r.Head)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return CommitRange{}, fmt.Errorf("%s and %s have no common ancestor", base, head)
+	}
+	r.MergeBase = strings.TrimSpace(out.String())
+	return r, nil
+}
+
+// resolveCommit returns the full hash of a revision that names a commit
+func resolveCommit(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// Subjects returns the subjects of the non-merge commits in the range, oldest first
+func (r CommitRange) Subjects() ([]string, error) {
+	cmd := exec.Command("git", "log", "--no-merges", "--reverse", "--format=%s", r.MergeBase+".."+r.Head)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var subjects []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrEmptyRange, r.Spec)
+	}
+	return subjects, nil
+}
+
+// Diff returns the combined diff of the range, from the merge base to head
+func (r CommitRange) Diff() (string, error) {
+	cmd := DiffCommand(r.MergeBase, r.Head)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Files returns the paths changed across the range
+func (r CommitRange) Files() ([]string, error) {
+	cmd := DiffCommand("--name-only", r.MergeBase, r.Head)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(out.String(), "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// CreateSquashCommit records head's tree as a single commit on top of the merge base and
+// points the new branch at it. The working tree, index and current branch are untouched.
+// It returns the new commit's hash.
+func (r CommitRange) CreateSquashCommit(message, branch string) (string, error) {
+	if resolved, _ := resolveCommit("refs/heads/" + branch); resolved != "" {
+		return "", fmt.Errorf("branch %q already exists", branch)
+	}
+
+	cmd := exec.Command("git", "commit-tree", r.Head+"^{tree}", "-p", r.MergeBase, "-F", "-")
+	cmd.Stdin = strings.NewReader(message)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git commit-tree: %s", strings.TrimSpace(stderr.String()))
+	}
+	commit := strings.TrimSpace(out.String())
+
+	cmd = exec.Command("git", "branch", branch, commit)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git branch: %s", strings.TrimSpace(stderr.String()))
+	}
+	return commit, nil
+}
@@ -0,0 +1,35 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSubmoduleNotCheckedOut is returned when a submodule's repository isn't available locally
+var ErrSubmoduleNotCheckedOut = errors.New("submodule is not checked out")
+
+// SubmoduleDiff returns the diff inside the submodule at path (relative to the repository
+// root) between two of its commits. File paths are prefixed with the submodule path so
+// they read like the rest of the superproject's diff.
+func SubmoduleDiff(path, from, to string) (string, error) {
+	root, err := revParsePath("--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, filepath.FromSlash(path))
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrSubmoduleNotCheckedOut, path)
+	}
+
+	prefix := strings.TrimSuffix(filepath.ToSlash(path), "/") + "/"
+	cmd := DiffCommand("--src-prefix=a/"+prefix, "--dst-prefix=b/"+prefix, from, to)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("diff of submodule %s: %w", path, err)
+	}
+	return string(out), nil
+}
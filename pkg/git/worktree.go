@@ -0,0 +1,126 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrHookExists is returned when a hook that commitron didn't write is already installed
+var ErrHookExists = errors.New("a hook is already installed")
+
+// hookMarker identifies hook scripts written by commitron so they can be replaced safely
+const hookMarker = "# installed by commitron"
+
+// revParsePath runs git rev-parse with the given arguments and returns the resulting
+// path made absolute. In a linked worktree .git is a file pointing elsewhere, so the
+// paths must always come from git rather than being joined onto ".git".
+func revParsePath(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"rev-parse"}, args...)...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	path := strings.TrimSpace(out.String())
+	if !filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(wd, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// TopLevel returns the root directory of the working tree
+func TopLevel() (string, error) {
+	return revParsePath("--show-toplevel")
+}
+
+// CommonDir returns the git directory shared by all worktrees of the repository
+func CommonDir() (string, error) {
+	return revParsePath("--git-common-dir")
+}
+
+// GitPath resolves a path inside the git directory the way git does, so per-worktree
+// files (MERGE_HEAD, rebase-merge) and shared ones (hooks) land in the right place
+func GitPath(name string) (string, error) {
+	return revParsePath("--git-path", name)
+}
+
+// IsWorktree reports whether the current directory is inside a linked worktree
+func IsWorktree() bool {
+	gitDir, err := revParsePath("--git-dir")
+	if err != nil {
+		return false
+	}
+	commonDir, err := CommonDir()
+	if err != nil {
+		return false
+	}
+	return gitDir != commonDir
+}
+
+// HooksDir returns the directory git runs hooks from, honoring core.hooksPath
+func HooksDir() (string, error) {
+	return GitPath("hooks")
+}
+
+// InProgressOperation returns the name of the merge, rebase, cherry-pick or revert
+// that is currently in progress in this worktree, or "" if there is none
+func InProgressOperation() string {
+	states := []struct {
+		path      string
+		operation string
+	}{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+	}
+
+	for _, state := range states {
+		path, err := GitPath(state.path)
+		if err != nil {
+			return ""
+		}
+		if _, err := os.Stat(path); err == nil {
+			return state.operation
+		}
+	}
+	return ""
+}
+
+// InstallHook writes an executable hook script to the hooks directory and returns its
+// path. An existing hook is only replaced if commitron wrote it or force is set.
+func InstallHook(name, script string, force bool) (string, error) {
+	dir, err := HooksDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+
+	if data, err := os.ReadFile(path); err == nil && !force && !strings.Contains(string(data), hookMarker) {
+		return "", fmt.Errorf("%w at %s (use --force to replace it)", ErrHookExists, path)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	content := "#!/bin/sh\n" + hookMarker + "\n" + script
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
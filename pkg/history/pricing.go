@@ -0,0 +1,62 @@
+package history
+
+import (
+	"sort"
+	"strings"
+)
+
+// modelPrice is the list price in USD per million tokens
+type modelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// modelPrices maps model name fragments to list prices. More specific fragments
+// are matched first, so "gpt-4o-mini" wins over "gpt-4o".
+var modelPrices = map[string]modelPrice{
+	"gpt-4o-mini":      {Input: 0.15, Output: 0.60},
+	"gpt-4o":           {Input: 2.50, Output: 10.00},
+	"gpt-4.1-nano":     {Input: 0.10, Output: 0.40},
+	"gpt-4.1-mini":     {Input: 0.40, Output: 1.60},
+	"gpt-4.1":          {Input: 2.00, Output: 8.00},
+	"gpt-4-turbo":      {Input: 10.00, Output: 30.00},
+	"gpt-3.5-turbo":    {Input: 0.50, Output: 1.50},
+	"o3-mini":          {Input: 1.10, Output: 4.40},
+	"claude-3-5-haiku": {Input: 0.80, Output: 4.00},
+	"claude-3-haiku":   {Input: 0.25, Output: 1.25},
+	"sonnet":           {Input: 3.00, Output: 15.00},
+	"opus":             {Input: 15.00, Output: 75.00},
+	"gemini-2.0-flash": {Input: 0.10, Output: 0.40},
+	"gemini-1.5-flash": {Input: 0.075, Output: 0.30},
+	"gemini-1.5-pro":   {Input: 1.25, Output: 5.00},
+}
+
+// EstimateCost returns the estimated spend in USD for the given token counts.
+// Local providers are free; unknown models return false.
+func EstimateCost(provider, model string, promptTokens, completionTokens int) (float64, bool) {
+	if provider == "ollama" {
+		return 0, true
+	}
+
+	// Try longer fragments first so the most specific price applies
+	fragments := make([]string, 0, len(modelPrices))
+	for fragment := range modelPrices {
+		fragments = append(fragments, fragment)
+	}
+	sort.Slice(fragments, func(i, j int) bool {
+		if len(fragments[i]) != len(fragments[j]) {
+			return len(fragments[i]) > len(fragments[j])
+		}
+		return fragments[i] < fragments[j]
+	})
+
+	model = strings.ToLower(model)
+	for _, fragment := range fragments {
+		if strings.Contains(model, fragment) {
+			price := modelPrices[fragment]
+			return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Stats summarizes the generation history
+type Stats struct {
+	Generations         int             `json:"generations"`
+	Accepted            int             `json:"accepted"`
+	AcceptanceRate      float64         `json:"acceptance_rate"`
+	AvgAttempts         float64         `json:"avg_attempts"`
+	AvgPromptTokens     float64         `json:"avg_prompt_tokens"`
+	AvgCompletionTokens float64         `json:"avg_completion_tokens"`
+	AvgLatencyMs        float64         `json:"avg_latency_ms"`
+	PerDay              []PeriodCount   `json:"per_day"`
+	PerWeek             []PeriodCount   `json:"per_week"`
+	Spend               []ProviderSpend `json:"spend"`
+}
+
+// PeriodCount is the number of generations in a day or ISO week
+type PeriodCount struct {
+	Period      string `json:"period"`
+	Generations int    `json:"generations"`
+}
+
+// ProviderSpend is the estimated spend for one provider/model pair
+type ProviderSpend struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Generations      int     `json:"generations"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	EstimatedUSD     float64 `json:"estimated_usd"`
+	Priced           bool    `json:"priced"` // False when the model's price is unknown
+}
+
+// Summarize computes usage statistics. Daily counts cover the last 7 days and
+// weekly counts the last 4 ISO weeks, relative to now.
+func Summarize(entries []Entry, now time.Time) Stats {
+	var stats Stats
+	stats.Generations = len(entries)
+
+	days := make(map[string]int)
+	weeks := make(map[string]int)
+	spend := make(map[string]*ProviderSpend)
+
+	var attempts, promptTokens, completionTokens int
+	var latency int64
+	for _, entry := range entries {
+		if entry.Accepted {
+			stats.Accepted++
+		}
+		attempts += entry.Attempts
+		promptTokens += entry.PromptTokens
+		completionTokens += entry.CompletionTokens
+		latency += entry.LatencyMs
+
+		local := entry.Time.In(now.Location())
+		days[local.Format("2006-01-02")]++
+		year, week := local.ISOWeek()
+		weeks[isoWeekLabel(year, week)]++
+
+		key := entry.Provider + "/" + entry.Model
+		if spend[key] == nil {
+			spend[key] = &ProviderSpend{Provider: entry.Provider, Model: entry.Model, Priced: true}
+		}
+		spend[key].Generations++
+		spend[key].PromptTokens += entry.PromptTokens
+		spend[key].CompletionTokens += entry.CompletionTokens
+	}
+
+	if stats.Generations > 0 {
+		n := float64(stats.Generations)
+		stats.AcceptanceRate = float64(stats.Accepted) / n
+		stats.AvgAttempts = float64(attempts) / n
+		stats.AvgPromptTokens = float64(promptTokens) / n
+		stats.AvgCompletionTokens = float64(completionTokens) / n
+		stats.AvgLatencyMs = float64(latency) / n
+	}
+
+	for i := 6; i >= 0; i-- {
+		day := now.AddDate(0, 0, -i).Format("2006-01-02")
+		stats.PerDay = append(stats.PerDay, PeriodCount{Period: day, Generations: days[day]})
+	}
+	for i := 3; i >= 0; i-- {
+		year, week := now.AddDate(0, 0, -7*i).ISOWeek()
+		label := isoWeekLabel(year, week)
+		stats.PerWeek = append(stats.PerWeek, PeriodCount{Period: label, Generations: weeks[label]})
+	}
+
+	for _, s := range spend {
+		s.EstimatedUSD, s.Priced = EstimateCost(s.Provider, s.Model, s.PromptTokens, s.CompletionTokens)
+		stats.Spend = append(stats.Spend, *s)
+	}
+	sort.Slice(stats.Spend, func(i, j int) bool {
+		if stats.Spend[i].Provider != stats.Spend[j].Provider {
+			return stats.Spend[i].Provider < stats.Spend[j].Provider
+		}
+		return stats.Spend[i].Model < stats.Spend[j].Model
+	})
+
+	return stats
+}
+
+// isoWeekLabel formats an ISO week as e.g. "2024-W07"
+func isoWeekLabel(year, week int) string {
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
@@ -0,0 +1,173 @@
+// Package ignore matches repository paths against gitignore-style patterns
+package ignore
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// rule is one compiled pattern line
+type rule struct {
+	base     string         // Directory of the file the pattern came from ("" for the repository root)
+	pattern  *regexp.Regexp // Matches the path relative to base (anchored) or a single name
+	anchored bool           // The pattern contains a slash, so it is matched against the whole relative path
+	dirOnly  bool           // The pattern ended in a slash and only matches directories
+	negate   bool           // The pattern started with "!" and re-includes what it matches
+}
+
+// Matcher decides whether repository-relative paths are ignored. Patterns follow gitignore:
+// "#" comments, "!" negations, a trailing "/" for directories only, a slash elsewhere to
+// anchor the pattern to its directory, and "*", "?", "[...]" and "**" wildcards. The last
+// matching pattern wins, and nothing inside an ignored directory can be re-included.
+type Matcher struct {
+	rules []rule
+}
+
+// Add adds the patterns of an ignore file in the directory base (relative to the repository
+// root, "" for the root), one pattern per line. Later patterns take precedence.
+func (m *Matcher) Add(base string, content string) {
+	base = strings.Trim(path.Clean("/"+base), "/")
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if r, ok := parseRule(base, line); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+}
+
+// Empty reports whether the matcher has no patterns, so callers can skip matching
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.rules) == 0
+}
+
+// Match reports whether the file at path (relative to the repository root) is ignored
+func (m *Matcher) Match(file string) bool {
+	if m.Empty() {
+		return false
+	}
+	parts := strings.Split(strings.Trim(file, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(strings.Join(parts, "/"), false)
+}
+
+// MatchDir reports whether the directory at path (relative to the repository root) is ignored
+func (m *Matcher) MatchDir(dir string) bool {
+	if m.Empty() {
+		return false
+	}
+	parts := strings.Split(strings.Trim(dir, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return false
+}
+
+// match applies the rules to one path, the last matching rule deciding
+func (m *Matcher) match(p string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel := p
+		if r.base != "" {
+			if !strings.HasPrefix(p, r.base+"/") {
+				continue
+			}
+			rel = p[len(r.base)+1:]
+		}
+		if !r.anchored {
+			rel = path.Base(rel)
+		}
+		if r.pattern.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// parseRule compiles one line of an ignore file, or returns false for blanks and comments
+func parseRule(base, line string) (rule, bool) {
+	// Trailing spaces are ignored unless escaped with a backslash
+	if !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to its directory
+	r.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	pattern, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return rule{}, false
+	}
+	r.pattern = pattern
+	return r, true
+}
+
+// globToRegexp translates a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Leading or inner "**/" matches zero or more directories
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			// Trailing "/**" matches everything inside
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
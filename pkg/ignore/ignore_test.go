@@ -0,0 +1,90 @@
+package ignore
+
+import "testing"
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns string
+		path     string
+		want     bool
+	}{
+		// Negation
+		{"negation re-includes", "*.log\n!keep.log", "keep.log", false},
+		{"negation leaves others", "*.log\n!keep.log", "debug.log", true},
+		{"negation in a subdirectory", "*.log\n!keep.log", "logs/keep.log", false},
+		{"later pattern wins", "!keep.log\n*.log", "keep.log", true},
+		{"no re-include inside an ignored directory", "build/\n!build/keep.txt", "build/keep.txt", true},
+		{"escaped exclamation mark", `\!important.txt`, "!important.txt", true},
+
+		// Trailing slash
+		{"directory pattern ignores its files", "build/", "build/out.bin", true},
+		{"directory pattern in a subdirectory", "build/", "src/build/out.bin", true},
+		{"directory pattern skips a file of that name", "build/", "build", false},
+		{"anchored directory pattern", "/build/", "src/build/out.bin", false},
+
+		// Double asterisk
+		{"leading **", "**/fixtures", "test/data/fixtures/a.json", true},
+		{"leading ** at the root", "**/fixtures", "fixtures/a.json", true},
+		{"trailing **", "vendor/**", "vendor/a/b.go", true},
+		{"trailing ** skips the directory name alone", "vendor/**", "vendor", false},
+		{"inner **", "docs/**/*.png", "docs/a/b/c.png", true},
+		{"inner ** matches no directories", "docs/**/*.png", "docs/c.png", true},
+		{"inner ** is anchored", "docs/**/*.png", "site/docs/c.png", false},
+		{"single * stays in a segment", "docs/*.png", "docs/a/c.png", false},
+
+		// Comments and escaped hash
+		{"comment", "# secrets.txt", "# secrets.txt", false},
+		{"comment does not match", "# secrets.txt", "secrets.txt", false},
+		{"escaped hash", `\#notes.md`, "#notes.md", true},
+		{"escaped hash in a subdirectory", `\#notes.md`, "docs/#notes.md", true},
+		{"hash inside a pattern", "a#b", "a#b", true},
+
+		// Other syntax
+		{"question mark", "file?.txt", "file1.txt", true},
+		{"character class", "file[0-9].txt", "filex.txt", false},
+		{"negated character class", "file[!0-9].txt", "filex.txt", true},
+		{"trailing spaces trimmed", "*.tmp   ", "a.tmp", true},
+		{"CRLF patterns", "*.tmp\r\n*.bak\r\n", "a.bak", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Matcher
+			m.Add("", tt.patterns)
+			if got := m.Match(tt.path); got != tt.want {
+				t.Errorf("patterns %q: Match(%q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherBase(t *testing.T) {
+	var m Matcher
+	m.Add("", "*.log")
+	m.Add("web", "/dist/\n!important.log")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"web/dist/app.js", true},
+		{"dist/app.js", false},
+		{"web/src/dist/app.js", false},
+		{"web/important.log", false},
+		{"important.log", true},
+		{"web/debug.log", true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if !m.MatchDir("web/dist") || m.MatchDir("web") {
+		t.Error("MatchDir doesn't follow the directory patterns")
+	}
+
+	var empty *Matcher
+	if !empty.Empty() || empty.Match("a.log") {
+		t.Error("a nil matcher matched")
+	}
+}
@@ -0,0 +1,65 @@
+package timing
+
+import (
+	"sync"
+	"time"
+)
+
+// Stage is the total time spent in one part of the pipeline
+type Stage struct {
+	Name     string        `json:"stage"`
+	Duration time.Duration `json:"-"`
+	Ms       float64       `json:"ms"`
+}
+
+// Recorder accumulates how long each pipeline stage took. A nil Recorder records
+// nothing, so callers don't need to check whether timing was requested.
+type Recorder struct {
+	mu     sync.Mutex
+	stages []Stage
+}
+
+// Track starts timing a stage and returns the function that stops it. Time spent
+// in a stage that was already tracked is added to it.
+func (r *Recorder) Track(name string) func() {
+	if r == nil {
+		return func() {}
+	}
+	started := time.Now()
+	return func() {
+		r.Add(name, time.Since(started))
+	}
+}
+
+// Add adds a duration to a stage, keeping stages in the order they first ran
+func (r *Recorder) Add(name string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.stages {
+		if r.stages[i].Name == name {
+			r.stages[i].Duration += d
+			r.stages[i].Ms = milliseconds(r.stages[i].Duration)
+			return
+		}
+	}
+	r.stages = append(r.stages, Stage{Name: name, Duration: d, Ms: milliseconds(d)})
+}
+
+// Stages returns the recorded stages in the order they first ran
+func (r *Recorder) Stages() []Stage {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Stage(nil), r.stages...)
+}
+
+// milliseconds converts a duration to milliseconds with microsecond precision
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
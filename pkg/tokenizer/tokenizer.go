@@ -1,34 +1,152 @@
 package tokenizer
 
 import (
+	"fmt"
+	"os"
 	"strings"
+	"sync"
 
 	"github.com/pkoukk/tiktoken-go"
 )
 
+// Token estimators for context.token_estimator
+const (
+	// EstimatorTiktoken counts tokens with the model's tiktoken encoding
+	EstimatorTiktoken = "tiktoken"
+	// EstimatorChars estimates tokens from the character count
+	EstimatorChars = "chars"
+	// EstimatorWords estimates tokens from the word count
+	EstimatorWords = "words"
+)
+
+// encodingLoader loads a tiktoken encoding by name
+type encodingLoader func(name string) (*tiktoken.Tiktoken, error)
+
+// newEncoding loads a tiktoken encoding by name. tiktoken-go downloads the encoding files
+// on first use, which fails offline; it is a variable so that failure can be simulated.
+var newEncoding encodingLoader = tiktoken.GetEncoding
+
+var (
+	encodingMu sync.Mutex
+	encodings  = map[string]*tiktoken.Tiktoken{} // Loaded encodings; building one is expensive
+	failed     = map[string]error{}              // Encodings that couldn't be loaded, so they aren't retried
+	estimator  = EstimatorTiktoken
+	warnOnce   sync.Once
+)
+
+// SetEstimator selects how tokens are counted: "tiktoken" (the default), or the rougher
+// "chars" or "words" estimates, which need no encoding files
+func SetEstimator(name string) error {
+	switch name {
+	case "", EstimatorTiktoken:
+		name = EstimatorTiktoken
+	case EstimatorChars, EstimatorWords:
+	default:
+		return fmt.Errorf("invalid context.token_estimator %q: use %q, %q or %q", name, EstimatorTiktoken, EstimatorChars, EstimatorWords)
+	}
+	encodingMu.Lock()
+	estimator = name
+	encodingMu.Unlock()
+	return nil
+}
+
+// encodingFor returns the model's encoding, or nil when tokens are estimated instead: either
+// an estimator was chosen or the encoding couldn't be loaded. The first load failure is
+// reported once, naming the encoding and the estimate used in its place.
+func encodingFor(model string) *tiktoken.Tiktoken {
+	encodingMu.Lock()
+	defer encodingMu.Unlock()
+	if estimator != EstimatorTiktoken {
+		return nil
+	}
+
+	name := EncodingName(model)
+	if encoding, ok := encodings[name]; ok {
+		return encoding
+	}
+	if _, ok := failed[name]; ok {
+		return nil
+	}
+	encoding, err := newEncoding(name)
+	if err != nil {
+		failed[name] = err
+		warnOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "\033[1;33m⚠️  Couldn't load the %s token encoding (%v); estimating tokens from the character count with a wider safety margin. Set context.token_estimator to silence this.\033[0m\n", name, err)
+		})
+		return nil
+	}
+	encodings[name] = encoding
+	return encoding
+}
+
+// Estimating reports whether token counts for the model are estimates rather than exact,
+// so budgets built on them need a wider margin
+func Estimating(model string) bool {
+	return encodingFor(model) == nil
+}
+
+// Method names how tokens are counted for the model, for debug output
+func Method(model string) string {
+	if encodingFor(model) != nil {
+		return EncodingName(model)
+	}
+	encodingMu.Lock()
+	defer encodingMu.Unlock()
+	if estimator == EstimatorWords {
+		return "word count estimate"
+	}
+	return "character count estimate"
+}
+
 // CountTokens returns the number of tokens in the given text for the specified model.
 // For unknown models, it falls back to cl100k_base encoding (current OpenAI standard).
+// Without an encoding the count is estimated (see SetEstimator).
 func CountTokens(text string, model string) int {
 	if text == "" {
 		return 0
 	}
 
-	// Try to get encoding for the specific model
-	encoding, err := tiktoken.EncodingForModel(model)
-	if err != nil {
-		// Fallback to cl100k_base for unknown models (gpt-4, gpt-3.5-turbo, future models)
-		encoding, err = tiktoken.GetEncoding("cl100k_base")
-		if err != nil {
-			// Ultimate fallback: estimate based on character count
-			// Typical ratio is 1 token ≈ 3.5 characters for English text
-			return int(float64(len(text)) / 3.5)
+	encoding := encodingFor(model)
+	if encoding == nil {
+		encodingMu.Lock()
+		words := estimator == EstimatorWords
+		encodingMu.Unlock()
+		if words {
+			return EstimateTokensFromWords(text)
 		}
+		return EstimateTokens(text)
 	}
+	return len(encoding.Encode(text, nil, nil))
+}
+
+// EncodingName returns the name of the encoding CountTokens uses for the model
+func EncodingName(model string) string {
+	if name, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return name
+	}
+	for prefix, name := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return name
+		}
+	}
+	return "cl100k_base"
+}
 
-	tokens := encoding.Encode(text, nil, nil)
-	return len(tokens)
+// EstimateTokens approximates the token count from the length of the text without
+// loading an encoder. Typical ratio is 1 token ≈ 3.5 characters for English text.
+func EstimateTokens(text string) int {
+	return int(float64(len(text)) / 3.5)
 }
 
+// EstimateTokensFromWords approximates the token count from whitespace-separated words,
+// at about 4 tokens per 3 words of English text
+func EstimateTokensFromWords(text string) int {
+	return len(strings.Fields(text)) * 4 / 3
+}
+
+// truncateChunkLines is the number of lines counted together while truncating
+const truncateChunkLines = 200
+
 // TruncateToTokenLimit intelligently truncates text to fit within the token limit.
 // It attempts to truncate at diff boundaries (file boundaries or hunk boundaries) rather
 // than cutting mid-content to preserve context integrity.
@@ -43,15 +161,29 @@ func TruncateToTokenLimit(text string, maxTokens int, model string) string {
 	var result []string
 	var currentTotal int
 
-	for _, line := range lines {
-		lineTokens := CountTokens(line+"\n", model)
-		if currentTotal+lineTokens > maxTokens {
-			// Stop before exceeding limit
-			result = append(result, "...[truncated to fit token limit]")
-			break
+	// Count whole chunks of lines first; encoding line by line is slow on large diffs
+	for start := 0; start < len(lines); start += truncateChunkLines {
+		chunk := lines[start:min(start+truncateChunkLines, len(lines))]
+		chunkTokens := CountTokens(strings.Join(chunk, "\n")+"\n", model)
+		if currentTotal+chunkTokens <= maxTokens {
+			result = append(result, chunk...)
+			currentTotal += chunkTokens
+			continue
+		}
+
+		// Only the chunk that crosses the limit is counted line by line
+		for _, line := range chunk {
+			lineTokens := CountTokens(line+"\n", model)
+			if currentTotal+lineTokens > maxTokens {
+				break
+			}
+			result = append(result, line)
+			currentTotal += lineTokens
 		}
-		result = append(result, line)
-		currentTotal += lineTokens
+
+		// Stop before exceeding limit
+		result = append(result, "...[truncated to fit token limit]")
+		break
 	}
 
 	return strings.Join(result, "\n")
@@ -108,6 +240,31 @@ func GetProviderTokenLimit(provider string, model string) int {
 	}
 }
 
+// GetModelOutputLimit returns the most tokens a known model can generate in one response,
+// or 0 when the model isn't known and the configured value is sent as is
+func GetModelOutputLimit(provider string, model string) int {
+	provider = strings.ToLower(provider)
+	model = strings.ToLower(model)
+
+	switch provider {
+	case "openai":
+		switch {
+		case strings.Contains(model, "gpt-4o"), strings.Contains(model, "gpt-4.1"):
+			return 16384
+		case strings.Contains(model, "gpt-4"), strings.Contains(model, "gpt-3.5"):
+			return 4096
+		}
+	case "claude":
+		switch {
+		case strings.Contains(model, "claude-3-5"), strings.Contains(model, "claude-3.5"):
+			return 8192
+		case strings.Contains(model, "claude-3"):
+			return 4096
+		}
+	}
+	return 0
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
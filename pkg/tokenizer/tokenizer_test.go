@@ -0,0 +1,146 @@
+package tokenizer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// useEncodingLoader makes loader the encoder factory with nothing cached and tiktoken
+// selected, and puts everything back when the test ends
+func useEncodingLoader(t *testing.T, loader encodingLoader) {
+	t.Helper()
+	reset := func() {
+		encodings = map[string]*tiktoken.Tiktoken{}
+		failed = map[string]error{}
+		warnOnce = sync.Once{}
+	}
+	original := newEncoding
+	t.Cleanup(func() {
+		newEncoding = original
+		reset()
+		SetEstimator(EstimatorTiktoken)
+	})
+	newEncoding = loader
+	reset()
+	if err := SetEstimator(EstimatorTiktoken); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// byteEncoding is an encoding with one token per byte, built without any encoding files. It
+// needs a special token: tiktoken-go loops forever matching the empty pattern of none.
+func byteEncoding(name string) (*tiktoken.Tiktoken, error) {
+	ranks := make(map[string]int, 256)
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	special := map[string]int{"<|endoftext|>": 256}
+	bpe, err := tiktoken.NewCoreBPE(ranks, special, `\S+|\s+`)
+	if err != nil {
+		return nil, err
+	}
+	encoding := &tiktoken.Encoding{Name: name, PatStr: `\S+|\s+`, MergeableRanks: ranks, SpecialTokens: special}
+	return tiktoken.NewTiktoken(bpe, encoding, map[string]any{"<|endoftext|>": nil}), nil
+}
+
+// captureStderr returns what fn writes to os.Stderr
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := os.Stderr
+	os.Stderr = write
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(read)
+		output <- string(data)
+	}()
+	fn()
+	write.Close()
+	os.Stderr = original
+	return <-output
+}
+
+func TestEncodingLoadFailureFallsBackToEstimate(t *testing.T) {
+	loads := 0
+	useEncodingLoader(t, func(name string) (*tiktoken.Tiktoken, error) {
+		loads++
+		return nil, errors.New("dial tcp: lookup openaipublic.blob.core.windows.net: no such host")
+	})
+
+	// Two models with different encodings; the warning names the first that failed
+	first, second := "gpt-4", "text-davinci-003"
+	if EncodingName(first) == EncodingName(second) {
+		t.Fatalf("%s and %s share the %s encoding", first, second, EncodingName(first))
+	}
+	text := strings.Repeat("func main() { fmt.Println(\"hello\") }\n", 20)
+	var counts []int
+	warning := captureStderr(t, func() {
+		counts = append(counts, CountTokens(text, first), CountTokens(text, second), CountTokens(text, first))
+	})
+
+	for _, count := range counts {
+		if count != EstimateTokens(text) {
+			t.Errorf("CountTokens = %v, want the character estimate %d each time", counts, EstimateTokens(text))
+			break
+		}
+	}
+	if loads != 2 {
+		t.Errorf("the loader ran %d times, want once per encoding: a failed encoding isn't retried", loads)
+	}
+	if strings.Count(warning, "Couldn't load the") != 1 || !strings.Contains(warning, EncodingName(first)) || !strings.Contains(warning, "no such host") {
+		t.Errorf("want one warning naming %s and the error, got:\n%s", EncodingName(first), warning)
+	}
+	if !Estimating(first) || Method(first) != "character count estimate" {
+		t.Errorf("Estimating = %v, Method = %q after a load failure", Estimating(first), Method(first))
+	}
+}
+
+func TestEncodingLoaderIsUsed(t *testing.T) {
+	var loaded []string
+	useEncodingLoader(t, func(name string) (*tiktoken.Tiktoken, error) {
+		loaded = append(loaded, name)
+		return byteEncoding(name)
+	})
+
+	if got := CountTokens("hello world", "gpt-4"); got != len("hello world") {
+		t.Errorf("CountTokens = %d, want %d from the one-token-per-byte encoding", got, len("hello world"))
+	}
+	CountTokens("again", "gpt-4")
+	if len(loaded) != 1 || loaded[0] != "cl100k_base" {
+		t.Errorf("loaded %q, want cl100k_base once", loaded)
+	}
+	if Estimating("gpt-4") || Method("gpt-4") != "cl100k_base" {
+		t.Errorf("Estimating = %v, Method = %q with a loaded encoding", Estimating("gpt-4"), Method("gpt-4"))
+	}
+	if got := TruncateToTokenLimit("line one\nline two\nline three", 10, "gpt-4"); got != "line one\n...[truncated to fit token limit]" {
+		t.Errorf("TruncateToTokenLimit = %q", got)
+	}
+}
+
+func TestEstimatorSkipsLoader(t *testing.T) {
+	useEncodingLoader(t, func(name string) (*tiktoken.Tiktoken, error) {
+		t.Errorf("encoding %s loaded although an estimator is selected", name)
+		return nil, errors.New("unexpected load")
+	})
+	for _, estimator := range []string{EstimatorChars, EstimatorWords} {
+		if err := SetEstimator(estimator); err != nil {
+			t.Fatal(err)
+		}
+		CountTokens("some text to count", "gpt-4o")
+		if !Estimating("gpt-4o") {
+			t.Errorf("Estimating = false with the %s estimator", estimator)
+		}
+	}
+	if err := SetEstimator("bytes"); err == nil {
+		t.Error("SetEstimator accepted an unknown estimator")
+	}
+}
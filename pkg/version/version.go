@@ -0,0 +1,4 @@
+package version
+
+// Version is the commitron release version
+const Version = "0.1.0"